@@ -9,9 +9,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 
 	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/storage/postgres"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres" // Драйвер для поддержки PostgreSQL в migrate
 	_ "github.com/golang-migrate/migrate/v4/source/file"       // Драйвер для чтения миграций из файлов
@@ -20,7 +22,7 @@ import (
 
 // MigrationCfg хранит конфигурацию, необходимую для запуска миграций.
 type MigrationCfg struct {
-	ConnStr         string // Строка подключения к базе данных (DSN).
+	ConnStr         string // Строка подключения к базе данных (DSN) с параметрами запроса.
 	MigrationsPath  string // Путь к директории с файлами миграций.
 	MigrationsTable string // Название таблицы в БД для хранения истории миграций.
 }
@@ -37,7 +39,7 @@ func main() {
 	// База данных - строка подключения, дополненная параметрами для migrate.
 	m, err := migrate.New(
 		"file://"+migration.MigrationsPath,
-		fmt.Sprintf("%s?sslmode=disable&x-migrations-table=%s", migration.ConnStr, migration.MigrationsTable),
+		fmt.Sprintf("%s&x-migrations-table=%s", migration.ConnStr, url.QueryEscape(migration.MigrationsTable)),
 	)
 	if err != nil {
 		log.Fatalf("can't create new migration: %v", err)
@@ -93,14 +95,9 @@ func MustLoad() *MigrationCfg {
 		log.Fatalf("can't read config: %v", err)
 	}
 
-	// Собираем строку подключения к PostgreSQL из загруженных данных.
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
-		cfg.Postgres.Username,
-		cfg.Postgres.Password,
-		cfg.Postgres.Host,
-		cfg.Postgres.Port,
-		cfg.Postgres.Database,
-	)
+	// Собираем строку подключения к PostgreSQL из загруженных данных
+	// (с теми же sslmode, connect_timeout и search_path, что у сервиса).
+	connStr := postgres.ConnString(cfg.Postgres)
 
 	// Возвращаем готовую структуру с конфигурацией для мигратора.
 	return &MigrationCfg{
@@ -8,20 +8,44 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/metrics"
 	"github.com/YusovID/order-service/internal/storage/kafka"
+	orderGen "github.com/YusovID/order-service/lib/generator/order"
 	"github.com/YusovID/order-service/lib/logger/sl"
 	"github.com/YusovID/order-service/lib/logger/slogpretty"
 )
 
+// resultsTimeout - максимальное время ожидания подтверждений от брокера
+// после завершения генерации.
+const resultsTimeout = 30 * time.Second
+
 // main инициализирует и запускает сервис генерации заказов.
 //
+// Параметры запуска задаются флагами командной строки, без правки YAML:
+//
+//	--topic orders      топик для отправки (по умолчанию - первый топик из конфигурации);
+//	--count N           отправить N сообщений, дождаться подтверждений и завершиться
+//	                    (код выхода 1, если часть сообщений не отправлена; 0 - без ограничения);
+//	--rate R            отправлять R сообщений в секунду (0 - случайные задержки);
+//	--duration 1m       остановиться через указанное время (0 - без ограничения);
+//	--burst N           отправлять пачки по N сообщений без задержек (0 - случайные задержки);
+//	--burst-pause 5s    пауза между пачками;
+//	--invalid-percent P доля некорректных сообщений в процентах (для проверки DLQ);
+//	--customers 1000    размер пула покупателей (0 - новый покупатель для каждого заказа);
+//	--customer-skew 1.1 параметр распределения Ципфа для выбора покупателя;
+//	--metrics-addr A    адрес HTTP-сервера с метриками Prometheus, например :9091 (пусто - выключен);
+//	--seed N            начальное значение генератора для воспроизводимых данных (0 - случайное).
+//
 // Функция выполняет следующие шаги:
 // 1. Создает контекст для управления жизненным циклом приложения и graceful shutdown.
 // 2. Загружает конфигурацию из файла и переменных окружения.
@@ -29,9 +53,23 @@ import (
 // 4. Создает и настраивает асинхронного продюсера для Kafka.
 // 5. Настраивает обработку системных сигналов (SIGINT, SIGTERM) для корректного завершения.
 // 6. Запускает в отдельных горутинах процессы генерации сообщений и обработки ответов от Kafka.
-// 7. Ожидает сигнала о завершении, после чего инициирует остановку всех процессов.
-// 8. Корректно закрывает соединение с продюсером Kafka.
+// 7. Ожидает сигнала о завершении, после чего прекращает генерацию сообщений.
+// 8. Коммитит последнюю транзакцию и дожидается подтверждений по всем отправленным сообщениям.
+// 9. Корректно закрывает соединение с продюсером Kafka.
 func main() {
+	topic := flag.String("topic", "", "topic to produce to, defaults to the first configured topic")
+	count := flag.Int("count", 0, "number of messages to produce before exiting, 0 means unlimited")
+	rate := flag.Float64("rate", 0, "messages per second, 0 means random delays")
+	duration := flag.Duration("duration", 0, "how long to produce before exiting, 0 means unlimited")
+	burstSize := flag.Int("burst", 0, "number of messages sent without delay in one burst, 0 disables burst mode")
+	burstPause := flag.Duration("burst-pause", 5*time.Second, "pause between bursts")
+	invalidPercent := flag.Float64("invalid-percent", 0, "percentage of intentionally malformed messages")
+	customerPool := flag.Int("customers", orderGen.DefaultCustomerPoolSize, "size of the customer pool, 0 means a new customer per order")
+	customerSkew := flag.Float64("customer-skew", orderGen.DefaultCustomerSkew, "Zipf skew of customer selection, must be greater than 1")
+	metricsAddr := flag.String("metrics-addr", "", "address to serve Prometheus metrics on, empty disables the endpoint")
+	seed := flag.Uint64("seed", 0, "seed for reproducible generated orders, 0 means random")
+	flag.Parse()
+
 	// Создаем корневой контекст с функцией отмены для управления graceful shutdown.
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -39,10 +77,40 @@ func main() {
 	cfg := config.MustLoad()
 
 	// Настраиваем логгер в соответствии с текущим окружением (ENV).
-	log := slogpretty.SetupLogger(cfg.Env)
+	log := slogpretty.New(cfg.Env, slogpretty.Options{Format: cfg.LogFormat})
 
 	log.Info("starting order generator", slog.String("env", cfg.Env))
 
+	// Отдаем метрики продюсера, если задан адрес. Сервер живет до выхода из процесса.
+	if *metricsAddr != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler())
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				log.Error("failed to serve metrics", sl.Err(err))
+			}
+		}()
+		log.Info("serving metrics", slog.String("address", *metricsAddr))
+	}
+
+	orderGen.SetCustomerPool(*customerPool, *customerSkew)
+
+	if *seed != 0 {
+		if err := orderGen.Seed(*seed); err != nil {
+			log.Error("failed to seed generator", sl.Err(err))
+			os.Exit(1)
+		}
+		log.Info("generator seeded", slog.Uint64("seed", *seed))
+	}
+
+	// Создаем недостающие топики до начала отправки сообщений.
+	if cfg.Kafka.AutoCreateTopics {
+		if err := kafka.EnsureTopics(cfg.Kafka, log); err != nil {
+			log.Error("failed to create topics", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+
 	// Инициализируем продюсера Kafka.
 	p, err := kafka.NewProducer(cfg.Kafka, log)
 	if err != nil {
@@ -51,6 +119,24 @@ func main() {
 	}
 	log.Info("producer init successful")
 
+	p.Load = kafka.Load{
+		BurstSize:      *burstSize,
+		BurstPause:     *burstPause,
+		InvalidPercent: *invalidPercent,
+		Count:          *count,
+		Rate:           *rate,
+		Duration:       *duration,
+	}
+	if p.Load.BurstSize > 0 {
+		log.Info("burst mode enabled",
+			slog.Int("burst", p.Load.BurstSize),
+			slog.String("pause", p.Load.BurstPause.String()),
+		)
+	}
+	if p.Load.InvalidPercent > 0 {
+		log.Info("invalid message injection enabled", slog.Float64("percent", p.Load.InvalidPercent))
+	}
+
 	// Создаем канал для прослушивания системных сигналов.
 	sigchan := make(chan os.Signal, 1)
 	// Регистрируем нотификацию о сигналах SIGINT (Ctrl+C) и SIGTERM.
@@ -59,24 +145,76 @@ func main() {
 	// WaitGroup для ожидания завершения всех запущенных горутин.
 	wg := &sync.WaitGroup{}
 
+	if *topic == "" {
+		*topic = cfg.Kafka.Topic.Primary()
+	}
+	log.Info("producing messages", slog.String("topic", *topic))
+
 	// Запускаем горутину, которая будет генерировать и отправлять сообщения в Kafka.
-	wg.Add(1)
-	go p.ProduceMessage(ctx, cfg.Kafka.Topic, wg)
+	// Генерация останавливается отдельным контекстом: после сигнала продюсер
+	// перестает отправлять сообщения, но HandleResult продолжает читать
+	// результаты, пока не придут подтверждения по всем отправленным.
+	produceCtx, stopProducing := context.WithCancel(ctx)
+	defer stopProducing()
+
+	// Канал produced закрывается, когда генерация завершилась сама
+	// (отправлено --count сообщений или истекло --duration).
+	produced := make(chan struct{})
+	produceWG := &sync.WaitGroup{}
+	produceWG.Add(1)
+	go p.ProduceMessage(produceCtx, *topic, produceWG)
+	go func() {
+		produceWG.Wait()
+		close(produced)
+	}()
 
 	// Запускаем горутину для обработки ответов от Kafka (успех/ошибка).
 	wg.Add(1)
 	go p.HandleResult(ctx, wg)
 
-	// Блокируем выполнение до получения сигнала в канал sigchan.
-	<-sigchan
-	// После получения сигнала вызываем cancel(), что приведет к завершению
-	// контекста ctx и сигнализирует всем горутинам о необходимости остановиться.
-	cancel()
+	// Блокируем выполнение до получения сигнала или завершения генерации.
+	finished := false
+	select {
+	case <-sigchan:
+		log.Info("stopping message generation")
+		stopProducing()
+	case <-produced:
+		finished = true
+	}
 
-	// Ожидаем, пока все горутины, добавленные в wg, завершат свою работу.
+	// Фаза drain: ProduceMessage коммитит последнюю транзакцию и выходит,
+	// после чего ждем результатов по всем сообщениям, переданным продюсеру.
+	produceWG.Wait()
+	log.Info("waiting for in-flight messages", slog.Int64("pending", p.Pending()))
+	if !p.WaitResults(resultsTimeout) {
+		log.Error("timed out waiting for send results",
+			slog.String("timeout", resultsTimeout.String()),
+			slog.Int64("pending", p.Pending()),
+		)
+	}
+
+	// Вызываем cancel(), что приведет к завершению контекста ctx
+	// и сигнализирует остальным горутинам о необходимости остановиться.
+	cancel()
 	wg.Wait()
 
 	log.Info("stopping producer")
 	// Закрываем продюсера, освобождая ресурсы.
-	p.Producer.Close()
+	if err := p.Producer.Close(); err != nil {
+		log.Error("failed to close producer", sl.Err(err))
+	}
+
+	// В режиме --count/--duration код выхода сообщает CI, все ли сообщения дошли.
+	if finished {
+		failedMessages, failedTxns := p.Failed()
+		pending := p.Pending()
+		if failedMessages > 0 || failedTxns > 0 || pending > 0 {
+			log.Error("some messages were not sent",
+				slog.Int64("failed_messages", failedMessages),
+				slog.Int64("unconfirmed_messages", pending),
+				slog.Int64("failed_transactions", failedTxns),
+			)
+			os.Exit(1)
+		}
+	}
 }
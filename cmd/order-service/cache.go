@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/YusovID/order-service/internal/storage/redis"
+	"github.com/YusovID/order-service/lib/logger/sl"
+)
+
+// CacheWarmer наполняет кэш заказами из основного хранилища: один раз при
+// старте (Warm, продолжает прерванный прогрев) и затем периодически
+// (RunRefresh). Реализуется redis.Client.
+type CacheWarmer interface {
+	Warm(ctx context.Context, storage redis.Storage, log *slog.Logger) error
+	RunRefresh(ctx context.Context, storage redis.Storage, interval time.Duration, log *slog.Logger, wg *sync.WaitGroup)
+}
+
+// runCacheWarmer прогревает кэш и затем запускает его периодическое
+// обновление. Обновление начинается после прогрева, чтобы не догружать
+// заказы, которые прогрев еще не записал.
+func runCacheWarmer(ctx context.Context, warmer CacheWarmer, storage redis.Storage, refreshInterval time.Duration, log *slog.Logger, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if err := warmer.Warm(ctx, storage, log); err != nil {
+		log.Error("failed to warm cache", sl.Err(err))
+	}
+
+	wg.Add(1)
+	warmer.RunRefresh(ctx, storage, refreshInterval, log, wg)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// levelStep - шаг между стандартными уровнями slog (debug, info, warn, error).
+const levelStep = slog.LevelInfo - slog.LevelDebug
+
+// runLevelSignals меняет уровень логирования по сигналам: SIGUSR1 делает
+// логи подробнее на один уровень (до debug), SIGUSR2 - короче (до error).
+// Так можно включить отладочные логи в продакшене без перезапуска:
+// `kill -USR1 <pid>`. Уровень действует до следующей смены, SIGHUP или
+// перезапуска.
+func runLevelSignals(ctx context.Context, level *slog.LevelVar, log *slog.Logger, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(sig)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s := <-sig:
+			previous := level.Level()
+
+			next := previous - levelStep
+			if s == syscall.SIGUSR2 {
+				next = previous + levelStep
+			}
+			next = min(max(next, slog.LevelDebug), slog.LevelError)
+			level.Set(next)
+
+			log.Warn("log level changed",
+				slog.String("signal", s.String()),
+				slog.String("from", previous.String()),
+				slog.String("to", next.String()),
+			)
+		}
+	}
+}
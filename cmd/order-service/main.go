@@ -5,7 +5,7 @@
 //  2. Обрабатывает полученные сообщения: валидирует и сохраняет данные в базу данных PostgreSQL.
 //  3. Кэширует данные заказов в Redis для обеспечения быстрого доступа.
 //  4. При старте восстанавливает кэш из данных, хранящихся в PostgreSQL.
-//  5. Запускает HTTP-сервер с API-эндпоинтом для получения информации о заказе по его ID.
+//  5. Запускает HTTP-сервер с API для получения информации о заказе по его ID и смены его статуса.
 //  6. Предоставляет простой веб-интерфейс для взаимодействия с API.
 //
 // Приложение спроектировано с поддержкой graceful shutdown, что позволяет корректно
@@ -20,14 +20,25 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/codec"
 	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/health"
+	adminConfig "github.com/YusovID/order-service/internal/http-server/handlers/admin/config"
+	"github.com/YusovID/order-service/internal/http-server/handlers/admin/loglevel"
+	"github.com/YusovID/order-service/internal/http-server/handlers/readyz"
 	"github.com/YusovID/order-service/internal/http-server/handlers/url/get"
+	"github.com/YusovID/order-service/internal/http-server/handlers/url/history"
+	"github.com/YusovID/order-service/internal/http-server/handlers/url/status"
 	mwLogger "github.com/YusovID/order-service/internal/http-server/middleware/logger"
+	"github.com/YusovID/order-service/internal/metrics"
 	processor "github.com/YusovID/order-service/internal/processor/order"
+	"github.com/YusovID/order-service/internal/secrets"
 	"github.com/YusovID/order-service/internal/storage/kafka"
 	"github.com/YusovID/order-service/internal/storage/postgres"
 	"github.com/YusovID/order-service/internal/storage/redis"
@@ -37,18 +48,30 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 )
 
+// dryRunGroupSuffix добавляется к group.id консьюмеров в режиме dry run.
+const dryRunGroupSuffix = "-dryrun"
+
 // main инициализирует и запускает все компоненты сервиса.
 //
 // Процесс запуска включает:
 //   - Настройку контекста для graceful shutdown.
 //   - Загрузку конфигурации и инициализацию логгера.
+//   - Чтение учетных данных из HashiCorp Vault, если он настроен.
+//   - Применение миграций БД, если включено postgres.auto_migrate.
 //   - Подключение к PostgreSQL (основное хранилище).
 //   - Создание каналов для обмена сообщениями между Kafka-консьюмером и обработчиком.
 //   - Запуск обработчика заказов (processor) в отдельной горутине.
 //   - Подключение к Redis (кэш).
 //   - Запуск процесса наполнения кэша из PostgreSQL в отдельной горутине.
+//   - Периодическую догрузку в кэш измененных заказов.
+//   - Подписку на уведомления PostgreSQL об изменении заказов для сброса кэша.
+//   - Периодическое создание месячных секций таблиц заказов.
+//   - Фоновый перенос старых заказов в архив.
+//   - Наблюдение за доступностью PostgreSQL и Redis (/readyz).
 //   - Инициализацию Kafka-консьюмера и запуск прослушивания сообщений.
+//   - Запуск экспорта лага консьюмеров в метрики Prometheus (/metrics).
 //   - Настройку и запуск HTTP-сервера с API и веб-интерфейсом.
+//   - Перечитывание конфигурации по SIGHUP и смену уровня логирования по SIGUSR1/SIGUSR2.
 //   - Ожидание сигнала завершения (SIGINT, SIGTERM) для корректной остановки всех компонентов.
 func main() {
 	// Создаем корневой контекст с функцией отмены для управления graceful shutdown.
@@ -59,30 +82,104 @@ func main() {
 	// Загружаем конфигурацию.
 	cfg := config.MustLoad()
 
-	// Настраиваем логгер.
-	log := slogpretty.SetupLogger(cfg.Env)
+	// Настраиваем логгер. Уровень логирования можно менять без перезапуска (SIGHUP).
+	logLevel := new(slog.LevelVar)
+	log := slogpretty.New(cfg.Env, slogpretty.Options{Format: cfg.LogFormat, Level: logLevel})
+	applyLogLevel(cfg.LogLevel, logLevel, log)
 
 	log.Info("starting order service", slog.String("env", cfg.Env))
 
+	// Если настроен Vault, учетные данные PostgreSQL, Redis и Kafka берем из него.
+	var vault *secrets.Vault
+	if cfg.Vault.Address != "" {
+		vault = secrets.NewVault(cfg.Vault, log)
+		if err := secrets.Apply(ctx, vault, cfg); err != nil {
+			log.Error("failed to load secrets from vault", sl.Err(err))
+			os.Exit(1)
+		}
+		log.Info("secrets loaded from vault")
+
+		wg.Add(1)
+		go vault.Run(ctx, wg)
+	}
+
+	// Действующая конфигурация для /admin/config. Обновляется при перечитывании по SIGHUP.
+	currentConfig := &atomic.Pointer[config.Config]{}
+	currentConfig.Store(cfg)
+	log.Info("effective config", slog.Any("config", cfg.Redacted()))
+
+	// Применяем миграции до подключения хранилища, чтобы оно работало
+	// с актуальной схемой. Теневой экземпляр (dry run) схему не меняет:
+	// она принадлежит основному экземпляру.
+	switch {
+	case cfg.Postgres.AutoMigrate && cfg.Processor.DryRun:
+		log.Warn("skipping migrations in dry run mode")
+	case cfg.Postgres.AutoMigrate:
+		if err := postgres.Migrate(cfg.Postgres, log); err != nil {
+			log.Error("failed to apply migrations", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+
 	// Инициализируем подключение к PostgreSQL.
 	storage, err := postgres.New(cfg.Postgres, log)
 	if err != nil {
 		log.Error("failed to init storage", sl.Err(err))
 		os.Exit(1)
 	}
+	defer storage.Close()
 	log.Info("storage init successful")
 
 	// Каналы для передачи сообщений от консьюмера к обработчику (orderChan)
 	// и для подтверждения обработки обратно консьюмеру (commitChan).
-	orderChan := make(chan *sarama.ConsumerMessage)
-	commitChan := make(chan *sarama.ConsumerMessage)
+	// orderChan буферизирован: по степени его заполнения консьюмер понимает,
+	// что обработчик не успевает, и приостанавливает чтение из Kafka.
+	orderChan := make(chan *sarama.ConsumerMessage, cfg.Processor.OrderBuffer)
+	commitChan := make(chan *sarama.ConsumerMessage, cfg.Processor.CommitBuffer)
 
-	// Создаем экземпляр обработчика заказов.
-	processor := processor.New(storage, orderChan, commitChan, log)
+	// Выбираем формат сообщений в топике (JSON или Protocol Buffers). Он
+	// используется для схемы версии 1; сообщения схемы версии 2 всегда в JSON.
+	orderCodec, err := codec.New(cfg.Kafka.Encoding)
+	if err != nil {
+		log.Error("failed to init codec", sl.Err(err))
+		os.Exit(1)
+	}
 
-	// Запускаем горутину, которая будет постоянно читать из orderChan и обрабатывать заказы.
-	wg.Add(1)
-	go processor.ProcessOrders(ctx, wg)
+	// Если задан топик DLQ, невалидные сообщения будут отправляться в него.
+	var dlq processor.DeadLetterQueue
+	if cfg.Kafka.DLQTopic != "" {
+		kafkaDLQ, err := kafka.NewDLQ(cfg.Kafka, log)
+		if err != nil {
+			log.Error("failed to init dlq", sl.Err(err))
+			os.Exit(1)
+		}
+		defer kafkaDLQ.Close()
+
+		dlq = kafkaDLQ
+		log.Info("dlq init successful", slog.String("topic", cfg.Kafka.DLQTopic))
+	}
+
+	// Если задан топик событий, события о заказах (order.created, order.saved),
+	// записанные в outbox вместе с заказами, публикуются в него.
+	var statusEvents status.EventSender
+	if cfg.Kafka.Events.Topic != "" {
+		publisher, err := kafka.NewEventPublisher(cfg.Kafka, log)
+		if err != nil {
+			log.Error("failed to init event publisher", sl.Err(err))
+			os.Exit(1)
+		}
+		defer publisher.Close()
+
+		statusEvents = publisher
+
+		// Публикуем события, записанные в outbox вместе с заказами.
+		// В режиме dry run outbox не разбирается: он принадлежит основному экземпляру.
+		if !cfg.Processor.DryRun {
+			wg.Add(1)
+			go storage.RunOutboxRelay(ctx, cfg.Kafka.Events.RelayInterval, cfg.Kafka.Events.RelayBatchSize, publisher.PublishOutbox, wg)
+		}
+		log.Info("event publisher init successful", slog.String("topic", cfg.Kafka.Events.Topic))
+	}
 
 	// Инициализируем подключение к Redis.
 	cache, err := redis.New(ctx, cfg.Redis)
@@ -92,44 +189,206 @@ func main() {
 	}
 	log.Info("cache init successful")
 
-	// Запускаем горутину для первоначального заполнения кэша данными из PostgreSQL.
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		err := cache.Warm(ctx, storage)
+	// Создаем экземпляр обработчика заказов. После сохранения заказа
+	// обработчик удаляет его из кэша, чтобы API не отдавало устаревшую версию.
+	// Кэш также служит учетом обработанных сообщений: повторно доставленные
+	// Kafka сообщения пропускаются.
+	processor := processor.New(storage, codec.NewVersioned(orderCodec), dlq, cache, cache, orderChan, commitChan, log)
+	processor.SetWorkers(cfg.Processor.Workers)
+	processor.SetBatching(cfg.Processor.BatchInterval, cfg.Processor.BatchSize)
+	processor.SetSaveRetry(cfg.Processor.Retry.Attempts, cfg.Processor.Retry.InitialBackoff, cfg.Processor.Retry.MaxBackoff)
+	processor.EnableBatchWrites(storage, cfg.Processor.BatchWrites)
+	processor.SetDrainTimeout(cfg.Processor.DrainTimeout)
+	processor.EnablePriority(cfg.Processor.PriorityWorkers)
+	processor.SetDryRun(cfg.Processor.DryRun)
+	processor.SetCrashOnPanic(cfg.Processor.CrashOnPanic)
+	if cfg.Processor.DryRun {
+		// Теневой экземпляр читает топики своей группой консьюмеров: иначе он
+		// забирал бы партиции у основного экземпляра и коммитил офсеты
+		// сообщений, которые никто не сохранил.
+		cfg.Kafka.Consumer.GroupId += dryRunGroupSuffix
+		log.Warn("processor is running in dry run mode, orders are not saved",
+			slog.String("group_id", cfg.Kafka.Consumer.GroupId),
+		)
+	}
+	if err := processor.SetConsistency(cfg.Processor.Consistency); err != nil {
+		log.Error("failed to init processor", sl.Err(err))
+		os.Exit(1)
+	}
+	if err := processor.SetCommitStrategy(cfg.Processor.CommitStrategy); err != nil {
+		log.Error("failed to init processor", sl.Err(err))
+		os.Exit(1)
+	}
+	if err := processor.EnableScrubbing(cfg.Processor.Scrub.Mode, cfg.Processor.Scrub.Fields, cfg.Processor.Scrub.Salt); err != nil {
+		log.Error("failed to init processor", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// Сообщения из топика изменений меняют или отменяют существующие заказы.
+	if cfg.Kafka.UpdatesTopic != "" {
+		if !slices.Contains(cfg.Kafka.Topic, cfg.Kafka.UpdatesTopic) {
+			log.Error("updates topic must be listed in kafka.topic", slog.String("topic", cfg.Kafka.UpdatesTopic))
+			os.Exit(1)
+		}
+		processor.EnableUpdates(cfg.Kafka.UpdatesTopic, storage)
+	}
+
+	// Сообщения, которые раз за разом роняют сервис, отправляются в карантин,
+	// чтобы не блокировать партицию. Попытки обработки считаются в Redis.
+	if cfg.Kafka.QuarantineTopic != "" && !cfg.Processor.DryRun {
+		quarantine, err := kafka.NewQuarantine(cfg.Kafka, log)
 		if err != nil {
-			log.Error("failed to fill cache", sl.Err(err))
+			log.Error("failed to init quarantine", sl.Err(err))
+			os.Exit(1)
 		}
+		defer quarantine.Close()
 
-		log.Info("cache was warmed")
-	}()
+		processor.EnableQuarantine(cache, quarantine, cfg.Kafka.QuarantineAfter)
+		log.Info("quarantine init successful", slog.String("topic", cfg.Kafka.QuarantineTopic))
+	}
+
+	// Подключаем промежуточные обработчики из processor.middlewares.
+	if err := processor.UseNamed(cfg.Processor.Middlewares...); err != nil {
+		log.Error("failed to init processor middlewares", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// Запускаем горутину для первоначального заполнения кэша данными из PostgreSQL,
+	// после которого кэш периодически догружает измененные заказы на случай
+	// пропущенных уведомлений.
+	wg.Add(1)
+	go runCacheWarmer(ctx, cache, storage, cfg.Redis.RefreshInterval, log, wg)
+
+	// Обслуживание базы не запускается в режиме dry run.
+	if !cfg.Processor.DryRun {
+		// Заранее создаем месячные секции таблиц заказов.
+		wg.Add(1)
+		go storage.RunPartitionMaintenance(ctx, cfg.Postgres.Partitions, wg)
+
+		// Переносим старые заказы в архив и удаляем их из кэша.
+		wg.Add(1)
+		go storage.RunArchiver(ctx, cfg.Postgres.Archive, func(ctx context.Context, orderUIDs []string) {
+			for _, orderUID := range orderUIDs {
+				if err := cache.DeleteOrder(ctx, orderUID); err != nil {
+					log.Error("failed to delete archived order from cache",
+						slog.String("order_uid", orderUID),
+						sl.Err(err),
+					)
+				}
+			}
+		}, wg)
+	}
+
+	// Сбрасываем кэш заказов, измененных любым экземпляром сервиса.
+	if !cfg.Postgres.DisableListenChanges {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			storage.ListenOrderChanges(ctx, func(ctx context.Context, orderUID string) {
+				if err := cache.DeleteOrder(ctx, orderUID); err != nil {
+					log.Error("failed to invalidate cached order",
+						slog.String("order_uid", orderUID),
+						sl.Err(err),
+					)
+				}
+			})
+		}()
+	}
+
+	// Создаем недостающие топики, чтобы не зависеть от автосоздания на стороне брокера.
+	if cfg.Kafka.AutoCreateTopics {
+		if err := kafka.EnsureTopics(cfg.Kafka, log); err != nil {
+			log.Error("failed to create topics", sl.Err(err))
+			os.Exit(1)
+		}
+	}
 
-	// Инициализируем Kafka-консьюмера.
-	c, err := kafka.NewConsumer(cfg.Kafka, orderChan, commitChan, log)
+	// Инициализируем Kafka-консьюмеров (по одному на каждую сессию группы).
+	c, err := kafka.NewConsumerPool(cfg.Kafka, orderChan, commitChan, processor, log)
 	if err != nil {
 		log.Error("failed to init consumer", sl.Err(err))
 		os.Exit(1)
 	}
 	log.Info("consumer init successful")
 
+	// Не полностью обработанные пачки перечитываются с наименьшего неподтвержденного офсета.
+	processor.SetRewinder(c)
+
+	// Запускаем горутину, которая будет постоянно читать из orderChan и обрабатывать заказы.
+	// При остановке она завершается после того, как консьюмер закроет orderChan.
+	wg.Add(1)
+	go processor.ProcessOrders(ctx, wg)
+
 	log.Info("listening messages")
 	// Запускаем горутину для чтения сообщений из Kafka.
 	wg.Add(1)
 	go c.ProcessMessages(ctx, cfg.Kafka.Topic, wg)
 
+	// Запускаем экспорт лага группы консьюмеров в метрики Prometheus.
+	if cfg.Kafka.Consumer.LagInterval > 0 {
+		lagExporter, err := kafka.NewLagExporter(cfg.Kafka, log)
+		if err != nil {
+			log.Error("failed to init lag exporter", sl.Err(err))
+			os.Exit(1)
+		}
+		defer lagExporter.Close()
+
+		wg.Add(1)
+		go lagExporter.Run(ctx, wg)
+	}
+
+	// Следим за доступностью зависимостей для /readyz и логов.
+	deps := []health.Dependency{
+		{Name: "postgres", Checker: storage},
+		{Name: "redis", Checker: cache},
+	}
+	// Учетные данные из Vault, аренду которых не удалось продлить.
+	if vault != nil {
+		deps = append(deps, health.Dependency{Name: "vault", Checker: vault})
+	}
+	watchdog := health.New(log, cfg.Health.Timeout, deps...)
+	wg.Add(1)
+	go watchdog.Run(ctx, cfg.Health.Interval, wg)
+
 	// Настраиваем HTTP-роутер.
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID) // Добавляет ID каждому запросу.
 	router.Use(middleware.Logger)    // Стандартный логгер chi.
 	router.Use(mwLogger.New(log))    // Наш кастомный логгер на базе slog.
+	router.Use(metrics.Middleware)   // Метрики запросов для Prometheus.
 	router.Use(middleware.Recoverer) // Восстанавливается после паник.
 	router.Use(middleware.URLFormat) // Форматирует URL.
 
 	// Регистрируем API-хендлер для получения заказа по ID.
 	router.Get("/order/{order_uid}", get.New(log, cache, storage))
+	// Регистрируем API-хендлер для смены статуса заказа.
+	router.Patch("/order/{order_uid}/status", status.New(log, storage, cache, statusEvents))
+	// Регистрируем API-хендлер для получения журнала изменений заказа.
+	router.Get("/order/{order_uid}/history", history.New(log, storage))
+	// Проверка готовности: доступны ли PostgreSQL и Redis.
+	router.Get("/readyz", readyz.New(log, watchdog))
+	// Отдаем метрики Prometheus.
+	router.Handle("/metrics", metrics.Handler())
 	// Отдаем статичные файлы для веб-интерфейса.
 	router.Handle("/", http.FileServer(http.Dir("./web")))
 
+	// Служебные ручки обслуживаются отдельным сервером на http_server.admin_address,
+	// недоступным из публичной сети.
+	admin := chi.NewRouter()
+	admin.Use(middleware.RequestID)
+	admin.Use(mwLogger.New(log))
+	admin.Use(middleware.Recoverer)
+
+	// Отдаем действующую конфигурацию без секретов.
+	admin.Get("/admin/config", adminConfig.New(log, func() *config.Config {
+		current := *currentConfig.Load()
+		current.LogLevel = logLevel.Level().String()
+		return &current
+	}))
+	// Просмотр и смена уровня логирования без перезапуска.
+	admin.Get("/admin/loglevel", loglevel.NewGet(logLevel))
+	admin.Put("/admin/loglevel", loglevel.NewSet(log, logLevel))
+
 	log.Info("starting server", slog.String("address", cfg.HTTPServer.Address))
 
 	defer func() {
@@ -157,6 +416,36 @@ func main() {
 		}
 	}()
 
+	// Запускаем служебный HTTP-сервер.
+	log.Info("starting admin server", slog.String("address", cfg.HTTPServer.AdminAddress))
+	adminSrv := &http.Server{
+		Addr:         cfg.HTTPServer.AdminAddress,
+		Handler:      admin,
+		ReadTimeout:  cfg.HTTPServer.Timeout,
+		WriteTimeout: cfg.HTTPServer.Timeout,
+		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := adminSrv.ListenAndServe(); err != nil {
+			if !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("failed to start admin server", sl.Err(err))
+				os.Exit(1)
+			}
+		}
+	}()
+
+	// По SIGHUP перечитываем конфигурацию и применяем настройки, которые
+	// можно менять без перезапуска.
+	wg.Add(1)
+	go runReloader(ctx, &reloadTargets{config: currentConfig, logLevel: logLevel, cache: cache, processor: processor}, log, wg)
+
+	// По SIGUSR1/SIGUSR2 делаем логи подробнее или короче.
+	wg.Add(1)
+	go runLevelSignals(ctx, logLevel, log, wg)
+
 	// Ожидаем сигнал для начала graceful shutdown.
 	sigchan := make(chan os.Signal, 1)
 	signal.Notify(sigchan, os.Interrupt, syscall.SIGTERM)
@@ -169,13 +458,17 @@ func main() {
 		log.Error("failed to shutdown server", sl.Err(err))
 		os.Exit(1)
 	}
+	if err := adminSrv.Shutdown(context.Background()); err != nil {
+		log.Error("failed to shutdown admin server", sl.Err(err))
+		os.Exit(1)
+	}
 
 	// Ждем завершения всех фоновых процессов.
 	wg.Wait()
 
 	// Корректно останавливаем Kafka-консьюмер.
 	log.Info("shutting down consumer")
-	if err = c.Consumer.Close(); err != nil {
+	if err = c.Close(); err != nil {
 		slog.Error("failed to close consumer", sl.Err(err))
 		os.Exit(1)
 	}
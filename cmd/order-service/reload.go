@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/YusovID/order-service/internal/config"
+	processor "github.com/YusovID/order-service/internal/processor/order"
+	"github.com/YusovID/order-service/internal/storage/redis"
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/YusovID/order-service/lib/logger/slogpretty"
+)
+
+// reloadTargets - компоненты, настройки которых меняются при перечитывании
+// конфигурации без перезапуска сервиса.
+type reloadTargets struct {
+	config    *atomic.Pointer[config.Config] // Действующая конфигурация (см. /admin/config).
+	logLevel  *slog.LevelVar
+	cache     *redis.Client
+	processor *processor.Processor
+}
+
+// runReloader перечитывает конфигурацию при получении SIGHUP и применяет
+// настройки, безопасные для изменения во время работы: уровень логирования
+// (log_level), сроки жизни кэша (redis.ttl, redis.negative_ttl) и число
+// воркеров обработчика (processor.workers). Остальные настройки требуют
+// перезапуска. Консьюмер при этом не перезапускается, и партиции Kafka
+// остаются за сервисом.
+//
+// Если новую конфигурацию не удалось прочитать, продолжает работать прежняя.
+func runReloader(ctx context.Context, targets *reloadTargets, log *slog.Logger, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	const fn = "main.runReloader"
+	log = log.With(slog.String("fn", fn))
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			cfg, err := config.Load()
+			if err != nil {
+				log.Error("failed to reload config, keeping current settings", sl.Err(err))
+				continue
+			}
+			targets.apply(cfg, log)
+		}
+	}
+}
+
+// apply применяет перечитанную конфигурацию `cfg` и отражает примененные
+// настройки в действующей конфигурации.
+func (t *reloadTargets) apply(cfg *config.Config, log *slog.Logger) {
+	applyLogLevel(cfg.LogLevel, t.logLevel, log)
+	t.cache.SetTTL(cfg.Redis.TTL, cfg.Redis.NegativeTTL)
+	t.processor.SetWorkers(cfg.Processor.Workers)
+
+	current := *t.config.Load()
+	current.Redis.TTL = cfg.Redis.TTL
+	current.Redis.NegativeTTL = cfg.Redis.NegativeTTL
+	current.Processor.Workers = cfg.Processor.Workers
+	t.config.Store(&current)
+
+	log.Info("config reloaded",
+		slog.String("log_level", t.logLevel.Level().String()),
+		slog.Duration("redis_ttl", cfg.Redis.TTL),
+		slog.Duration("redis_negative_ttl", cfg.Redis.NegativeTTL),
+		slog.Int("processor_workers", cfg.Processor.Workers),
+	)
+}
+
+// applyLogLevel устанавливает уровень логирования `level`. Пустое значение
+// оставляет уровень по умолчанию для окружения, некорректное - текущий уровень.
+func applyLogLevel(level string, logLevel *slog.LevelVar, log *slog.Logger) {
+	if level == "" {
+		return
+	}
+
+	parsed, err := slogpretty.ParseLevel(level)
+	if err != nil {
+		log.Error("invalid log level, keeping current", slog.String("log_level", level), sl.Err(err))
+		return
+	}
+	logLevel.Set(parsed)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/storage/redis"
+)
+
+// runCacheFlush реализует команду `orderctl cache-flush`.
+//
+// Команда удаляет из Redis только ключи сервиса (с префиксом redis.namespace),
+// поэтому ее можно выполнять на Redis, общем для нескольких окружений.
+// Следующий старт сервиса прогреет кэш полностью.
+func runCacheFlush(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("cache-flush", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := config.MustLoad()
+
+	cache, err := redis.New(ctx, cfg.Redis)
+	if err != nil {
+		return fmt.Errorf("can't init cache: %v", err)
+	}
+	defer cache.Close()
+
+	deleted, err := cache.Flush(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("deleted %d keys with prefix %q\n", deleted, cfg.Redis.Namespace+":")
+	return nil
+}
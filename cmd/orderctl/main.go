@@ -0,0 +1,74 @@
+// package main реализует утилиту orderctl для обслуживания сервиса заказов.
+// Утилита объединяет служебные команды, которые не нужны основному сервису
+// во время работы: например, наполнение базы тестовыми данными или сброс
+// офсетов группы консьюмеров для повторного чтения сообщений.
+//
+// Использование:
+//
+//	orderctl <command> [flags]
+//
+// Конфигурация загружается так же, как у остальных сервисов: из файла,
+// путь к которому указан в переменной окружения CONFIG_PATH.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// command описывает одну подкоманду orderctl.
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+// commands - список всех доступных подкоманд.
+var commands = []command{
+	{name: "seed", usage: "generate historical orders directly into PostgreSQL and warm the cache", run: runSeed},
+	{name: "cache-flush", usage: "delete this service's keys (redis.namespace) from Redis", run: runCacheFlush},
+	{name: "reset-offsets", usage: "move consumer group offsets to a time or offset to replay messages", run: runResetOffsets},
+}
+
+// main разбирает имя подкоманды и запускает ее.
+// Контекст отменяется по SIGINT/SIGTERM, чтобы долгие команды можно было прервать.
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	name := os.Args[1]
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		if err := cmd.run(ctx, os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "orderctl %s: %v\n", name, err)
+			cancel()
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	printUsage()
+	os.Exit(2)
+}
+
+// printUsage выводит список доступных подкоманд.
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: orderctl <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/storage/kafka"
+	"github.com/YusovID/order-service/internal/storage/redis"
+)
+
+// runResetOffsets реализует команду `orderctl reset-offsets`.
+//
+// Команда перемещает офсеты группы консьюмеров на момент времени, конкретный
+// офсет, начало или конец топика. Сервис заказов при этом должен быть
+// остановлен: после запуска он повторно прочитает сообщения с новых офсетов.
+//
+// По умолчанию команда также удаляет из Redis отметки об обработке сообщений
+// начиная с нового офсета (redis.ledger_ttl): иначе сервис пропустил бы
+// повторно прочитанные сообщения как уже обработанные. Флаг --clear-ledger=false
+// оставляет отметки, если повторно нужно прочитать только необработанные сообщения.
+//
+// Примеры:
+//
+//	orderctl reset-offsets --to-time 2025-01-01T00:00:00Z
+//	orderctl reset-offsets --to-offset 1500 --partitions 0,2 --dry-run
+func runResetOffsets(ctx context.Context, args []string) error {
+	cfg := config.MustLoad()
+
+	fs := flag.NewFlagSet("reset-offsets", flag.ContinueOnError)
+	group := fs.String("group", cfg.Kafka.Consumer.GroupId, "consumer group id")
+	topic := fs.String("topic", cfg.Kafka.Topic.Primary(), "topic to reset")
+	partitionsArg := fs.String("partitions", "", "comma-separated partitions (default: all)")
+	toTime := fs.String("to-time", "", "reset to the first message at or after this time (RFC3339)")
+	toOffset := fs.Int64("to-offset", -1, "reset to this offset")
+	toEarliest := fs.Bool("to-earliest", false, "reset to the oldest available offset")
+	toLatest := fs.Bool("to-latest", false, "reset to the newest offset (skip everything)")
+	dryRun := fs.Bool("dry-run", false, "only print the plan without committing")
+	clearLedger := fs.Bool("clear-ledger", true, "delete processed-message marks from the new offset so the messages are not skipped")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	target, err := parseOffsetTarget(*toTime, *toOffset, *toEarliest, *toLatest)
+	if err != nil {
+		return err
+	}
+
+	partitions, err := parsePartitions(*partitionsArg)
+	if err != nil {
+		return err
+	}
+
+	plan, err := kafka.ResetOffsets(cfg.Kafka, *group, *topic, partitions, target, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, po := range plan {
+		fmt.Fprintf(os.Stdout, "%s/%d: %d -> %d\n", *topic, po.Partition, po.Current, po.Target)
+	}
+	if *dryRun {
+		fmt.Fprintln(os.Stdout, "dry run: offsets were not committed")
+		return nil
+	}
+
+	if *clearLedger && cfg.Redis.LedgerTTL > 0 {
+		return clearProcessed(ctx, cfg.Redis, *topic, plan)
+	}
+
+	return nil
+}
+
+// clearProcessed удаляет отметки об обработке сообщений, которые будут
+// прочитаны заново. Если офсет сдвинут вперед, отметки не трогаются: с них
+// сообщения все равно не прочитаются повторно.
+func clearProcessed(ctx context.Context, cfg config.Redis, topic string, plan []kafka.PartitionOffset) error {
+	ledger, err := redis.New(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("offsets were reset, but can't connect to redis to clear the ledger: %v", err)
+	}
+	defer ledger.Close()
+
+	for _, po := range plan {
+		if po.Current >= 0 && po.Target >= po.Current {
+			continue
+		}
+		deleted, err := ledger.ClearProcessed(ctx, topic, po.Partition, po.Target)
+		if err != nil {
+			return fmt.Errorf("offsets were reset, but can't clear the ledger: %v", err)
+		}
+		fmt.Fprintf(os.Stdout, "%s/%d: cleared %d processed marks\n", topic, po.Partition, deleted)
+	}
+
+	return nil
+}
+
+// parseOffsetTarget проверяет, что задана ровно одна цель сброса, и возвращает ее.
+func parseOffsetTarget(toTime string, toOffset int64, toEarliest, toLatest bool) (kafka.OffsetTarget, error) {
+	var targets []kafka.OffsetTarget
+
+	if toTime != "" {
+		t, err := time.Parse(time.RFC3339, toTime)
+		if err != nil {
+			return kafka.OffsetTarget{}, fmt.Errorf("invalid --to-time: %v", err)
+		}
+		targets = append(targets, kafka.OffsetTarget{Time: t})
+	}
+	if toOffset >= 0 {
+		targets = append(targets, kafka.OffsetTarget{Offset: toOffset})
+	}
+	if toEarliest {
+		targets = append(targets, kafka.OffsetTarget{Offset: sarama.OffsetOldest})
+	}
+	if toLatest {
+		targets = append(targets, kafka.OffsetTarget{Offset: sarama.OffsetNewest})
+	}
+
+	if len(targets) != 1 {
+		return kafka.OffsetTarget{}, errors.New("exactly one of --to-time, --to-offset, --to-earliest, --to-latest is required")
+	}
+
+	return targets[0], nil
+}
+
+// parsePartitions разбирает список партиций через запятую.
+func parsePartitions(s string) ([]int32, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var partitions []int32
+	for _, p := range strings.Split(s, ",") {
+		n, err := strconv.ParseInt(strings.TrimSpace(p), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid partition %q: %v", p, err)
+		}
+		partitions = append(partitions, int32(n))
+	}
+
+	return partitions, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/storage/postgres"
+	"github.com/YusovID/order-service/internal/storage/redis"
+	orderGen "github.com/YusovID/order-service/lib/generator/order"
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/YusovID/order-service/lib/logger/slogpretty"
+)
+
+// seedWorkers - количество параллельных вставок в PostgreSQL.
+const seedWorkers = 8
+
+// runSeed реализует команду `orderctl seed`.
+//
+// Команда генерирует заказы с датами создания за последние `--days` дней
+// и сохраняет их напрямую в PostgreSQL, минуя Kafka. После этого кэш Redis
+// прогревается теми же данными (если не указан `--no-warm`), так что веб-интерфейс
+// сразу показывает заполненную базу.
+func runSeed(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	ordersCount := fs.Int("orders", 1000, "number of orders to generate")
+	days := fs.Int("days", 30, "spread order creation dates over the last N days")
+	noWarm := fs.Bool("no-warm", false, "skip cache warm-up after seeding")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *ordersCount <= 0 {
+		return errors.New("--orders must be positive")
+	}
+	if *days <= 0 {
+		return errors.New("--days must be positive")
+	}
+
+	cfg := config.MustLoad()
+	log := slogpretty.New(cfg.Env, slogpretty.Options{Format: cfg.LogFormat})
+
+	storage, err := postgres.New(cfg.Postgres, log)
+	if err != nil {
+		return fmt.Errorf("can't init storage: %v", err)
+	}
+	defer storage.Close()
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -*days)
+
+	log.Info("seeding orders",
+		slog.Int("orders", *ordersCount),
+		slog.Time("from", from),
+		slog.Time("to", to),
+	)
+
+	start := time.Now()
+	saved, failed := seedOrders(ctx, storage, *ordersCount, from, to, log)
+
+	log.Info("seeding finished",
+		slog.Int64("saved", saved),
+		slog.Int64("failed", failed),
+		slog.String("duration", time.Since(start).String()),
+	)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !*noWarm {
+		cache, err := redis.New(ctx, cfg.Redis)
+		if err != nil {
+			return fmt.Errorf("can't init cache: %v", err)
+		}
+		defer cache.Close()
+
+		if err := cache.WarmFull(ctx, storage, log); err != nil {
+			return fmt.Errorf("can't warm cache: %v", err)
+		}
+		log.Info("cache was warmed")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d orders failed to save", failed)
+	}
+
+	return nil
+}
+
+// seedOrders генерирует и сохраняет `count` заказов, распределяя вставки
+// между seedWorkers горутинами. Возвращает количество успешно сохраненных
+// и неудачных заказов.
+func seedOrders(
+	ctx context.Context,
+	storage *postgres.Storage,
+	count int,
+	from, to time.Time,
+	log *slog.Logger,
+) (saved, failed int64) {
+	var savedCnt, failedCnt atomic.Int64
+
+	jobs := make(chan struct{})
+	wg := &sync.WaitGroup{}
+
+	for range seedWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				order := orderGen.GenerateOrderDataBetween(from, to)
+				if err := storage.SaveOrder(ctx, order); err != nil {
+					log.Error("failed to save order", slog.String("order_uid", order.OrderUID), sl.Err(err))
+					failedCnt.Add(1)
+					continue
+				}
+				savedCnt.Add(1)
+			}
+		}()
+	}
+
+loop:
+	for range count {
+		select {
+		case <-ctx.Done():
+			break loop
+		case jobs <- struct{}{}:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return savedCnt.Load(), failedCnt.Load()
+}
@@ -0,0 +1,75 @@
+// Package codec отвечает за преобразование заказов в байты сообщений Kafka и обратно.
+// Формат сообщений выбирается в конфигурации (`kafka.encoding`), благодаря чему
+// продюсер и консьюмер могут переключаться между JSON и Protocol Buffers,
+// а обработчик заказов не зависит от конкретного формата.
+package codec
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/YusovID/order-service/internal/models"
+)
+
+// Поддерживаемые форматы сообщений.
+const (
+	EncodingJSON     = "json"
+	EncodingProtobuf = "protobuf"
+)
+
+// Codec кодирует заказ в тело сообщения и декодирует его обратно.
+// Новые форматы добавляются реализацией этого интерфейса и вызовом Register.
+type Codec interface {
+	Encode(orderData *models.OrderData) ([]byte, error)
+	Decode(data []byte) (*models.OrderData, error)
+}
+
+// registry хранит кодеки по названию формата.
+var registry = struct {
+	sync.RWMutex
+	codecs map[string]Codec
+}{
+	codecs: map[string]Codec{
+		EncodingJSON:     JSON{},
+		EncodingProtobuf: Protobuf{},
+	},
+}
+
+// Register добавляет кодек для формата `encoding` (например, avro или msgpack),
+// после чего его можно выбрать в `kafka.encoding`. Повторная регистрация
+// формата заменяет кодек. Вызывается до New, обычно из init пакета кодека.
+func Register(encoding string, c Codec) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.codecs[encoding] = c
+}
+
+// New возвращает кодек для указанного формата.
+// Пустая строка означает формат по умолчанию - JSON.
+func New(encoding string) (Codec, error) {
+	if encoding == "" {
+		encoding = EncodingJSON
+	}
+
+	registry.RLock()
+	defer registry.RUnlock()
+
+	c, ok := registry.codecs[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q, supported: %v", encoding, encodings())
+	}
+	return c, nil
+}
+
+// encodings возвращает отсортированный список зарегистрированных форматов.
+// Вызывается под блокировкой реестра.
+func encodings() []string {
+	names := make([]string, 0, len(registry.codecs))
+	for name := range registry.codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
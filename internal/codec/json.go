@@ -0,0 +1,29 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/YusovID/order-service/internal/models"
+)
+
+// JSON кодирует заказы в JSON. Это формат по умолчанию.
+type JSON struct{}
+
+// Encode сериализует заказ в JSON.
+func (JSON) Encode(orderData *models.OrderData) ([]byte, error) {
+	b, err := json.Marshal(orderData)
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal json: %v", err)
+	}
+	return b, nil
+}
+
+// Decode десериализует заказ из JSON.
+func (JSON) Decode(data []byte) (*models.OrderData, error) {
+	var orderData models.OrderData
+	if err := json.Unmarshal(data, &orderData); err != nil {
+		return nil, fmt.Errorf("can't unmarshal json: %v", err)
+	}
+	return &orderData, nil
+}
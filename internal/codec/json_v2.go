@@ -0,0 +1,116 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/YusovID/order-service/internal/models"
+)
+
+// JSONV2 декодирует заказы схемы версии 2. В отличие от версии 1, данные
+// заказа в ней сгруппированы по смыслу (покупатель, получатель, адрес,
+// служебные поля), а время оплаты передается в RFC 3339, а не Unix-временем.
+type JSONV2 struct{}
+
+// orderV2 - тело сообщения о заказе схемы версии 2.
+type orderV2 struct {
+	ID          string    `json:"id"`
+	TrackNumber string    `json:"track_number"`
+	CreatedAt   time.Time `json:"created_at"`
+	Customer    struct {
+		ID     string `json:"id"`
+		Locale string `json:"locale"`
+	} `json:"customer"`
+	Delivery struct {
+		Service   string `json:"service"`
+		Recipient struct {
+			Name  string `json:"name"`
+			Phone string `json:"phone"`
+			Email string `json:"email"`
+		} `json:"recipient"`
+		Address struct {
+			Zip    string `json:"zip"`
+			City   string `json:"city"`
+			Street string `json:"street"`
+			Region string `json:"region"`
+		} `json:"address"`
+	} `json:"delivery"`
+	Payment struct {
+		Transaction  string    `json:"transaction"`
+		RequestID    string    `json:"request_id"`
+		Currency     string    `json:"currency"`
+		Provider     string    `json:"provider"`
+		Bank         string    `json:"bank"`
+		PaidAt       time.Time `json:"paid_at"`
+		Amount       int       `json:"amount"`
+		DeliveryCost int       `json:"delivery_cost"`
+		GoodsTotal   int       `json:"goods_total"`
+		CustomFee    int       `json:"custom_fee"`
+	} `json:"payment"`
+	Items []models.Item `json:"items"`
+	Meta  struct {
+		Entry             string `json:"entry"`
+		InternalSignature string `json:"internal_signature"`
+		Shardkey          string `json:"shardkey"`
+		SmID              int    `json:"sm_id"`
+		OofShard          string `json:"oof_shard"`
+	} `json:"meta"`
+}
+
+// Decode десериализует заказ схемы версии 2 и приводит его к models.OrderData.
+func (JSONV2) Decode(data []byte) (*models.OrderData, error) {
+	var o orderV2
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, fmt.Errorf("can't unmarshal json v2: %v", err)
+	}
+	return o.toOrderData(), nil
+}
+
+// toOrderData преобразует заказ схемы версии 2 в каноническую модель.
+func (o *orderV2) toOrderData() *models.OrderData {
+	orderData := &models.OrderData{
+		OrderUID:        o.ID,
+		TrackNumber:     o.TrackNumber,
+		CustomerID:      o.Customer.ID,
+		DeliveryService: o.Delivery.Service,
+		DateCreated:     o.CreatedAt,
+		Items:           o.Items,
+		Delivery: models.Delivery{
+			Name:    o.Delivery.Recipient.Name,
+			Phone:   o.Delivery.Recipient.Phone,
+			Zip:     o.Delivery.Address.Zip,
+			City:    o.Delivery.Address.City,
+			Address: o.Delivery.Address.Street,
+			Region:  o.Delivery.Address.Region,
+			Email:   o.Delivery.Recipient.Email,
+		},
+		Payment: models.Payment{
+			Transaction:  o.Payment.Transaction,
+			RequestID:    o.Payment.RequestID,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       o.Payment.Amount,
+			Bank:         o.Payment.Bank,
+			DeliveryCost: o.Payment.DeliveryCost,
+			GoodsTotal:   o.Payment.GoodsTotal,
+			CustomFee:    o.Payment.CustomFee,
+		},
+		AdditionalData: models.AdditionalData{
+			Entry:             o.Meta.Entry,
+			Locale:            o.Customer.Locale,
+			InternalSignature: o.Meta.InternalSignature,
+			Shardkey:          o.Meta.Shardkey,
+			SmID:              o.Meta.SmID,
+			OofShard:          o.Meta.OofShard,
+		},
+	}
+	if !o.Payment.PaidAt.IsZero() {
+		orderData.Payment.PaymentDT = int(o.Payment.PaidAt.Unix())
+	}
+	if orderData.Items == nil {
+		orderData.Items = make([]models.Item, 0)
+	}
+
+	return orderData
+}
@@ -0,0 +1,406 @@
+package codec
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/YusovID/order-service/internal/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Protobuf кодирует заказы в формате Protocol Buffers по схеме
+// api/proto/order/v1/order.proto.
+//
+// Сообщение кодируется вручную через protowire, без сгенерированного кода,
+// поэтому для сборки не нужен protoc. Номера полей здесь должны совпадать
+// со схемой: ее можно отдать другим командам для генерации клиентов.
+// Совпадение номеров и сохранность всех полей проверяют тесты.
+type Protobuf struct{}
+
+// Encode сериализует заказ в Protocol Buffers.
+func (Protobuf) Encode(o *models.OrderData) ([]byte, error) {
+	var b []byte
+
+	b = appendString(b, 1, o.OrderUID)
+	b = appendString(b, 2, o.TrackNumber)
+	b = appendString(b, 3, o.CustomerID)
+	b = appendString(b, 4, o.DeliveryService)
+	if !o.DateCreated.IsZero() {
+		b = appendMessage(b, 5, encodeTimestamp(o.DateCreated))
+	}
+	for _, item := range o.Items {
+		// Повторяющиеся сообщения кодируются всегда, даже пустые,
+		// иначе при декодировании изменится количество товаров.
+		b = protowire.AppendTag(b, 6, protowire.BytesType)
+		b = protowire.AppendBytes(b, encodeItem(item))
+	}
+	b = appendMessage(b, 7, encodeDelivery(o.Delivery))
+	b = appendMessage(b, 8, encodePayment(o.Payment))
+	b = appendMessage(b, 9, encodeAdditionalData(o.AdditionalData))
+
+	return b, nil
+}
+
+// Decode десериализует заказ из Protocol Buffers.
+// Неизвестные поля пропускаются, что позволяет добавлять новые поля в схему
+// без одновременного обновления всех консьюмеров.
+func (Protobuf) Decode(data []byte) (*models.OrderData, error) {
+	fields, err := parseFields(data)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse protobuf: %v", err)
+	}
+
+	o := &models.OrderData{Items: make([]models.Item, 0)}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			o.OrderUID = f.string()
+		case 2:
+			o.TrackNumber = f.string()
+		case 3:
+			o.CustomerID = f.string()
+		case 4:
+			o.DeliveryService = f.string()
+		case 5:
+			if o.DateCreated, err = decodeTimestamp(f.bytes); err != nil {
+				return nil, fmt.Errorf("can't parse date_created: %v", err)
+			}
+		case 6:
+			item, err := decodeItem(f.bytes)
+			if err != nil {
+				return nil, fmt.Errorf("can't parse item: %v", err)
+			}
+			o.Items = append(o.Items, item)
+		case 7:
+			if o.Delivery, err = decodeDelivery(f.bytes); err != nil {
+				return nil, fmt.Errorf("can't parse delivery: %v", err)
+			}
+		case 8:
+			if o.Payment, err = decodePayment(f.bytes); err != nil {
+				return nil, fmt.Errorf("can't parse payment: %v", err)
+			}
+		case 9:
+			if o.AdditionalData, err = decodeAdditionalData(f.bytes); err != nil {
+				return nil, fmt.Errorf("can't parse additional data: %v", err)
+			}
+		}
+	}
+
+	return o, nil
+}
+
+func encodeTimestamp(t time.Time) []byte {
+	var b []byte
+	b = appendInt(b, 1, t.Unix())
+	b = appendInt(b, 2, int64(t.Nanosecond()))
+	return b
+}
+
+func decodeTimestamp(data []byte) (time.Time, error) {
+	fields, err := parseFields(data)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var seconds, nanos int64
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			seconds = f.int()
+		case 2:
+			nanos = f.int()
+		}
+	}
+
+	return time.Unix(seconds, nanos).UTC(), nil
+}
+
+func encodeDelivery(d models.Delivery) []byte {
+	var b []byte
+	b = appendString(b, 1, d.Name)
+	b = appendString(b, 2, d.Phone)
+	b = appendString(b, 3, d.Zip)
+	b = appendString(b, 4, d.City)
+	b = appendString(b, 5, d.Address)
+	b = appendString(b, 6, d.Region)
+	b = appendString(b, 7, d.Email)
+	return b
+}
+
+func decodeDelivery(data []byte) (models.Delivery, error) {
+	var d models.Delivery
+
+	fields, err := parseFields(data)
+	if err != nil {
+		return d, err
+	}
+
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			d.Name = f.string()
+		case 2:
+			d.Phone = f.string()
+		case 3:
+			d.Zip = f.string()
+		case 4:
+			d.City = f.string()
+		case 5:
+			d.Address = f.string()
+		case 6:
+			d.Region = f.string()
+		case 7:
+			d.Email = f.string()
+		}
+	}
+
+	return d, nil
+}
+
+func encodePayment(p models.Payment) []byte {
+	var b []byte
+	b = appendString(b, 1, p.Transaction)
+	b = appendString(b, 2, p.RequestID)
+	b = appendString(b, 3, p.Currency)
+	b = appendString(b, 4, p.Provider)
+	b = appendInt(b, 5, int64(p.Amount))
+	b = appendInt(b, 6, int64(p.PaymentDT))
+	b = appendString(b, 7, p.Bank)
+	b = appendInt(b, 8, int64(p.DeliveryCost))
+	b = appendInt(b, 9, int64(p.GoodsTotal))
+	b = appendInt(b, 10, int64(p.CustomFee))
+	return b
+}
+
+func decodePayment(data []byte) (models.Payment, error) {
+	var p models.Payment
+
+	fields, err := parseFields(data)
+	if err != nil {
+		return p, err
+	}
+
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			p.Transaction = f.string()
+		case 2:
+			p.RequestID = f.string()
+		case 3:
+			p.Currency = f.string()
+		case 4:
+			p.Provider = f.string()
+		case 5:
+			p.Amount = int(f.int())
+		case 6:
+			p.PaymentDT = int(f.int())
+		case 7:
+			p.Bank = f.string()
+		case 8:
+			p.DeliveryCost = int(f.int())
+		case 9:
+			p.GoodsTotal = int(f.int())
+		case 10:
+			p.CustomFee = int(f.int())
+		}
+	}
+
+	return p, nil
+}
+
+func encodeAdditionalData(a models.AdditionalData) []byte {
+	var b []byte
+	b = appendString(b, 1, a.Entry)
+	b = appendString(b, 2, a.Locale)
+	b = appendString(b, 3, a.InternalSignature)
+	b = appendString(b, 4, a.Shardkey)
+	b = appendInt(b, 5, int64(a.SmID))
+	b = appendString(b, 6, a.OofShard)
+	return b
+}
+
+func decodeAdditionalData(data []byte) (models.AdditionalData, error) {
+	var a models.AdditionalData
+
+	fields, err := parseFields(data)
+	if err != nil {
+		return a, err
+	}
+
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			a.Entry = f.string()
+		case 2:
+			a.Locale = f.string()
+		case 3:
+			a.InternalSignature = f.string()
+		case 4:
+			a.Shardkey = f.string()
+		case 5:
+			a.SmID = int(f.int())
+		case 6:
+			a.OofShard = f.string()
+		}
+	}
+
+	return a, nil
+}
+
+func encodeItem(i models.Item) []byte {
+	var b []byte
+	b = appendInt(b, 1, int64(i.ChrtID))
+	b = appendString(b, 2, i.TrackNumber)
+	b = appendDouble(b, 3, i.Price)
+	b = appendString(b, 4, i.Rid)
+	b = appendString(b, 5, i.Name)
+	b = appendDouble(b, 6, i.Sale)
+	b = appendString(b, 7, i.Size)
+	b = appendDouble(b, 8, i.TotalPrice)
+	b = appendInt(b, 9, int64(i.NmID))
+	b = appendString(b, 10, i.Brand)
+	b = appendInt(b, 11, int64(i.Status))
+	return b
+}
+
+func decodeItem(data []byte) (models.Item, error) {
+	var i models.Item
+
+	fields, err := parseFields(data)
+	if err != nil {
+		return i, err
+	}
+
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			i.ChrtID = int(f.int())
+		case 2:
+			i.TrackNumber = f.string()
+		case 3:
+			i.Price = f.double()
+		case 4:
+			i.Rid = f.string()
+		case 5:
+			i.Name = f.string()
+		case 6:
+			i.Sale = f.double()
+		case 7:
+			i.Size = f.string()
+		case 8:
+			i.TotalPrice = f.double()
+		case 9:
+			i.NmID = int(f.int())
+		case 10:
+			i.Brand = f.string()
+		case 11:
+			i.Status = int(f.int())
+		}
+	}
+
+	return i, nil
+}
+
+// appendString добавляет строковое поле. Пустые значения, как и в proto3, не кодируются.
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// appendInt добавляет поле int64. Нулевые значения не кодируются.
+func appendInt(b []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+// appendDouble добавляет поле double. Нулевые значения не кодируются.
+func appendDouble(b []byte, num protowire.Number, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+// appendMessage добавляет вложенное сообщение. Пустые сообщения не кодируются.
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	if len(msg) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// field - одно поле сообщения, прочитанное из wire-формата.
+type field struct {
+	num   protowire.Number
+	typ   protowire.Type
+	v     uint64 // Значение для varint и fixed64.
+	bytes []byte // Значение для строк и вложенных сообщений.
+}
+
+// string возвращает значение строкового поля или пустую строку,
+// если тип поля в сообщении не совпадает с ожидаемым.
+func (f field) string() string {
+	if f.typ != protowire.BytesType {
+		return ""
+	}
+	return string(f.bytes)
+}
+
+// int возвращает значение поля int64 или 0 при несовпадении типа.
+func (f field) int() int64 {
+	if f.typ != protowire.VarintType {
+		return 0
+	}
+	return int64(f.v)
+}
+
+// double возвращает значение поля double или 0 при несовпадении типа.
+func (f field) double() float64 {
+	if f.typ != protowire.Fixed64Type {
+		return 0
+	}
+	return math.Float64frombits(f.v)
+}
+
+// parseFields разбирает сообщение на поля верхнего уровня.
+// Поля неподдерживаемых типов (fixed32, группы) пропускаются.
+func parseFields(b []byte) ([]field, error) {
+	var fields []field
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := field{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			f.v, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
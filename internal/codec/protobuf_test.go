@@ -0,0 +1,189 @@
+package codec
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YusovID/order-service/internal/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protoPath - схема, с которой должен совпадать Protobuf.
+const protoPath = "../../api/proto/order/v1/order.proto"
+
+// notInSchema - поля модели, которых нет в сообщениях Kafka.
+var notInSchema = map[string]bool{"Status": true, "UpdatedAt": true}
+
+// TestProtobufRoundTrip проверяет, что каждое поле заказа, включая товары,
+// доставку и оплату, переживает кодирование и декодирование без изменений.
+func TestProtobufRoundTrip(t *testing.T) {
+	var order models.OrderData
+	seed := 0
+	fill(t, reflect.ValueOf(&order).Elem(), &seed)
+	order.DateCreated = time.Date(2025, 3, 14, 15, 9, 26, 535897932, time.UTC)
+	order.Payment.CustomFee = -1 // Отрицательные int64 кодируются десятью байтами varint.
+
+	var item models.Item
+	fill(t, reflect.ValueOf(&item).Elem(), &seed)
+	// Пустой товар тоже должен сохраниться, иначе изменится количество товаров.
+	order.Items = []models.Item{item, {}}
+
+	data, err := Protobuf{}.Encode(&order)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, err := Protobuf{}.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, &order) {
+		t.Errorf("round trip mismatch:\n got: %+v\nwant: %+v", got, &order)
+	}
+}
+
+// TestProtobufFieldNumbers проверяет, что номера полей в Protobuf совпадают
+// со схемой order.proto: каждое поле модели кодируется отдельно и
+// сравнивается с номером поля того же имени (JSON-тега) в схеме.
+func TestProtobufFieldNumbers(t *testing.T) {
+	schema := parseSchema(t)
+
+	encoders := map[string]struct {
+		typ    reflect.Type
+		encode func(v reflect.Value) []byte
+	}{
+		"Delivery": {reflect.TypeOf(models.Delivery{}), func(v reflect.Value) []byte {
+			return encodeDelivery(v.Interface().(models.Delivery))
+		}},
+		"Payment": {reflect.TypeOf(models.Payment{}), func(v reflect.Value) []byte {
+			return encodePayment(v.Interface().(models.Payment))
+		}},
+		"AdditionalData": {reflect.TypeOf(models.AdditionalData{}), func(v reflect.Value) []byte {
+			return encodeAdditionalData(v.Interface().(models.AdditionalData))
+		}},
+		"Item": {reflect.TypeOf(models.Item{}), func(v reflect.Value) []byte {
+			return encodeItem(v.Interface().(models.Item))
+		}},
+		"OrderData": {reflect.TypeOf(models.OrderData{}), func(v reflect.Value) []byte {
+			order := v.Interface().(models.OrderData)
+			data, err := Protobuf{}.Encode(&order)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			return data
+		}},
+	}
+
+	for message, enc := range encoders {
+		numbers, ok := schema[message]
+		if !ok {
+			t.Fatalf("message %s is not found in %s", message, protoPath)
+		}
+
+		for i := range enc.typ.NumField() {
+			sf := enc.typ.Field(i)
+			if notInSchema[sf.Name] {
+				continue
+			}
+
+			name := strings.Split(sf.Tag.Get("json"), ",")[0]
+			if sf.Anonymous {
+				name = "additional_data"
+			}
+			want, ok := numbers[name]
+			if !ok {
+				t.Errorf("%s.%s: field %q is not found in %s", message, sf.Name, name, protoPath)
+				continue
+			}
+
+			// Заполняем только одно поле, остальные пустые и не кодируются.
+			v := reflect.New(enc.typ).Elem()
+			seed := 0
+			fill(t, v.Field(i), &seed)
+			if sf.Type == reflect.TypeOf([]models.Item{}) {
+				v.Field(i).Set(reflect.ValueOf([]models.Item{{Rid: "rid"}}))
+			}
+
+			fields, err := parseFields(enc.encode(v))
+			if err != nil {
+				t.Fatalf("%s.%s: parseFields() error = %v", message, sf.Name, err)
+			}
+			if len(fields) != 1 || fields[0].num != want {
+				got := make([]protowire.Number, 0, len(fields))
+				for _, f := range fields {
+					got = append(got, f.num)
+				}
+				t.Errorf("%s.%s: encoded as fields %v, want %d", message, sf.Name, got, want)
+			}
+		}
+	}
+}
+
+// fill заполняет поля `v` разными ненулевыми значениями, чтобы перепутанные
+// при кодировании поля не совпали случайно.
+func fill(t *testing.T, v reflect.Value, seed *int) {
+	t.Helper()
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			*seed++
+			v.Set(reflect.ValueOf(time.Unix(int64(*seed)*1000, int64(*seed)).UTC()))
+			return
+		}
+		for i := range v.NumField() {
+			if notInSchema[v.Type().Field(i).Name] {
+				continue
+			}
+			fill(t, v.Field(i), seed)
+		}
+	case reflect.String:
+		*seed++
+		v.SetString("v" + strconv.Itoa(*seed))
+	case reflect.Int:
+		*seed++
+		v.SetInt(int64(*seed))
+	case reflect.Float64:
+		*seed++
+		v.SetFloat(float64(*seed) + 0.25)
+	case reflect.Slice:
+		// Товары заполняются отдельно.
+	default:
+		t.Fatalf("unsupported field kind %s, update fill", v.Kind())
+	}
+}
+
+// parseSchema возвращает номера полей каждого сообщения order.proto.
+func parseSchema(t *testing.T) map[string]map[string]protowire.Number {
+	t.Helper()
+
+	data, err := os.ReadFile(protoPath)
+	if err != nil {
+		t.Fatalf("can't read schema: %v", err)
+	}
+
+	messageRe := regexp.MustCompile(`^message\s+(\w+)\s*\{`)
+	fieldRe := regexp.MustCompile(`^(?:repeated\s+)?[\w.]+\s+(\w+)\s*=\s*(\d+);`)
+
+	schema := make(map[string]map[string]protowire.Number)
+	var current map[string]protowire.Number
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if m := messageRe.FindStringSubmatch(line); m != nil {
+			current = make(map[string]protowire.Number)
+			schema[m[1]] = current
+			continue
+		}
+		if m := fieldRe.FindStringSubmatch(line); m != nil && current != nil {
+			n, _ := strconv.Atoi(m[2])
+			current[m[1]] = protowire.Number(n)
+		}
+	}
+
+	return schema
+}
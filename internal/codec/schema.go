@@ -0,0 +1,92 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/YusovID/order-service/internal/models"
+)
+
+// Версии схемы тела сообщения о заказе (заголовок или поле schema_version).
+const (
+	SchemaV1 = "1" // Плоская модель models.OrderData в формате kafka.encoding.
+	SchemaV2 = "2" // Вложенная JSON-модель orderV2.
+)
+
+// ErrUnknownSchema возвращается для сообщений с неподдерживаемой версией схемы.
+var ErrUnknownSchema = errors.New("unknown schema version")
+
+// Decoder декодирует тело сообщения одной версии схемы в заказ.
+type Decoder interface {
+	Decode(data []byte) (*models.OrderData, error)
+}
+
+// Versioned декодирует сообщения разных версий схемы в models.OrderData.
+// Благодаря этому продюсеры можно переводить на новую схему постепенно:
+// консьюмер принимает обе версии одновременно.
+type Versioned struct {
+	decoders map[string]Decoder
+}
+
+// NewVersioned создает декодер, который разбирает сообщения версии 1
+// кодеком `v1` (формат из kafka.encoding), а версии 2 - как JSON V2.
+func NewVersioned(v1 Decoder) *Versioned {
+	return &Versioned{
+		decoders: map[string]Decoder{
+			SchemaV1: v1,
+			SchemaV2: JSONV2{},
+		},
+	}
+}
+
+// Register добавляет декодер для версии схемы `version` или заменяет
+// существующий. Вызывается до начала декодирования.
+func (v *Versioned) Register(version string, d Decoder) {
+	v.decoders[version] = d
+}
+
+// Decode декодирует сообщение версии `version`. Если версия не передана
+// (нет заголовка schema_version), она берется из поля schema_version
+// JSON-тела, а при его отсутствии считается равной 1.
+func (v *Versioned) Decode(version string, data []byte) (*models.OrderData, error) {
+	if version == "" {
+		version = bodySchemaVersion(data)
+	}
+
+	d, ok := v.decoders[version]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, supported: %v", ErrUnknownSchema, version, v.versions())
+	}
+	return d.Decode(data)
+}
+
+// versions возвращает отсортированный список поддерживаемых версий схемы.
+func (v *Versioned) versions() []string {
+	versions := make([]string, 0, len(v.decoders))
+	for version := range v.decoders {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// bodySchemaVersion читает поле schema_version из JSON-тела сообщения.
+// Поле может быть строкой или числом. Для тел не в формате JSON
+// (например, Protocol Buffers) и без поля возвращается SchemaV1.
+func bodySchemaVersion(data []byte) string {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return SchemaV1
+	}
+
+	var body struct {
+		SchemaVersion json.RawMessage `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil || len(body.SchemaVersion) == 0 {
+		return SchemaV1
+	}
+	return strings.Trim(string(body.SchemaVersion), `"`)
+}
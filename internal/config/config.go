@@ -6,6 +6,8 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"time"
@@ -15,66 +17,420 @@ import (
 
 // Config - это корневая структура, объединяющая все конфигурационные
 // параметры приложения. Она загружается при старте сервиса.
+//
+// У всех параметров, кроме пароля PostgreSQL, есть значения по умолчанию
+// для локального запуска (localhost, стандартные порты, окружение local).
 type Config struct {
-	Env        string     `yaml:"env" env:"ENV" env-required:"true"`
-	Postgres   Postgres   `yaml:"postgres" env-required:"true"`
-	Redis      Redis      `yaml:"redis" env-required:"true"`
-	Kafka      Kafka      `yaml:"kafka" env-required:"true"`
-	HTTPServer HTTPServer `yaml:"http_server" env-required:"true"`
+	Env        string     `yaml:"env" env:"ENV" env-default:"local"` // local, dev или prod.
+	LogLevel   string     `yaml:"log_level" env:"LOG_LEVEL"`         // debug, info, warn или error. Пусто - по окружению.
+	LogFormat  string     `yaml:"log_format" env:"LOG_FORMAT"`       // json или pretty. Пусто - pretty для local, json для остальных.
+	Postgres   Postgres   `yaml:"postgres"`
+	Redis      Redis      `yaml:"redis"`
+	Kafka      Kafka      `yaml:"kafka"`
+	HTTPServer HTTPServer `yaml:"http_server"`
+	Health     Health     `yaml:"health"`
+	Processor  Processor  `yaml:"processor"`
+	Vault      Vault      `yaml:"vault"`
+}
+
+// Vault содержит параметры чтения учетных данных из HashiCorp Vault при
+// старте. Секрет по пути Path в KV-хранилище Mount может содержать ключи
+// postgres_username, postgres_password, redis_username, redis_password и
+// redis_sentinel_password; они заменяют значения из конфигурации.
+// Пустой Address отключает Vault.
+type Vault struct {
+	Address   string `yaml:"address" env:"VAULT_ADDR"`
+	Namespace string `yaml:"namespace" env:"VAULT_NAMESPACE"` // Пространство имен Vault Enterprise.
+
+	// Аутентификация: по токену Token или, если он не задан, по AppRole
+	// (RoleID и SecretID, метод смонтирован по пути AuthPath). Токен и
+	// SecretID можно передать файлами: VAULT_TOKEN_FILE, VAULT_SECRET_ID_FILE.
+	Token    string `yaml:"token" env:"VAULT_TOKEN"`
+	RoleID   string `yaml:"role_id" env:"VAULT_ROLE_ID"`
+	SecretID string `yaml:"secret_id" env:"VAULT_SECRET_ID"`
+	AuthPath string `yaml:"auth_path" env:"VAULT_AUTH_PATH" env-default:"approle"`
+
+	Mount     string        `yaml:"mount" env:"VAULT_MOUNT" env-default:"secret"`      // Путь KV-хранилища.
+	Path      string        `yaml:"path" env:"VAULT_PATH" env-default:"order-service"` // Путь секрета в хранилище.
+	KVVersion int           `yaml:"kv_version" env:"VAULT_KV_VERSION" env-default:"2"` // Версия KV-хранилища: 1 или 2.
+	Timeout   time.Duration `yaml:"timeout" env:"VAULT_TIMEOUT" env-default:"5s"`      // Ограничение времени одного запроса.
+}
+
+// Processor содержит параметры обработки заказов.
+type Processor struct {
+	// Workers - сколько шардов пачки обрабатываются параллельно. Пока
+	// хранилище недоступно, воркеров временно становится меньше.
+	Workers int `yaml:"workers" env:"PROCESSOR_WORKERS" env-default:"10"`
+
+	// BatchInterval - период обработки накопленной пачки сообщений. BatchSize -
+	// размер пачки, при котором она обрабатывается сразу. 0 - без ограничения.
+	BatchInterval time.Duration `yaml:"batch_interval" env:"PROCESSOR_BATCH_INTERVAL" env-default:"1s"`
+	BatchSize     int           `yaml:"batch_size" env:"PROCESSOR_BATCH_SIZE" env-default:"0"`
+
+	// OrderBuffer - емкость буфера сообщений между консьюмером и обработчиком.
+	// По его заполнению консьюмер приостанавливает чтение из Kafka.
+	// CommitBuffer - емкость буфера подтверждений обратно консьюмеру.
+	OrderBuffer  int `yaml:"order_buffer" env:"PROCESSOR_ORDER_BUFFER" env-default:"1000"`
+	CommitBuffer int `yaml:"commit_buffer" env:"PROCESSOR_COMMIT_BUFFER" env-default:"0"`
+
+	// Retry - повторы сохранения заказа, прежде чем сообщение уйдет в DLQ.
+	Retry ProcessorRetry `yaml:"retry"`
+
+	// BatchWrites - максимум новых заказов, сохраняемых в одной транзакции.
+	// Пачка заметно ускоряет запись под нагрузкой; если ее транзакция не
+	// удалась, заказы сохраняются по одному. 0 или 1 - транзакция на каждый заказ.
+	BatchWrites int `yaml:"batch_writes" env:"PROCESSOR_BATCH_WRITES" env-default:"0"`
+
+	// Middlewares - промежуточные обработчики вокруг обработки сообщений
+	// в порядке вызова. Встроенные: recover (перехват паники с отправкой
+	// сообщения в DLQ) и log (отладочный лог времени обработки). Другие
+	// добавляются через processor.RegisterMiddleware.
+	Middlewares []string `yaml:"middlewares" env:"PROCESSOR_MIDDLEWARES" env-separator:","`
+
+	// Consistency - что делать с заказом, суммы оплаты которого не сходятся
+	// с товарами: flag (только лог и метрики), correct (исправить суммы)
+	// или reject (отправить в DLQ).
+	Consistency string `yaml:"consistency" env:"PROCESSOR_CONSISTENCY" env-default:"flag"`
+
+	// CommitStrategy - когда подтверждать обработанные сообщения: message
+	// (каждое сразу, сообщения с ошибками сохранения уходят в DLQ) или batch
+	// (пачку целиком, только когда все ее сообщения обработаны успешно).
+	CommitStrategy string `yaml:"commit_strategy" env:"PROCESSOR_COMMIT_STRATEGY" env-default:"message"`
+
+	// DrainTimeout - сколько при остановке сервиса обработчик продолжает
+	// обрабатывать и подтверждать уже прочитанные из Kafka сообщения.
+	// Должен превышать время ожидания подтверждений консьюмером (10s).
+	DrainTimeout time.Duration `yaml:"drain_timeout" env:"PROCESSOR_DRAIN_TIMEOUT" env-default:"30s"`
+
+	// PriorityWorkers - воркеры, выделенные для срочных сообщений (заголовок
+	// `priority: high`). Срочные сообщения обрабатываются сразу, не дожидаясь
+	// пачки, но подтверждаются только после более ранних сообщений партиции.
+	// 0 - срочные сообщения обрабатываются вместе с остальными.
+	PriorityWorkers int `yaml:"priority_workers" env:"PROCESSOR_PRIORITY_WORKERS" env-default:"0"`
+
+	// DryRun - режим проверки без записи для теневого запуска на боевых
+	// топиках: сообщения декодируются, проверяются и учитываются в метриках,
+	// но заказы не сохраняются, а в DLQ и карантин ничего не отправляется.
+	// Фоновые задачи, изменяющие базу (outbox, архивация, секции), не запускаются,
+	// миграции (postgres.auto_migrate) не применяются.
+	// Консьюмеры входят в отдельную группу `<group.id>-dryrun`, чтобы не
+	// забирать партиции и не коммитить офсеты основной группы.
+	DryRun bool `yaml:"dry_run" env:"PROCESSOR_DRY_RUN" env-default:"false"`
+
+	// CrashOnPanic - не перехватывать паники в пуле воркеров обработчика.
+	// По умолчанию паника пишется в лог и метрики, а процесс продолжает работу.
+	CrashOnPanic bool `yaml:"crash_on_panic" env:"PROCESSOR_CRASH_ON_PANIC" env-default:"false"`
+
+	Scrub Scrub `yaml:"scrub"`
+}
+
+// ProcessorRetry определяет повторы сохранения заказа обработчиком. Они
+// реже и дольше повторов хранилища (Postgres.Retry), чтобы пережить
+// кратковременную недоступность базы.
+type ProcessorRetry struct {
+	Attempts       int           `yaml:"attempts" env:"PROCESSOR_RETRY_ATTEMPTS" env-default:"3"`                   // Число попыток, включая первую.
+	InitialBackoff time.Duration `yaml:"initial_backoff" env:"PROCESSOR_RETRY_INITIAL_BACKOFF" env-default:"500ms"` // Пауза перед второй попыткой.
+	MaxBackoff     time.Duration `yaml:"max_backoff" env:"PROCESSOR_RETRY_MAX_BACKOFF" env-default:"10s"`           // Максимальная пауза между попытками.
+}
+
+// Scrub содержит параметры обезличивания персональных данных заказа
+// перед сохранением. Пустой список полей отключает обезличивание.
+type Scrub struct {
+	// Fields - поля доставки: name, phone, email, address, zip.
+	Fields []string `yaml:"fields" env:"PROCESSOR_SCRUB_FIELDS" env-separator:","`
+	// Mode - hash (HMAC-SHA256 с ключом Salt) или mask (звездочки).
+	Mode string `yaml:"mode" env:"PROCESSOR_SCRUB_MODE" env-default:"hash"`
+	Salt string `yaml:"salt" env:"PROCESSOR_SCRUB_SALT"`
 }
 
 // Postgres содержит параметры для подключения к базе данных PostgreSQL.
 type Postgres struct {
-	Username string `yaml:"username" env:"POSTGRES_USER" env-required:"true"`
-	Password string `yaml:"password" env:"POSTGRES_PASSWORD" env-required:"true"`
-	Host     string `yaml:"host" env:"POSTGRES_HOST" env-required:"true"`
-	Port     string `yaml:"port" env:"POSTGRES_PORT" env-required:"true"`
-	Database string `yaml:"database" env:"POSTGRES_DB" env-required:"true"`
+	Username string `yaml:"username" env:"POSTGRES_USER" env-default:"postgres"`
+	Password string `yaml:"password" env:"POSTGRES_PASSWORD"` // Обязателен. Можно передать файлом: POSTGRES_PASSWORD_FILE.
+	Host     string `yaml:"host" env:"POSTGRES_HOST" env-default:"localhost"`
+	Port     string `yaml:"port" env:"POSTGRES_PORT" env-default:"5432"`
+	Database string `yaml:"database" env:"POSTGRES_DB" env-default:"postgres"`
+
+	// Параметры соединения. SSLMode принимает значения libpq: disable, allow,
+	// prefer, require, verify-ca, verify-full. Управляемые PostgreSQL обычно
+	// требуют require или verify-full. SearchPath - список схем через запятую,
+	// пустое значение оставляет настройку сервера.
+	SSLMode        string        `yaml:"sslmode" env:"POSTGRES_SSLMODE" env-default:"disable"`
+	SSLRootCert    string        `yaml:"sslrootcert" env:"POSTGRES_SSLROOTCERT"` // CA-сертификат для verify-ca/verify-full.
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"POSTGRES_CONNECT_TIMEOUT" env-default:"10s"`
+	SearchPath     string        `yaml:"search_path" env:"POSTGRES_SEARCH_PATH"`
+	Compat         Compat        `yaml:"compat"`
+	Pool           Pool          `yaml:"pool"`
+	Retry          Retry         `yaml:"retry"`
+
+	// AutoMigrate включает применение встроенных миграций при старте сервиса
+	// (вместо отдельного запуска cmd/migrator). MigrationsTable - таблица
+	// истории миграций, та же, что у cmd/migrator.
+	AutoMigrate     bool   `yaml:"auto_migrate" env:"POSTGRES_AUTO_MIGRATE" env-default:"false"`
+	MigrationsTable string `yaml:"migrations_table" env:"MIGRATIONS_TABLE" env-default:"migrations"`
+
+	// Partitions - обслуживание месячных секций таблиц orders и order_items.
+	Partitions Partitions `yaml:"partitions"`
+
+	// Archive - перенос старых заказов в архив.
+	Archive Archive `yaml:"archive"`
+
+	// DisableListenChanges отключает подписку на уведомления об изменении
+	// заказов (LISTEN orders_changed), по которым сбрасывается кэш, в том
+	// числе при записи другими экземплярами сервиса. По умолчанию подписка
+	// включена.
+	DisableListenChanges bool `yaml:"disable_listen_changes" env:"POSTGRES_DISABLE_LISTEN_CHANGES"`
+
+	// Replica - реплика для чтения. Учетные данные и база те же, что у
+	// основного сервера. Пустой Host - все запросы идут на основной сервер.
+	Replica Replica `yaml:"replica"`
+
+	// QueryTimeout ограничивает время одной операции хранилища (запроса или
+	// транзакции SaveOrder) на стороне клиента. StatementTimeout передается
+	// серверу как `statement_timeout` для всех соединений пула и прерывает
+	// зависший запрос, даже если клиент не дождался ответа. Отрицательное
+	// значение (например, -1s) снимает ограничение.
+	QueryTimeout     time.Duration `yaml:"query_timeout" env:"POSTGRES_QUERY_TIMEOUT" env-default:"5s"`
+	StatementTimeout time.Duration `yaml:"statement_timeout" env:"POSTGRES_STATEMENT_TIMEOUT" env-default:"5s"`
+}
+
+// Pool определяет настройки пула соединений с PostgreSQL (pgxpool).
+// В отличие от database/sql, pgxpool не ограничивает число простаивающих
+// соединений: лишние закрываются по ConnMaxIdleTime, но не ниже MinConns.
+type Pool struct {
+	MaxConns        int32         `yaml:"max_conns" env:"POSTGRES_MAX_CONNS" env-default:"20"`                   // Максимум открытых соединений.
+	MinConns        int32         `yaml:"min_conns" env:"POSTGRES_MIN_CONNS" env-default:"2"`                    // Минимум соединений, которые пул держит открытыми.
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"POSTGRES_CONN_MAX_LIFETIME" env-default:"30m"`  // Время жизни соединения.
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"POSTGRES_CONN_MAX_IDLE_TIME" env-default:"5m"` // Время простоя, после которого соединение закрывается.
+}
+
+// Replica содержит адрес реплики PostgreSQL для запросов на чтение.
+type Replica struct {
+	Host string `yaml:"host" env:"POSTGRES_REPLICA_HOST"`
+	Port string `yaml:"port" env:"POSTGRES_REPLICA_PORT" env-default:"5432"`
+}
+
+// Partitions определяет, как сервис создает секции таблиц заказов заранее.
+type Partitions struct {
+	Ahead    int           `yaml:"ahead" env:"POSTGRES_PARTITIONS_AHEAD" env-default:"3"`         // Сколько месяцев вперед держать созданными. Отрицательное значение - не создавать.
+	Interval time.Duration `yaml:"interval" env:"POSTGRES_PARTITIONS_INTERVAL" env-default:"24h"` // Период проверки.
+}
+
+// Archive определяет фоновый перенос заказов старше RetentionDays
+// в таблицу orders_archive с удалением из рабочих таблиц и кэша.
+type Archive struct {
+	RetentionDays int           `yaml:"retention_days" env:"POSTGRES_ARCHIVE_RETENTION_DAYS"`           // Срок хранения в рабочих таблицах. 0 - архивация выключена.
+	Interval      time.Duration `yaml:"interval" env:"POSTGRES_ARCHIVE_INTERVAL" env-default:"1h"`      // Период запуска.
+	BatchSize     int           `yaml:"batch_size" env:"POSTGRES_ARCHIVE_BATCH_SIZE" env-default:"500"` // Заказов в одной транзакции.
+}
+
+// Retry определяет повторы операций с PostgreSQL при временных ошибках
+// (deadlock, конфликт сериализации, обрыв соединения). Пауза между
+// попытками растет экспоненциально от InitialBackoff до MaxBackoff.
+type Retry struct {
+	Attempts       int           `yaml:"attempts" env:"POSTGRES_RETRY_ATTEMPTS" env-default:"3"`                   // Число попыток, включая первую.
+	InitialBackoff time.Duration `yaml:"initial_backoff" env:"POSTGRES_RETRY_INITIAL_BACKOFF" env-default:"100ms"` // Пауза перед второй попыткой.
+	MaxBackoff     time.Duration `yaml:"max_backoff" env:"POSTGRES_RETRY_MAX_BACKOFF" env-default:"2s"`            // Максимальная пауза между попытками.
+}
+
+// Compat описывает режим совместимости после миграций схемы. Пока режим
+// активен, сервис пишет данные одновременно в старую и новую раскладку колонок,
+// поэтому предыдущую версию бинарника можно откатить без потери данных.
+type Compat struct {
+	Enabled bool      `yaml:"enabled" env:"POSTGRES_COMPAT_ENABLED"`
+	Until   time.Time `yaml:"until" env:"POSTGRES_COMPAT_UNTIL"` // Конец окна совместимости (RFC3339). Пустое значение - без ограничения.
 }
 
 // Redis содержит параметры для подключения к серверу Redis.
 type Redis struct {
-	Host     string `yaml:"host" env:"REDIS_HOST" env-required:"true"`
-	Port     string `yaml:"port" env:"REDIS_PORT" env-required:"true"`
+	Host     string `yaml:"host" env:"REDIS_HOST" env-default:"localhost"`
+	Port     string `yaml:"port" env:"REDIS_PORT" env-default:"6379"`
 	DB       int    `yaml:"db" env:"REDIS_DB"`
-	Password string `yaml:"password" env:"REDIS_PASSWORD"`
+	Username string `yaml:"username" env:"REDIS_USERNAME"` // Пользователь ACL (Redis 6+). Пусто - пользователь default.
+	Password string `yaml:"password" env:"REDIS_PASSWORD"` // Можно передать файлом: REDIS_PASSWORD_FILE.
+	TLS      TLS    `yaml:"tls" env-prefix:"REDIS_TLS_"`
+
+	// TTL - срок жизни заказа в кэше, NegativeTTL - срок, на который
+	// запоминается отсутствие заказа (чтобы повторные запросы несуществующего
+	// заказа не шли в PostgreSQL). Отрицательное значение (например, -1s):
+	// заказ хранится бессрочно, отсутствие заказа не запоминается. Ноль
+	// из файла конфигурации заменяется значением по умолчанию.
+	TTL         time.Duration `yaml:"ttl" env:"REDIS_TTL" env-default:"24h"`
+	NegativeTTL time.Duration `yaml:"negative_ttl" env:"REDIS_NEGATIVE_TTL" env-default:"30s"`
+
+	// WarmLimit и WarmDays ограничивают полный прогрев кэша при старте
+	// последними WarmLimit заказами и заказами не старше WarmDays дней
+	// (по date_created). 0 - без ограничения.
+	WarmLimit int `yaml:"warm_limit" env:"REDIS_WARM_LIMIT"`
+	WarmDays  int `yaml:"warm_days" env:"REDIS_WARM_DAYS"`
+
+	// RefreshInterval - период догрузки в кэш измененных заказов после
+	// прогрева. Отрицательное значение отключает догрузку.
+	RefreshInterval time.Duration `yaml:"refresh_interval" env:"REDIS_REFRESH_INTERVAL" env-default:"1m"`
+
+	// LedgerTTL - сколько хранятся отметки об обработанных сообщениях Kafka
+	// (защита от повторной обработки после падения или ребалансировки).
+	// Отрицательное значение отключает учет.
+	LedgerTTL time.Duration `yaml:"ledger_ttl" env:"REDIS_LEDGER_TTL" env-default:"24h"`
+
+	// Namespace - префикс всех ключей сервиса (`<namespace>:order:<order_uid>`),
+	// чтобы несколько окружений могли использовать один Redis.
+	Namespace string `yaml:"namespace" env:"REDIS_NAMESPACE" env-default:"order-service"`
+
+	// Sentinel - подключение через Redis Sentinel. Если MasterName задан,
+	// адрес мастера запрашивается у sentinel, а Host и Port не используются.
+	Sentinel Sentinel `yaml:"sentinel"`
+}
+
+// TLS определяет параметры TLS-соединения. CACert - сертификат CA для
+// проверки сервера (пусто - системные сертификаты), Cert и Key - клиентский
+// сертификат для взаимной аутентификации (необязательны). Имена переменных
+// окружения задаются с префиксом компонента, например REDIS_TLS_ENABLED.
+type TLS struct {
+	Enabled    bool   `yaml:"enabled" env:"ENABLED"`
+	CACert     string `yaml:"ca_cert" env:"CA_CERT"`
+	Cert       string `yaml:"cert" env:"CERT"`
+	Key        string `yaml:"key" env:"KEY"`
+	ServerName string `yaml:"server_name" env:"SERVER_NAME"` // Имя для проверки сертификата, если отличается от хоста.
+}
+
+// Sentinel содержит параметры Redis Sentinel. Password используется
+// для самих sentinel, для мастера - Redis.Password.
+type Sentinel struct {
+	MasterName string   `yaml:"master_name" env:"REDIS_SENTINEL_MASTER"`
+	Addrs      []string `yaml:"addrs" env:"REDIS_SENTINEL_ADDRS"`       // Адреса sentinel (host:port).
+	Password   string   `yaml:"password" env:"REDIS_SENTINEL_PASSWORD"` // Можно передать файлом: REDIS_SENTINEL_PASSWORD_FILE.
 }
 
 // Kafka содержит параметры для взаимодействия с Apache Kafka,
 // включая настройки для продюсера и консьюмера.
 type Kafka struct {
-	BootstrapServers []string `yaml:"bootstrap.servers" env:"KAFKA_BOOTSTRAP_SERVERS" env-required:"true"`
-	Topic            string   `yaml:"topic" env-required:"true"`
-	Producer         Producer `yaml:"producer" env-required:"true"`
-	Consumer         Consumer `yaml:"consumer" env-required:"true"`
+	BootstrapServers []string `yaml:"bootstrap.servers" env:"KAFKA_BOOTSTRAP_SERVERS" env-default:"localhost:9092"`
+	Topic            Topics   `yaml:"topic" env:"KAFKA_TOPIC" env-default:"orders"`     // Один топик или список топиков для чтения.
+	Encoding         string   `yaml:"encoding" env:"KAFKA_ENCODING" env-default:"json"` // Формат сообщений: json или protobuf.
+	DLQTopic         string   `yaml:"dlq_topic" env:"KAFKA_DLQ_TOPIC"`                  // Топик для необработанных сообщений. Пустое значение отключает DLQ.
+
+	// UpdatesTopic - топик сообщений об изменении и отмене заказов
+	// (models.OrderUpdate в JSON). Должен быть в списке `topic`.
+	// Пустое значение отключает обработку изменений.
+	UpdatesTopic string `yaml:"updates_topic" env:"KAFKA_UPDATES_TOPIC"`
+
+	// QuarantineTopic - топик для сообщений, обработка которых прервалась
+	// (падение или перезапуск сервиса) QuarantineAfter раз подряд. Такие
+	// сообщения больше не обрабатываются, чтобы не блокировать партицию.
+	// Попытки считаются в Redis. Пустое значение отключает карантин.
+	QuarantineTopic string `yaml:"quarantine_topic" env:"KAFKA_QUARANTINE_TOPIC"`
+	QuarantineAfter int    `yaml:"quarantine_after" env:"KAFKA_QUARANTINE_AFTER" env-default:"3"`
+
+	// AutoCreateTopics включает создание недостающих топиков при старте.
+	AutoCreateTopics       bool  `yaml:"auto_create_topics" env:"KAFKA_AUTO_CREATE_TOPICS"`
+	TopicPartitions        int32 `yaml:"topic_partitions" env-default:"1"`         // Количество партиций новых топиков.
+	TopicReplicationFactor int16 `yaml:"topic_replication_factor" env-default:"1"` // Фактор репликации новых топиков.
+
+	SASL     SASL     `yaml:"sasl" env-prefix:"KAFKA_SASL_"`
+	Events   Events   `yaml:"events"`
+	Producer Producer `yaml:"producer"`
+	Consumer Consumer `yaml:"consumer"`
+}
+
+// SASL определяет аутентификацию в Kafka для всех клиентов сервиса
+// (консьюмеры, продюсеры, админ-клиенты). Пустой Username отключает SASL.
+type SASL struct {
+	Mechanism string `yaml:"mechanism" env:"MECHANISM" env-default:"PLAIN"` // PLAIN, SCRAM-SHA-256 или SCRAM-SHA-512.
+	Username  string `yaml:"username" env:"USERNAME"`
+	Password  string `yaml:"password" env:"PASSWORD"` // Можно передать файлом: KAFKA_SASL_PASSWORD_FILE.
+}
+
+// Events определяет настройки публикации событий о заказах.
+type Events struct {
+	// Topic - топик событий (order.created и др.). Пустое значение отключает публикацию.
+	Topic string `yaml:"topic" env:"KAFKA_EVENTS_TOPIC"`
+	// TransactionalID - идентификатор транзакционного продюсера событий.
+	// Должен быть уникальным для каждого экземпляра сервиса.
+	TransactionalID string `yaml:"transactional_id" env:"KAFKA_EVENTS_TRANSACTIONAL_ID" env-default:"order-service-events"`
+	// RelayInterval и RelayBatchSize задают, как часто и какими пачками
+	// события из таблицы outbox публикуются в Topic. Если Topic пуст,
+	// события копятся в outbox до включения публикации.
+	RelayInterval  time.Duration `yaml:"relay_interval" env:"KAFKA_EVENTS_RELAY_INTERVAL" env-default:"1s"`
+	RelayBatchSize int           `yaml:"relay_batch_size" env:"KAFKA_EVENTS_RELAY_BATCH_SIZE" env-default:"100"`
 }
 
 // Producer определяет настройки для Kafka-продюсера.
 type Producer struct {
-	Acks              int    `yaml:"acks" env-required:"true"`
+	Acks              int    `yaml:"acks" env-default:"-1"` // -1 (все реплики) или 1 (лидер).
 	EnableIdempotence bool   `yaml:"enable.idempotence"`
 	Retries           int    `yaml:"retries"`
 	TransactionalId   string `yaml:"transactional.id"`
+
+	// Сжатие и батчинг. Нулевые значения оставляют умолчания sarama.
+	CompressionType  string        `yaml:"compression.type" env:"KAFKA_PRODUCER_COMPRESSION"` // none, gzip, snappy, lz4 или zstd.
+	CompressionLevel int           `yaml:"compression.level"`                                 // Уровень сжатия (для gzip и zstd).
+	Linger           time.Duration `yaml:"linger"`                                            // Время накопления батча перед отправкой.
+	BatchSize        int           `yaml:"batch.size"`                                        // Размер батча в байтах, при котором он отправляется сразу.
+	BatchMessages    int           `yaml:"batch.num.messages"`                                // Количество сообщений, при котором батч отправляется сразу.
 }
 
 // Consumer определяет настройки для Kafka-консьюмера.
 type Consumer struct {
-	GroupId          string `yaml:"group.id" env-required:"true"`
-	AutoOffsetReset  string `yaml:"auto.offset.reset" env-required:"true"`
-	EnableAutoCommit bool   `yaml:"enable.auto.commit"`
+	GroupId          string `yaml:"group.id" env:"KAFKA_GROUP_ID" env-default:"order-service-group"`
+	AutoOffsetReset  string `yaml:"auto.offset.reset" env-default:"earliest"` // earliest или latest.
+	EnableAutoCommit bool   `yaml:"enable.auto.commit"`                       // Фоновый коммит офсетов подтвержденных сообщений.
 	SecurityProtocol string `yaml:"security.protocol"`
-	IsolationLevel   int8   `yaml:"isolation.level"`
+	IsolationLevel   string `yaml:"isolation.level" env-default:"read_committed"` // read_committed (1) или read_uncommitted (0).
+
+	// CommitBatchSize - количество обработанных сообщений, после которого коммитятся офсеты.
+	CommitBatchSize int `yaml:"commit.batch_size" env-default:"100"`
+	// CommitInterval - период коммита офсетов, если сообщений меньше, чем CommitBatchSize.
+	// Должен быть больше 0.
+	CommitInterval time.Duration `yaml:"commit.interval" env-default:"5s"`
+
+	// ReconnectErrorThreshold - количество ошибок группы консьюмеров за ReconnectWindow,
+	// после которого консьюмер заново подключается к группе. 0 отключает перезапуск.
+	ReconnectErrorThreshold int           `yaml:"reconnect.error_threshold"`
+	ReconnectWindow         time.Duration `yaml:"reconnect.window" env-default:"1m"`
+
+	// Тюнинг сессии и выборки сообщений. Нулевые значения оставляют умолчания sarama.
+	SessionTimeout    time.Duration `yaml:"session.timeout"`               // Таймаут сессии участника группы.
+	HeartbeatInterval time.Duration `yaml:"heartbeat.interval"`            // Период heartbeat-запросов (меньше трети SessionTimeout).
+	MaxPollInterval   time.Duration `yaml:"max.poll.interval"`             // Время на обработку до исключения из группы (rebalance timeout).
+	FetchMinBytes     int32         `yaml:"fetch.min.bytes"`               // Минимальный объем данных в ответе на fetch-запрос.
+	FetchMaxBytes     int32         `yaml:"fetch.max.bytes"`               // Максимальный объем данных в ответе на fetch-запрос.
+	MaxPartitionFetch int32         `yaml:"max.partition.fetch.bytes"`     // Объем данных на партицию в одном fetch-запросе.
+	FetchMaxWait      time.Duration `yaml:"fetch.max.wait"`                // Максимальное ожидание FetchMinBytes брокером.
+	RebalanceStrategy string        `yaml:"partition.assignment.strategy"` // range, roundrobin или sticky.
+
+	// Sessions - количество сессий группы консьюмеров в одном процессе.
+	// Каждая сессия получает свою часть партиций и читает их параллельно.
+	Sessions int `yaml:"sessions" env:"KAFKA_CONSUMER_SESSIONS" env-default:"1"`
+
+	// LagInterval - период опроса лага группы консьюмеров для метрик.
+	// Отрицательное значение отключает экспорт лага.
+	LagInterval time.Duration `yaml:"lag.interval" env-default:"15s"`
 }
 
 // HTTPServer содержит параметры для запуска встроенного HTTP-сервера.
 type HTTPServer struct {
-	Address     string        `yaml:"address" env-required:"true"`
-	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
-	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	Address string `yaml:"address" env:"HTTP_ADDRESS" env-default:":8080"`
+	// AdminAddress - адрес отдельного сервера для /admin/* (смена уровня
+	// логирования и т.д.). По умолчанию слушает только localhost: эти
+	// ручки не должны быть доступны вместе с публичным API.
+	AdminAddress string        `yaml:"admin_address" env:"HTTP_ADMIN_ADDRESS" env-default:"localhost:8082"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"4s"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env-default:"60s"`
 }
 
-// MustLoad читает конфигурацию из файла, путь к которому указан в переменной
-// окружения CONFIG_PATH, и переменных окружения.
+// Health определяет проверки доступности зависимостей (PostgreSQL, Redis)
+// для /readyz и фонового наблюдателя.
+type Health struct {
+	Interval time.Duration `yaml:"interval" env:"HEALTH_INTERVAL" env-default:"15s"` // Период фоновой проверки. Отрицательное значение - без фоновой проверки.
+	Timeout  time.Duration `yaml:"timeout" env:"HEALTH_TIMEOUT" env-default:"2s"`    // Ограничение времени одной проверки.
+}
+
+// MustLoad читает конфигурацию из файла (YAML или JSON), путь к которому
+// указан в переменной окружения CONFIG_PATH, файла профиля рядом с ним (например, config.prod.yml
+// для ENV=prod или CONFIG_PROFILE=prod) и переменных окружения. Без
+// CONFIG_PATH конфигурация читается только из переменных окружения и
+// значений по умолчанию.
 //
 // Функция имеет префикс "Must", так как она вызывает log.Fatalf (паникует)
 // при любой ошибке во время загрузки или парсинга конфигурации. Такой подход
@@ -83,24 +439,58 @@ type HTTPServer struct {
 //
 // Возвращает указатель на заполненную структуру Config.
 func MustLoad() *Config {
+	cfg, err := Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return cfg
+}
+
+// Load загружает конфигурацию так же, как MustLoad, но возвращает ошибку
+// вместо завершения процесса. Используется для перечитывания конфигурации
+// во время работы (SIGHUP).
+func Load() (*Config, error) {
+	var cfg Config
+
 	// Получаем путь к файлу конфигурации из переменной окружения.
+	// Без файла достаточно переменных окружения и значений по умолчанию.
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
-		log.Fatal("CONFIG_PATH is not set")
-	}
+		if err := cleanenv.ReadEnv(&cfg); err != nil {
+			return nil, fmt.Errorf("cannot read config: %s", err)
+		}
+	} else {
+		// Проверяем, существует ли файл по указанному пути.
+		if _, err := os.Stat(configPath); err != nil {
+			return nil, fmt.Errorf("config file does not exist: %s", configPath)
+		}
+
+		// Читаем базовый файл и файл профиля поверх него (см. layerFiles).
+		files, err := layerFiles(configPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := readLayers(files, &cfg); err != nil {
+			return nil, fmt.Errorf("cannot read config: %s", err)
+		}
 
-	// Проверяем, существует ли файл по указанному пути.
-	if _, err := os.Stat(configPath); err != nil {
-		log.Fatalf("config file does not exist: %s", configPath)
+		// Переменные окружения переопределяют значения из файлов.
+		// cleanenv автоматически сопоставляет поля структуры с данными из источников.
+		if err := cleanenv.ReadEnv(&cfg); err != nil {
+			return nil, fmt.Errorf("cannot read config: %s", err)
+		}
 	}
 
-	var cfg Config
+	// Секреты из смонтированных файлов (`*_FILE`) заменяют значения из YAML.
+	if err := cfg.readSecretFiles(); err != nil {
+		return nil, fmt.Errorf("cannot read secrets: %s", err)
+	}
 
-	// Читаем YAML-файл и переменные окружения в структуру Config.
-	// cleanenv автоматически сопоставляет поля структуры с данными из источников.
-	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		log.Fatalf("cannot read config: %s", err)
+	// Пароль из Vault проверяется после его чтения (secrets.Apply).
+	if cfg.Postgres.Password == "" && cfg.Vault.Address == "" {
+		return nil, errors.New("postgres password is required: set POSTGRES_PASSWORD or POSTGRES_PASSWORD_FILE")
 	}
 
-	return &cfg
+	return &cfg, nil
 }
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+// profileEnv - переменная окружения с именем профиля конфигурации.
+// Если она не задана, профилем служит окружение (ENV).
+const profileEnv = "CONFIG_PROFILE"
+
+// layerFiles возвращает файлы конфигурации в порядке применения: базовый
+// файл `path` и, если он существует, файл профиля рядом с ним
+// (`config.yml` и `config.prod.yml` для профиля prod).
+func layerFiles(path string) ([]string, error) {
+	files := []string{path}
+
+	profile := os.Getenv(profileEnv)
+	if profile == "" {
+		profile = os.Getenv("ENV")
+	}
+	if profile == "" {
+		return files, nil
+	}
+
+	ext := filepath.Ext(path)
+	overlay := strings.TrimSuffix(path, ext) + "." + profile + ext
+
+	if _, err := os.Stat(overlay); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return files, nil
+		}
+		return nil, fmt.Errorf("cannot read profile config: %w", err)
+	}
+
+	return append(files, overlay), nil
+}
+
+// readLayers читает файлы `files` по очереди в одну структуру `cfg`. Каждый
+// следующий файл переопределяет только указанные в нем поля (вложенные
+// секции сливаются, списки заменяются целиком), поэтому файлу профиля
+// достаточно содержать отличия от базового.
+func readLayers(files []string, cfg *Config) error {
+	for _, path := range files {
+		if err := parseFile(path, cfg); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// parseFile читает файл `path` в `cfg` в формате, определяемом расширением.
+//
+// JSON - подмножество YAML, поэтому файлы .json разбираются YAML-парсером:
+// ключи те же, что в YAML (`bootstrap.servers`, `drain_timeout`), длительности
+// задаются строками ("30s"), а `topic` может быть строкой или списком.
+func parseFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml", ".json":
+		return cleanenv.ParseYAML(f, cfg)
+	case ".toml":
+		return cleanenv.ParseTOML(f, cfg)
+	default:
+		return fmt.Errorf("unsupported config format %q", ext)
+	}
+}
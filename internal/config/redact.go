@@ -0,0 +1,26 @@
+package config
+
+// redactedMask заменяет значения секретов в выводе конфигурации.
+const redactedMask = "***"
+
+// Redacted возвращает копию конфигурации, в которой пароли, токены и ключи
+// заменены на маску. Пустые значения остаются пустыми, чтобы было видно,
+// задан ли секрет. Используется для вывода конфигурации в лог и /admin/config.
+func (c Config) Redacted() Config {
+	r := c
+	for _, field := range []*string{
+		&r.Postgres.Password,
+		&r.Redis.Password,
+		&r.Redis.Sentinel.Password,
+		&r.Kafka.SASL.Password,
+		&r.Vault.Token,
+		&r.Vault.SecretID,
+		&r.Processor.Scrub.Salt,
+	} {
+		if *field != "" {
+			*field = redactedMask
+		}
+	}
+
+	return r
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// fileSuffix - суффикс переменных окружения, указывающих на файл с секретом
+// (Docker secrets, секреты Kubernetes, смонтированные как файлы).
+const fileSuffix = "_FILE"
+
+// secretFields возвращает поля с секретами, которые можно прочитать из файла,
+// по именам переменных окружения с путем к файлу.
+func (c *Config) secretFields() map[string]*string {
+	return map[string]*string{
+		"POSTGRES_PASSWORD_FILE":       &c.Postgres.Password,
+		"REDIS_PASSWORD_FILE":          &c.Redis.Password,
+		"REDIS_SENTINEL_PASSWORD_FILE": &c.Redis.Sentinel.Password,
+		"KAFKA_SASL_PASSWORD_FILE":     &c.Kafka.SASL.Password,
+		"VAULT_TOKEN_FILE":             &c.Vault.Token,
+		"VAULT_SECRET_ID_FILE":         &c.Vault.SecretID,
+	}
+}
+
+// readSecretFiles заполняет секреты из файлов, указанных в переменных
+// окружения `<NAME>_FILE`. Значение из файла заменяет значение из YAML;
+// задавать одновременно `<NAME>` и `<NAME>_FILE` нельзя. Завершающий
+// перевод строки в файле отбрасывается.
+func (c *Config) readSecretFiles() error {
+	for env, field := range c.secretFields() {
+		path := os.Getenv(env)
+		if path == "" {
+			continue
+		}
+
+		name := strings.TrimSuffix(env, fileSuffix)
+		if _, ok := os.LookupEnv(name); ok {
+			return fmt.Errorf("both %s and %s are set", name, env)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", env, err)
+		}
+
+		*field = strings.TrimRight(string(data), "\r\n")
+	}
+
+	return nil
+}
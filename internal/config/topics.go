@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Topics - список топиков Kafka.
+//
+// В YAML поле можно задать как одной строкой (`topic: orders`), так и
+// списком (`topic: [orders, order-updates]`), поэтому старые конфиги
+// продолжают работать без изменений. В переменных окружения топики
+// перечисляются через запятую.
+type Topics []string
+
+// UnmarshalYAML реализует yaml.Unmarshaler и принимает строку или список строк.
+func (t *Topics) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*t = Topics{value.Value}
+		return nil
+	case yaml.SequenceNode:
+		var topics []string
+		if err := value.Decode(&topics); err != nil {
+			return err
+		}
+		*t = topics
+		return nil
+	default:
+		return fmt.Errorf("topic must be a string or a list of strings")
+	}
+}
+
+// SetValue реализует cleanenv.Setter для чтения топиков из переменной окружения.
+func (t *Topics) SetValue(s string) error {
+	var topics Topics
+	for _, topic := range strings.Split(s, ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	*t = topics
+	return nil
+}
+
+// Primary возвращает основной топик - первый в списке.
+// В него пишет генератор заказов.
+func (t Topics) Primary() string {
+	if len(t) == 0 {
+		return ""
+	}
+	return t[0]
+}
@@ -0,0 +1,28 @@
+// Package correlation создает сквозные идентификаторы корреляции, по которым
+// связываются записи логов, сообщения DLQ и события одного заказа.
+package correlation
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// idSize - длина идентификатора в байтах (32 шестнадцатеричных символа).
+const idSize = 16
+
+// New генерирует случайный идентификатор корреляции для нового сообщения.
+func New() string {
+	b := make([]byte, idSize)
+	_, _ = rand.Read(b) // crypto/rand.Read не возвращает ошибок.
+	return hex.EncodeToString(b)
+}
+
+// FromMessage возвращает идентификатор корреляции сообщения Kafka без
+// заголовка correlation_id. Идентификатор выводится из координат сообщения,
+// поэтому при повторных попытках и повторной доставке он не меняется.
+func FromMessage(topic string, partition int32, offset int64) string {
+	sum := sha256.Sum256([]byte(topic + "/" + strconv.Itoa(int(partition)) + "/" + strconv.FormatInt(offset, 10)))
+	return hex.EncodeToString(sum[:idSize])
+}
@@ -0,0 +1,26 @@
+package correlation
+
+import "testing"
+
+// TestFromMessage проверяет, что идентификатор сообщения не меняется между
+// попытками обработки и различается у разных сообщений.
+func TestFromMessage(t *testing.T) {
+	id := FromMessage("orders", 1, 42)
+	if got := FromMessage("orders", 1, 42); got != id {
+		t.Errorf("FromMessage() = %q on retry, want %q", got, id)
+	}
+	if len(id) != len(New()) {
+		t.Errorf("len(FromMessage()) = %d, want %d as for New()", len(id), len(New()))
+	}
+
+	for _, other := range []string{
+		FromMessage("orders", 1, 43),
+		FromMessage("orders", 2, 42),
+		FromMessage("orders-priority", 1, 42),
+		FromMessage("orders/1", 4, 2),
+	} {
+		if other == id {
+			t.Errorf("FromMessage() = %q for different messages", id)
+		}
+	}
+}
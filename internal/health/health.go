@@ -0,0 +1,115 @@
+// Package health проверяет доступность зависимостей сервиса (PostgreSQL,
+// Redis). Результаты используются эндпоинтом /readyz, а фоновый наблюдатель
+// (Watchdog) пишет в лог, когда зависимость становится недоступной и когда
+// восстанавливается.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/YusovID/order-service/lib/logger/sl"
+)
+
+// Checker - зависимость, доступность которой можно проверить.
+type Checker interface {
+	Healthy(ctx context.Context) error
+}
+
+// Dependency - именованная зависимость.
+type Dependency struct {
+	Name    string
+	Checker Checker
+}
+
+// Watchdog проверяет зависимости и запоминает их последнее состояние.
+type Watchdog struct {
+	deps    []Dependency
+	timeout time.Duration
+	log     *slog.Logger
+
+	mu     sync.Mutex
+	failed map[string]bool // Зависимости, недоступные при последней проверке.
+}
+
+// New создает наблюдателя за зависимостями `deps`.
+// `timeout` ограничивает время проверки одной зависимости.
+func New(log *slog.Logger, timeout time.Duration, deps ...Dependency) *Watchdog {
+	return &Watchdog{
+		deps:    deps,
+		timeout: timeout,
+		log:     log,
+		failed:  make(map[string]bool),
+	}
+}
+
+// Check проверяет все зависимости параллельно и возвращает ошибку каждой
+// недоступной зависимости по ее имени. Пустой результат означает, что
+// все зависимости доступны.
+func (w *Watchdog) Check(ctx context.Context) map[string]error {
+	errs := make([]error, len(w.deps))
+
+	wg := &sync.WaitGroup{}
+	for i, dep := range w.deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, w.timeout)
+			defer cancel()
+			errs[i] = dep.Checker.Healthy(ctx)
+		}()
+	}
+	wg.Wait()
+
+	result := make(map[string]error)
+	for i, dep := range w.deps {
+		if errs[i] != nil {
+			result[dep.Name] = errs[i]
+		}
+	}
+
+	w.record(result)
+	return result
+}
+
+// record (unexported) пишет в лог изменения состояния зависимостей.
+func (w *Watchdog) record(result map[string]error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, dep := range w.deps {
+		err, failed := result[dep.Name]
+		switch {
+		case failed && !w.failed[dep.Name]:
+			w.log.Error("dependency degraded", slog.String("dependency", dep.Name), sl.Err(err))
+		case !failed && w.failed[dep.Name]:
+			w.log.Info("dependency recovered", slog.String("dependency", dep.Name))
+		}
+		w.failed[dep.Name] = failed
+	}
+}
+
+// Run проверяет зависимости каждые `interval`, пока не отменен контекст.
+// Интервал не больше 0 отключает фоновую проверку.
+func (w *Watchdog) Run(ctx context.Context, interval time.Duration, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check(ctx)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Package config содержит HTTP-хендлер, отдающий действующую конфигурацию
+// сервиса с замаскированными секретами.
+package config
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/go-chi/render"
+)
+
+// Source возвращает действующую конфигурацию сервиса.
+type Source func() *config.Config
+
+// New создает и возвращает http.HandlerFunc для `GET /admin/config`.
+//
+// Отдает конфигурацию, с которой работает экземпляр, включая изменения,
+// примененные при перечитывании по SIGHUP. Пароли и ключи замаскированы
+// (см. config.Config.Redacted).
+func New(log *slog.Logger, source Source) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.admin.config.New"
+
+		log.Debug("config requested", slog.String("fn", fn))
+
+		render.JSON(w, r, source().Redacted())
+	}
+}
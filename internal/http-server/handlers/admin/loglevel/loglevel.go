@@ -0,0 +1,73 @@
+// Package loglevel содержит HTTP-хендлеры просмотра и смены уровня
+// логирования во время работы сервиса.
+package loglevel
+
+import (
+	"log/slog"
+	"net/http"
+
+	resp "github.com/YusovID/order-service/lib/api/response"
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/YusovID/order-service/lib/logger/slogpretty"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Request - тело запроса на смену уровня.
+type Request struct {
+	Level string `json:"level"`
+}
+
+// Response определяет структуру ответа: текущий уровень логирования.
+type Response struct {
+	resp.Response
+	Level string `json:"level"`
+}
+
+// NewGet создает и возвращает http.HandlerFunc для `GET /admin/loglevel`,
+// отдающий текущий уровень логирования.
+func NewGet(level *slog.LevelVar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, Response{Response: resp.OK(), Level: level.Level().String()})
+	}
+}
+
+// NewSet создает и возвращает http.HandlerFunc для смены уровня
+// логирования (`PUT /admin/loglevel` с телом `{"level": "debug"}`).
+// Уровень действует до следующей смены, SIGHUP или перезапуска.
+func NewSet(log *slog.Logger, level *slog.LevelVar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.admin.loglevel.NewSet"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		parsed, err := slogpretty.ParseLevel(req.Level)
+		if err != nil {
+			log.Info("invalid log level", slog.String("level", req.Level))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("invalid log level: use debug, info, warn or error"))
+			return
+		}
+
+		previous := level.Level()
+		level.Set(parsed)
+
+		log.Warn("log level changed",
+			slog.String("from", previous.String()),
+			slog.String("to", parsed.String()),
+		)
+
+		render.JSON(w, r, Response{Response: resp.OK(), Level: parsed.String()})
+	}
+}
@@ -0,0 +1,52 @@
+// Package readyz содержит HTTP-хендлер проверки готовности сервиса.
+package readyz
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	resp "github.com/YusovID/order-service/lib/api/response"
+	"github.com/go-chi/render"
+)
+
+// Response определяет структуру ответа: ошибки недоступных зависимостей по имени.
+type Response struct {
+	resp.Response
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Checker проверяет зависимости сервиса.
+type Checker interface {
+	Check(ctx context.Context) map[string]error
+}
+
+// New создает и возвращает http.HandlerFunc для `/readyz`.
+//
+// Если все зависимости доступны, возвращается 200, иначе 503 со списком
+// недоступных зависимостей, чтобы балансировщик перестал направлять
+// запросы на этот экземпляр.
+func New(log *slog.Logger, checker Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.readyz.New"
+
+		errs := checker.Check(r.Context())
+		if len(errs) == 0 {
+			render.JSON(w, r, resp.OK())
+			return
+		}
+
+		checks := make(map[string]string, len(errs))
+		for name, err := range errs {
+			checks[name] = err.Error()
+		}
+
+		log.Warn("service is not ready", slog.String("fn", fn), slog.Any("checks", checks))
+
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, Response{
+			Response: resp.Error("dependencies unavailable"),
+			Checks:   checks,
+		})
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
+	"golang.org/x/sync/singleflight"
 )
 
 // Response определяет структуру ответа для успешного запроса.
@@ -25,6 +26,9 @@ type Response struct {
 	Order *models.OrderData `json:"order"`
 }
 
+// requestTimeout ограничивает время обработки одного запроса.
+const requestTimeout = 2 * time.Second
+
 // Storage определяет интерфейс для хранилищ (кэша и основной БД),
 // с которыми взаимодействует хендлер. Это позволяет использовать
 // разные реализации хранилищ (например, Redis и PostgreSQL) взаимозаменяемо.
@@ -33,25 +37,35 @@ type Storage interface {
 	GetOrder(ctx context.Context, orderUID string) (*models.OrderData, error)
 }
 
+// Cache дополняет Storage запоминанием отсутствующих заказов.
+type Cache interface {
+	Storage
+	SaveMissing(ctx context.Context, orderUID string) error
+}
+
 // New создает и возвращает http.HandlerFunc для получения данных о заказе.
 //
 // Этот хендлер реализует следующую логику:
 //  1. Извлекает `order_uid` из URL-параметра.
 //  2. Сначала пытается найти заказ в `cache` (быстрое хранилище, например, Redis).
-//  3. Если в кэше заказ не найден, он обращается к `storage` (основное хранилище, например, PostgreSQL).
+//  3. Если в кэше заказ не найден или кэш недоступен, он обращается к `storage` (основное хранилище, например, PostgreSQL).
 //  4. Если заказ найден в основном хранилище, он асинхронно (в горутине) сохраняется в кэш для ускорения последующих запросов.
-//  5. Если заказ не найден ни в одном из хранилищ, возвращается ошибка 404.
+//  5. Если заказ не найден ни в одном из хранилищ, возвращается ошибка 404,
+//     а отсутствие заказа запоминается в кэше: повторные запросы не идут в основное хранилище.
 //  6. В случае успеха, данные заказа возвращаются в формате JSON.
 //
 // Параметры:
 //   - log: логгер для записи информации о ходе выполнения запроса.
-//   - cache: реализация интерфейса Storage для кэша.
+//   - cache: реализация интерфейса Cache для кэша.
 //   - storage: реализация интерфейса Storage для основного хранилища.
-func New(log *slog.Logger, cache Storage, storage Storage) http.HandlerFunc {
+func New(log *slog.Logger, cache Cache, storage Storage) http.HandlerFunc {
+	// group объединяет одновременные загрузки одного заказа из основного хранилища.
+	var group singleflight.Group
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.url.get.New"
 
-		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
 		defer cancel()
 
 		r = r.WithContext(ctx)
@@ -77,28 +91,39 @@ func New(log *slog.Logger, cache Storage, storage Storage) http.HandlerFunc {
 
 		// 1. Пытаемся получить данные из кэша.
 		orderData, err = cache.GetOrder(r.Context(), orderUID)
+		if errors.Is(err, strg.ErrNoOrderCached) {
+			log.Info("order not found (cached)", slog.String("order_uid", orderUID))
+			render.JSON(w, r, resp.Error("order not found"))
+			return
+		}
+		// Недоступный кэш не мешает ответить из основного хранилища.
+		if err != nil && !errors.Is(err, strg.ErrNoOrder) {
+			if errors.Is(err, strg.ErrCacheUnavailable) {
+				log.Debug("cache unavailable, reading from storage")
+			} else {
+				log.Error("failed to get order from cache", sl.Err(err))
+			}
+			err = strg.ErrNoOrder
+		}
 		if errors.Is(err, strg.ErrNoOrder) {
 			log.Info("order not found in cache")
 
-			// 2. Если в кэше нет, идем в основное хранилище.
-			orderData, err = storage.GetOrder(r.Context(), orderUID)
+			// 2. Если в кэше нет, идем в основное хранилище. Одновременные
+			// промахи по одному order_uid объединяются: в хранилище уходит
+			// один запрос, остальные получают его результат.
+			v, loadErr, shared := group.Do(orderUID, func() (any, error) {
+				return load(r.Context(), log, cache, storage, orderUID)
+			})
+			orderData, err = v.(*models.OrderData), loadErr
+			if shared {
+				log.Debug("order load shared with concurrent requests")
+			}
 			if errors.Is(err, strg.ErrNoOrder) {
 				// Если и в хранилище нет, возвращаем ошибку.
 				log.Info("order not found", slog.String("order_uid", orderUID))
 				render.JSON(w, r, resp.Error("order not found"))
 				return
 			}
-			// Если в хранилище есть, асинхронно сохраняем в кэш.
-			if err == nil {
-				go func() {
-					log.Info("saving order in cache")
-					// Используем фоновый контекст, так как основной запрос уже может завершиться.
-					errCache := cache.SaveOrder(context.Background(), orderData)
-					if errCache != nil {
-						log.Error("failed to save order in cache", sl.Err(errCache))
-					}
-				}()
-			}
 		}
 
 		// Обрабатываем прочие возможные ошибки при получении данных.
@@ -123,3 +148,36 @@ func New(log *slog.Logger, cache Storage, storage Storage) http.HandlerFunc {
 		})
 	}
 }
+
+// load загружает заказ из основного хранилища и обновляет кэш: найденный
+// заказ асинхронно сохраняется, а отсутствие заказа запоминается.
+//
+// Загрузка может выполняться для нескольких запросов сразу (см. singleflight
+// в New), поэтому она не прерывается, если клиент первого запроса отключился.
+func load(ctx context.Context, log *slog.Logger, cache Cache, storage Storage, orderUID string) (*models.OrderData, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), requestTimeout)
+	defer cancel()
+
+	orderData, err := storage.GetOrder(ctx, orderUID)
+	if errors.Is(err, strg.ErrNoOrder) {
+		if errCache := cache.SaveMissing(ctx, orderUID); errCache != nil {
+			log.Error("failed to save missing order in cache", sl.Err(errCache))
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Если в хранилище есть, асинхронно сохраняем в кэш.
+	go func() {
+		log.Info("saving order in cache")
+		// Используем фоновый контекст, так как основной запрос уже может завершиться.
+		errCache := cache.SaveOrder(context.Background(), orderData)
+		if errCache != nil {
+			log.Error("failed to save order in cache", sl.Err(errCache))
+		}
+	}()
+
+	return orderData, nil
+}
@@ -0,0 +1,73 @@
+// Package history содержит HTTP-хендлер для получения журнала изменений заказа.
+package history
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/YusovID/order-service/internal/models"
+	strg "github.com/YusovID/order-service/internal/storage"
+	resp "github.com/YusovID/order-service/lib/api/response"
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Response определяет структуру ответа для успешного запроса.
+type Response struct {
+	resp.Response
+	OrderUID string               `json:"order_uid"`
+	History  []models.OrderChange `json:"history"`
+}
+
+// HistoryGetter возвращает журнал изменений заказа.
+type HistoryGetter interface {
+	GetOrderHistory(ctx context.Context, orderUID string) ([]models.OrderChange, error)
+}
+
+// New создает и возвращает http.HandlerFunc для получения журнала
+// изменений заказа (`GET /order/{order_uid}/history`).
+func New(log *slog.Logger, storage HistoryGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.history.New"
+
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		r = r.WithContext(ctx)
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		orderUID := chi.URLParam(r, "order_uid")
+		if orderUID == "" {
+			log.Error("order uid is empty")
+			render.JSON(w, r, resp.Error("order uid is empty"))
+			return
+		}
+
+		history, err := storage.GetOrderHistory(r.Context(), orderUID)
+		if errors.Is(err, strg.ErrNoOrder) {
+			log.Info("order history not found", slog.String("order_uid", orderUID))
+			render.JSON(w, r, resp.Error("order not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get order history", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to get order history"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			OrderUID: orderUID,
+			History:  history,
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package status содержит HTTP-хендлер для смены статуса заказа.
+package status
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/YusovID/order-service/internal/models"
+	strg "github.com/YusovID/order-service/internal/storage"
+	resp "github.com/YusovID/order-service/lib/api/response"
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Request - тело запроса на смену статуса.
+type Request struct {
+	Status string `json:"status"`
+}
+
+// Response определяет структуру ответа для успешного запроса.
+type Response struct {
+	resp.Response
+	OrderUID       string             `json:"order_uid"`
+	Status         models.OrderStatus `json:"status"`
+	PreviousStatus models.OrderStatus `json:"previous_status"`
+}
+
+// StatusUpdater меняет статус заказа в основном хранилище.
+type StatusUpdater interface {
+	UpdateStatus(ctx context.Context, orderUID string, to models.OrderStatus) (models.OrderStatus, error)
+}
+
+// Cache - кэш заказов, из которого удаляется заказ с устаревшим статусом.
+type Cache interface {
+	DeleteOrder(ctx context.Context, orderUID string) error
+}
+
+// EventSender публикует события о заказах.
+type EventSender interface {
+	Send(ctx context.Context, event models.OrderEvent) error
+}
+
+// New создает и возвращает http.HandlerFunc для смены статуса заказа
+// (`PATCH /order/{order_uid}/status` с телом `{"status": "paid"}`).
+//
+// Хендлер проверяет статус, меняет его в `storage` (допустимость перехода
+// проверяет хранилище), удаляет заказ из `cache` и публикует событие
+// order.status_changed через `events`. `events` может быть nil.
+func New(log *slog.Logger, storage StatusUpdater, cache Cache, events EventSender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.status.New"
+
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		r = r.WithContext(ctx)
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		orderUID := chi.URLParam(r, "order_uid")
+		if orderUID == "" {
+			log.Error("order uid is empty")
+			render.JSON(w, r, resp.Error("order uid is empty"))
+			return
+		}
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		to, err := models.ParseOrderStatus(req.Status)
+		if err != nil {
+			log.Info("invalid status", slog.String("status", req.Status))
+			render.JSON(w, r, resp.Error(err.Error()))
+			return
+		}
+
+		from, err := storage.UpdateStatus(strg.WithActor(r.Context(), "api"), orderUID, to)
+		if err != nil {
+			switch {
+			case errors.Is(err, strg.ErrNoOrder):
+				log.Info("order not found", slog.String("order_uid", orderUID))
+				render.JSON(w, r, resp.Error("order not found"))
+			case errors.Is(err, strg.ErrInvalidTransition):
+				log.Info("invalid status transition", slog.String("order_uid", orderUID), sl.Err(err))
+				render.JSON(w, r, resp.Error(err.Error()))
+			default:
+				log.Error("failed to update order status", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to update order status"))
+			}
+			return
+		}
+
+		log.Info("order status updated",
+			slog.String("order_uid", orderUID),
+			slog.String("from", string(from)),
+			slog.String("to", string(to)),
+		)
+
+		if err := cache.DeleteOrder(r.Context(), orderUID); err != nil {
+			log.Error("failed to invalidate cached order", sl.Err(err))
+		}
+
+		if events != nil {
+			err := events.Send(r.Context(), models.OrderEvent{
+				Type:           models.EventOrderStatusChanged,
+				OrderUID:       orderUID,
+				OccurredAt:     time.Now().UTC(),
+				Status:         string(to),
+				PreviousStatus: string(from),
+			})
+			if err != nil {
+				log.Error("failed to publish status event", sl.Err(err))
+			}
+		}
+
+		render.JSON(w, r, Response{
+			Response:       resp.OK(),
+			OrderUID:       orderUID,
+			Status:         to,
+			PreviousStatus: from,
+		})
+	}
+}
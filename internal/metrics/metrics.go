@@ -0,0 +1,505 @@
+// Package metrics объявляет метрики Prometheus, которые экспортирует сервис.
+// Все метрики регистрируются в стандартном реестре через promauto и
+// отдаются HTTP-сервером по пути /metrics (см. Handler).
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace - общий префикс имен всех метрик сервиса.
+const namespace = "order_service"
+
+// Метрики Kafka-консьюмера.
+var (
+	// ConsumerLag - отставание группы консьюмеров по каждой партиции:
+	// разница между high watermark партиции и закоммиченным офсетом группы.
+	ConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "lag",
+		Help:      "Number of messages the consumer group is behind the partition high watermark.",
+	}, []string{"topic", "partition"})
+
+	// ConsumerErrors - количество ошибок, полученных из канала Errors() группы консьюмеров.
+	ConsumerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "errors_total",
+		Help:      "Number of errors reported by the consumer group.",
+	}, []string{"client_id", "topic"})
+
+	// ConsumerMessages - количество сообщений, полученных каждой сессией консьюмера.
+	ConsumerMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "messages_total",
+		Help:      "Number of messages received by the consumer session.",
+	}, []string{"client_id", "topic"})
+
+	// ConsumerAssignedPartitions - количество партиций, назначенных каждой сессии консьюмера.
+	ConsumerAssignedPartitions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "kafka_consumer",
+		Name:      "assigned_partitions",
+		Help:      "Number of partitions assigned to the consumer session.",
+	}, []string{"client_id"})
+)
+
+// Метрики Kafka-продюсера генератора заказов.
+var (
+	// ProducerMessages - количество сообщений, подтвержденных брокером.
+	ProducerMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka_producer",
+		Name:      "messages_total",
+		Help:      "Number of messages acknowledged by the broker.",
+	}, []string{"topic"})
+
+	// ProducerBytes - объем ключей и тел подтвержденных сообщений.
+	ProducerBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka_producer",
+		Name:      "bytes_total",
+		Help:      "Number of key and value bytes in acknowledged messages.",
+	}, []string{"topic"})
+
+	// ProducerErrors - количество неудачных отправок по типу ошибки.
+	ProducerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka_producer",
+		Name:      "errors_total",
+		Help:      "Number of messages that failed to be sent, by error type.",
+	}, []string{"topic", "type"})
+
+	// ProducerTxnFailures - количество транзакций, которые не удалось закоммитить.
+	ProducerTxnFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kafka_producer",
+		Name:      "transaction_failures_total",
+		Help:      "Number of transactions that failed to commit.",
+	})
+
+	// ProducerTxnState - текущее состояние транзакции продюсера:
+	// 1 у текущего состояния, 0 у остальных.
+	ProducerTxnState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "kafka_producer",
+		Name:      "transaction_state",
+		Help:      "Current transaction state of the producer (1 for the active state).",
+	}, []string{"state"})
+)
+
+// Метрики DLQ.
+var (
+	// DLQMessages - количество сообщений, отправленных в DLQ (и карантин),
+	// по топику и результату отправки (ok или error).
+	DLQMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "dlq",
+		Name:      "messages_total",
+		Help:      "Number of messages sent to dead letter topics, by topic and result.",
+	}, []string{"topic", "result"})
+)
+
+// Метрики HTTP-сервера.
+var (
+	// HTTPRequests - количество обработанных запросов по методу, шаблону
+	// маршрута (`/order/{order_uid}`) и коду ответа.
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests, by method, route and status code.",
+	}, []string{"method", "route", "code"})
+
+	// HTTPRequestDuration - длительность обработки запросов по методу и шаблону маршрута.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests, by method and route.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"method", "route"})
+
+	// HTTPInFlight - количество запросов, обрабатываемых в данный момент.
+	HTTPInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "Number of HTTP requests currently being served.",
+	})
+)
+
+// Метрики PostgreSQL.
+var (
+	// DBQueryDuration - длительность операций хранилища по методу и результату
+	// (ok, duplicate или error). Для методов с повторами учитывается время всех попыток.
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of storage operations by method and result.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"method", "result"})
+)
+
+// Метрики обработки заказов.
+var (
+	// ProcessorReceived - количество сообщений с заказами, полученных обработчиком.
+	ProcessorReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "received_total",
+		Help:      "Number of order messages received by the processor.",
+	})
+
+	// ProcessorSaved - количество заказов, сохраненных в базу данных.
+	ProcessorSaved = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "saved_total",
+		Help:      "Number of orders saved to the database.",
+	})
+
+	// ProcessorFailed - количество сообщений, обработка которых завершилась
+	// ошибкой, по типу ошибки (decode, consistency, validation, storage, canceled).
+	ProcessorFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "failed_total",
+		Help:      "Number of order messages that failed to be processed, by error type.",
+	}, []string{"error"})
+
+	// ProcessorSkipped - количество сообщений, пропущенных без сохранения,
+	// по причине (duplicate, replay или dry_run).
+	ProcessorSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "skipped_total",
+		Help:      "Number of order messages skipped without saving, by reason.",
+	}, []string{"reason"})
+
+	// ProcessorDuration - длительность обработки одного сообщения с заказом
+	// по типу ошибки (none при успешной обработке). Учитывает повторы сохранения.
+	ProcessorDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "message_duration_seconds",
+		Help:      "Duration of processing a single order message, by error type.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"error"})
+
+	// ProcessorDuplicates - количество сообщений с заказами, которые уже были
+	// сохранены (повторная доставка или устаревшая версия).
+	ProcessorDuplicates = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "duplicates_total",
+		Help:      "Number of order messages skipped because the order was already saved.",
+	})
+
+	// ProcessorBatchFallbacks - количество пачек заказов, которые не удалось
+	// сохранить одной транзакцией и пришлось сохранять по одному.
+	ProcessorBatchFallbacks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "batch_fallbacks_total",
+		Help:      "Number of order batches that failed as a single transaction and were saved one by one.",
+	})
+
+	// ProcessorQuarantined - количество сообщений, отправленных в карантин
+	// после многократно прерванной обработки.
+	ProcessorQuarantined = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "quarantined_total",
+		Help:      "Number of messages moved to the quarantine topic after repeatedly interrupted processing.",
+	})
+
+	// ProcessorInconsistencies - количество расхождений в суммах заказов
+	// по полю (payment.goods_total или payment.amount).
+	ProcessorInconsistencies = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "inconsistencies_total",
+		Help:      "Number of order totals inconsistent with the order items, by field.",
+	}, []string{"field"})
+
+	// ProcessorPriority - количество срочных сообщений, обработанных
+	// выделенными воркерами вне пачки.
+	ProcessorPriority = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "priority_messages_total",
+		Help:      "Number of high-priority messages processed immediately on reserved workers.",
+	})
+
+	// ProcessorScrubbed - количество обезличенных перед сохранением полей
+	// доставки по имени поля.
+	ProcessorScrubbed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "scrubbed_fields_total",
+		Help:      "Number of delivery fields hashed or masked before saving, by field.",
+	}, []string{"field"})
+
+	// ProcessorUpdates - количество сообщений об изменении заказов по операции
+	// (update или cancel) и результату (applied, duplicate, invalid, failed, dry_run).
+	ProcessorUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "updates_total",
+		Help:      "Number of order update messages, by operation and result.",
+	}, []string{"op", "result"})
+
+	// ProcessorBatchRetries - количество повторных обработок сообщений пачки
+	// перед ее подтверждением (стратегия коммита batch).
+	ProcessorBatchRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "batch_retries_total",
+		Help:      "Number of retries of failed messages before committing a batch.",
+	})
+
+	// ProcessorBatchRewinds - количество пачек, которые не удалось обработать
+	// полностью и партиции которых перечитываются (стратегия коммита batch).
+	ProcessorBatchRewinds = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "batch_rewinds_total",
+		Help:      "Number of abandoned batches whose partitions were rewound to the last committed offset.",
+	})
+
+	// ProcessorReplays - количество сообщений, пропущенных как уже
+	// обработанные (повторное чтение после падения или ребалансировки).
+	ProcessorReplays = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "processor",
+		Name:      "replays_total",
+		Help:      "Number of messages skipped because the ledger marks them as already processed.",
+	})
+)
+
+// Метрики кэша.
+var (
+	// CacheCommandDuration - длительность команд Redis по имени команды
+	// (для pipeline - "pipeline") и результату (ok или error).
+	// Отсутствие ключа ошибкой не считается.
+	CacheCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "command_duration_seconds",
+		Help:      "Duration of Redis commands, by command and result.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"command", "result"})
+
+	// CacheLookups - результаты чтения заказов из кэша: hit, miss (заказа
+	// нет в кэше) или negative (кэш помнит, что заказа нет и в базе).
+	CacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Number of order lookups in the cache, by result.",
+	}, []string{"result"})
+
+	// CacheWarmOrders - количество заказов, записанных в кэш при прогреве,
+	// по режиму прогрева (full, recent или incremental).
+	CacheWarmOrders = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "warm_orders_total",
+		Help:      "Number of orders written to the cache by warm-up and refresh, by mode.",
+	}, []string{"mode"})
+
+	// CacheWarmInProgress - 1, пока идет прогрев кэша при старте.
+	CacheWarmInProgress = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "warm_in_progress",
+		Help:      "Whether the startup cache warm-up is running (1) or not (0).",
+	})
+
+	// WorkerPoolTaskDuration - длительность задач пула воркеров по имени пула.
+	WorkerPoolTaskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "worker_pool",
+		Name:      "task_duration_seconds",
+		Help:      "Duration of worker pool tasks, by pool.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"pool"})
+
+	// WorkerPoolPanics - количество паник в задачах пула воркеров по имени пула.
+	WorkerPoolPanics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker_pool",
+		Name:      "panics_total",
+		Help:      "Number of panics in worker pool tasks, by pool.",
+	}, []string{"pool"})
+)
+
+// WorkerPoolStats - снимок состояния пула воркеров.
+type WorkerPoolStats struct {
+	Size   int // Текущий размер пула.
+	Busy   int // Занятые воркеры.
+	Queued int // Задачи в очереди.
+}
+
+// workerPoolCollector отдает состояние пула воркеров при каждом сборе метрик.
+type workerPoolCollector struct {
+	stats func() WorkerPoolStats
+
+	size, busy, queued *prometheus.Desc
+}
+
+// RegisterWorkerPool регистрирует метрики пула воркеров `pool` (например,
+// processor). `stats` вызывается при каждом сборе метрик. По отношению
+// busy_workers к workers можно настроить оповещение о длительной перегрузке.
+// Повторная регистрация пула с тем же именем игнорируется.
+func RegisterWorkerPool(pool string, stats func() WorkerPoolStats) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "worker_pool", name), help,
+			nil, prometheus.Labels{"pool": pool},
+		)
+	}
+
+	err := prometheus.Register(&workerPoolCollector{
+		stats:  stats,
+		size:   desc("workers", "Current size of the worker pool."),
+		busy:   desc("busy_workers", "Number of workers currently running tasks."),
+		queued: desc("queued_tasks", "Number of tasks waiting in the pool queue."),
+	})
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if err != nil && !errors.As(err, &alreadyRegistered) {
+		panic(err)
+	}
+}
+
+// Describe реализует prometheus.Collector.
+func (c *workerPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+	ch <- c.busy
+	ch <- c.queued
+}
+
+// Collect реализует prometheus.Collector.
+func (c *workerPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(s.Size))
+	ch <- prometheus.MustNewConstMetric(c.busy, prometheus.GaugeValue, float64(s.Busy))
+	ch <- prometheus.MustNewConstMetric(c.queued, prometheus.GaugeValue, float64(s.Queued))
+}
+
+// DBPoolStats - снимок состояния пула соединений с базой данных.
+type DBPoolStats struct {
+	TotalConns      int32         // Открытые соединения.
+	AcquiredConns   int32         // Соединения, занятые запросами.
+	IdleConns       int32         // Простаивающие соединения.
+	MaxConns        int32         // Максимальный размер пула.
+	AcquireCount    int64         // Сколько раз соединение было взято из пула.
+	WaitCount       int64         // Сколько раз пришлось ждать свободного соединения.
+	AcquireDuration time.Duration // Суммарное время ожидания соединений.
+}
+
+// dbPoolCollector отдает состояние пула соединений при каждом сборе метрик.
+type dbPoolCollector struct {
+	pool  string
+	stats func() DBPoolStats
+
+	totalConns, acquiredConns, idleConns, maxConns *prometheus.Desc
+	acquireCount, waitCount, acquireDuration       *prometheus.Desc
+}
+
+// RegisterDBPool регистрирует метрики пула соединений `pool` (например,
+// primary или replica). `stats` вызывается при каждом сборе метрик.
+// Повторная регистрация пула с тем же именем игнорируется.
+func RegisterDBPool(pool string, stats func() DBPoolStats) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "postgres_pool", name), help,
+			nil, prometheus.Labels{"pool": pool},
+		)
+	}
+
+	err := prometheus.Register(&dbPoolCollector{
+		pool:            pool,
+		stats:           stats,
+		totalConns:      desc("open_connections", "Number of open connections in the pool."),
+		acquiredConns:   desc("in_use_connections", "Number of connections currently in use."),
+		idleConns:       desc("idle_connections", "Number of idle connections in the pool."),
+		maxConns:        desc("max_connections", "Maximum size of the pool."),
+		acquireCount:    desc("acquires_total", "Number of successful connection acquires."),
+		waitCount:       desc("waits_total", "Number of acquires that had to wait for a free connection."),
+		acquireDuration: desc("wait_seconds_total", "Total time spent acquiring connections."),
+	})
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if err != nil && !errors.As(err, &alreadyRegistered) {
+		panic(err)
+	}
+}
+
+// Describe реализует prometheus.Collector.
+func (c *dbPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.totalConns
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.waitCount
+	ch <- c.acquireDuration
+}
+
+// Collect реализует prometheus.Collector.
+func (c *dbPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(s.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration.Seconds())
+}
+
+// Middleware записывает количество, длительность и число одновременно
+// обрабатываемых HTTP-запросов. Маршрут берется из шаблона chi, чтобы
+// идентификаторы заказов не попадали в метки; запросы без маршрута
+// учитываются как "unmatched".
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		HTTPInFlight.Inc()
+		defer HTTPInFlight.Dec()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		HTTPRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Handler возвращает HTTP-обработчик, отдающий метрики в формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OrderChange - запись журнала изменений заказа.
+type OrderChange struct {
+	Operation string          `json:"operation"`          // INSERT, UPDATE или DELETE.
+	OldData   json.RawMessage `json:"old_data,omitempty"` // Строка заказа до изменения.
+	NewData   json.RawMessage `json:"new_data,omitempty"` // Строка заказа после изменения.
+	Actor     string          `json:"actor"`              // Кто изменил заказ.
+	ChangedAt time.Time       `json:"changed_at"`         // Время изменения.
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// currencyAliases - нестандартные обозначения валют, которые встречаются
+// в сообщениях, и соответствующие им коды ISO 4217.
+var currencyAliases = map[string]string{
+	"RUR": "RUB",
+	"₽":   "RUB",
+	"$":   "USD",
+	"€":   "EUR",
+	"£":   "GBP",
+}
+
+// NormalizeCurrency приводит код валюты к виду ISO 4217: убирает пробелы,
+// переводит в верхний регистр и заменяет известные синонимы ("rur", "$").
+func NormalizeCurrency(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if alias, ok := currencyAliases[code]; ok {
+		return alias
+	}
+	return code
+}
+
+// Inconsistency описывает расхождение суммы в заказе с суммой,
+// вычисленной по остальным данным заказа.
+type Inconsistency struct {
+	Field string // Поле с расхождением в нотации JSON, например payment.amount.
+	Got   int    // Значение в заказе.
+	Want  int    // Вычисленное значение.
+}
+
+// String возвращает описание расхождения, например "payment.amount: got 100, want 150".
+func (i Inconsistency) String() string {
+	return fmt.Sprintf("%s: got %d, want %d", i.Field, i.Got, i.Want)
+}
+
+// Normalize приводит к единому виду поля заказа, которые продюсеры
+// заполняют по-разному. Сейчас нормализуется код валюты.
+func (o *OrderData) Normalize() {
+	o.Payment.Currency = NormalizeCurrency(o.Payment.Currency)
+}
+
+// CheckConsistency сверяет суммы оплаты с товарами заказа:
+//   - goods_total равен сумме total_price товаров (дробная часть отбрасывается);
+//   - amount равен goods_total + delivery_cost + custom_fee.
+//
+// Возвращает найденные расхождения, не изменяя заказ.
+func (o *OrderData) CheckConsistency() []Inconsistency {
+	var issues []Inconsistency
+
+	goodsTotal := o.itemsTotal()
+	if o.Payment.GoodsTotal != goodsTotal {
+		issues = append(issues, Inconsistency{Field: "payment.goods_total", Got: o.Payment.GoodsTotal, Want: goodsTotal})
+	}
+
+	amount := goodsTotal + o.Payment.DeliveryCost + o.Payment.CustomFee
+	if o.Payment.Amount != amount {
+		issues = append(issues, Inconsistency{Field: "payment.amount", Got: o.Payment.Amount, Want: amount})
+	}
+
+	return issues
+}
+
+// FixConsistency исправляет суммы оплаты по правилам CheckConsistency
+// и возвращает исправленные расхождения.
+func (o *OrderData) FixConsistency() []Inconsistency {
+	issues := o.CheckConsistency()
+	for _, issue := range issues {
+		switch issue.Field {
+		case "payment.goods_total":
+			o.Payment.GoodsTotal = issue.Want
+		case "payment.amount":
+			o.Payment.Amount = issue.Want
+		}
+	}
+	return issues
+}
+
+// itemsTotal возвращает сумму итоговых цен товаров заказа.
+func (o *OrderData) itemsTotal() int {
+	var total int
+	for _, item := range o.Items {
+		total += int(item.TotalPrice)
+	}
+	return total
+}
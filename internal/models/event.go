@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Типы событий о заказах.
+const (
+	EventOrderCreated       = "order.created"        // Заказ впервые сохранен в хранилище (через outbox).
+	EventOrderSaved         = "order.saved"          // Заказ создан или обновлен (через outbox).
+	EventOrderStatusChanged = "order.status_changed" // Изменился статус заказа.
+)
+
+// OrderEvent - событие о заказе, которое сервис публикует для других систем.
+type OrderEvent struct {
+	Type        string    `json:"type"`
+	OrderUID    string    `json:"order_uid"`
+	TrackNumber string    `json:"track_number"`
+	CustomerID  string    `json:"customer_id"`
+	OccurredAt  time.Time `json:"occurred_at"`
+
+	// Amount - сумма оплаты. Заполняется только для EventOrderCreated.
+	Amount int `json:"amount,omitempty"`
+
+	// Заполняются только для EventOrderStatusChanged.
+	Status         string `json:"status,omitempty"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+}
@@ -9,43 +9,53 @@ import "time"
 // OrderData представляет полную информацию о заказе.
 // Это корневая структура, которая объединяет все связанные данные,
 // включая информацию о доставке, оплате и товарах.
+// Теги `validate` описывают правила, которые проверяет Validate.
 type OrderData struct {
-	OrderUID        string    `json:"order_uid"`        // Уникальный идентификатор заказа.
-	TrackNumber     string    `json:"track_number"`     // Номер для отслеживания заказа.
-	CustomerID      string    `json:"customer_id"`      // Идентификатор клиента.
-	DeliveryService string    `json:"delivery_service"` // Служба доставки.
-	DateCreated     time.Time `json:"date_created"`     // Дата и время создания заказа.
+	OrderUID        string    `json:"order_uid" validate:"required,uuid"`   // Уникальный идентификатор заказа.
+	TrackNumber     string    `json:"track_number" validate:"required"`     // Номер для отслеживания заказа.
+	CustomerID      string    `json:"customer_id" validate:"required"`      // Идентификатор клиента.
+	DeliveryService string    `json:"delivery_service" validate:"required"` // Служба доставки.
+	DateCreated     time.Time `json:"date_created" validate:"required"`     // Дата и время создания заказа.
 
-	Items []Item `json:"items"` // Список товаров в заказе.
+	// Status - текущий статус заказа. Меняется только через API статусов;
+	// сообщения о заказе из Kafka его не содержат и не изменяют.
+	Status OrderStatus `json:"status,omitempty"`
+
+	Items []Item `json:"items" validate:"min=1,dive"` // Список товаров в заказе.
 
 	Delivery Delivery `json:"delivery"` // Информация о доставке.
 	Payment  Payment  `json:"payment"`  // Информация об оплате.
 	AdditionalData
+
+	// UpdatedAt - время изменения заказа (время отправки сообщения о нем).
+	// По нему хранилище отличает более новую версию заказа от устаревшей.
+	// Не является частью JSON-модели заказа.
+	UpdatedAt time.Time `json:"-"`
 }
 
 // Delivery содержит информацию, необходимую для доставки заказа.
 type Delivery struct {
-	Name    string `json:"name"`    // Имя и фамилия получателя.
-	Phone   string `json:"phone"`   // Контактный телефон.
-	Zip     string `json:"zip"`     // Почтовый индекс.
-	City    string `json:"city"`    // Город доставки.
-	Address string `json:"address"` // Адрес доставки (улица, дом).
-	Region  string `json:"region"`  // Регион/область.
-	Email   string `json:"email"`   // Электронная почта получателя.
+	Name    string `json:"name" validate:"required"`        // Имя и фамилия получателя.
+	Phone   string `json:"phone" validate:"required,phone"` // Контактный телефон.
+	Zip     string `json:"zip"`                             // Почтовый индекс.
+	City    string `json:"city"`                            // Город доставки.
+	Address string `json:"address"`                         // Адрес доставки (улица, дом).
+	Region  string `json:"region"`                          // Регион/область.
+	Email   string `json:"email" validate:"required,email"` // Электронная почта получателя.
 }
 
 // Payment содержит информацию об оплате заказа.
 type Payment struct {
-	Transaction  string `json:"transaction"`   // ID транзакции, обычно совпадает с OrderUID.
-	RequestID    string `json:"request_id"`    // Внутренний ID запроса на оплату.
-	Currency     string `json:"currency"`      // Валюта платежа.
-	Provider     string `json:"provider"`      // Платежный провайдер.
-	Amount       int    `json:"amount"`        // Общая сумма к оплате.
-	PaymentDT    int    `json:"payment_dt"`    // Unix-время транзакции.
-	Bank         string `json:"bank"`          // Банк, через который прошел платеж.
-	DeliveryCost int    `json:"delivery_cost"` // Стоимость доставки.
-	GoodsTotal   int    `json:"goods_total"`   // Суммарная стоимость товаров.
-	CustomFee    int    `json:"custom_fee"`    // Таможенный сбор.
+	Transaction  string `json:"transaction"`                           // ID транзакции, обычно совпадает с OrderUID.
+	RequestID    string `json:"request_id"`                            // Внутренний ID запроса на оплату.
+	Currency     string `json:"currency" validate:"required,iso4217"`  // Валюта платежа (код ISO 4217).
+	Provider     string `json:"provider"`                              // Платежный провайдер.
+	Amount       int    `json:"amount" validate:"gtefield=GoodsTotal"` // Общая сумма к оплате, не меньше стоимости товаров.
+	PaymentDT    int    `json:"payment_dt"`                            // Unix-время транзакции.
+	Bank         string `json:"bank"`                                  // Банк, через который прошел платеж.
+	DeliveryCost int    `json:"delivery_cost"`                         // Стоимость доставки.
+	GoodsTotal   int    `json:"goods_total" validate:"gte=0"`          // Суммарная стоимость товаров.
+	CustomFee    int    `json:"custom_fee"`                            // Таможенный сбор.
 }
 
 // AdditionalData содержит дополнительные метаданные о заказе.
@@ -60,15 +70,15 @@ type AdditionalData struct {
 
 // Item представляет один товар в заказе.
 type Item struct {
-	ChrtID      int     `json:"chrt_id"`      // Уникальный идентификатор товара.
-	TrackNumber string  `json:"track_number"` // Номер отслеживания, обычно совпадает с общим.
-	Price       float64 `json:"price"`        // Цена товара до применения скидок.
-	Rid         string  `json:"rid"`          // Уникальный идентификатор строки заказа.
-	Name        string  `json:"name"`         // Название товара.
-	Sale        float64 `json:"sale"`         // Скидка в процентах.
-	Size        string  `json:"size"`         // Размер товара.
-	TotalPrice  float64 `json:"total_price"`  // Итоговая цена товара с учетом скидки.
-	NmID        int     `json:"nm_id"`        // Артикул товара от WB.
-	Brand       string  `json:"brand"`        // Бренд товара.
-	Status      int     `json:"status"`       // Статус товара в системе поставщика.
+	ChrtID      int     `json:"chrt_id"`                 // Уникальный идентификатор товара.
+	TrackNumber string  `json:"track_number"`            // Номер отслеживания, обычно совпадает с общим.
+	Price       float64 `json:"price" validate:"gte=0"`  // Цена товара до применения скидок.
+	Rid         string  `json:"rid" validate:"required"` // Уникальный идентификатор строки заказа.
+	Name        string  `json:"name"`                    // Название товара.
+	Sale        float64 `json:"sale"`                    // Скидка в процентах.
+	Size        string  `json:"size"`                    // Размер товара.
+	TotalPrice  float64 `json:"total_price"`             // Итоговая цена товара с учетом скидки.
+	NmID        int     `json:"nm_id"`                   // Артикул товара от WB.
+	Brand       string  `json:"brand"`                   // Бренд товара.
+	Status      int     `json:"status"`                  // Статус товара в системе поставщика.
 }
@@ -0,0 +1,41 @@
+package models
+
+import "fmt"
+
+// OrderStatus - этап жизненного цикла заказа.
+type OrderStatus string
+
+// Статусы заказа. Новый заказ получает статус StatusCreated.
+const (
+	StatusCreated   OrderStatus = "created"   // Заказ создан.
+	StatusPaid      OrderStatus = "paid"      // Заказ оплачен.
+	StatusShipped   OrderStatus = "shipped"   // Заказ передан в доставку.
+	StatusDelivered OrderStatus = "delivered" // Заказ доставлен. Конечный статус.
+	StatusCancelled OrderStatus = "cancelled" // Заказ отменен. Конечный статус.
+)
+
+// transitions перечисляет допустимые переходы между статусами.
+var transitions = map[OrderStatus][]OrderStatus{
+	StatusCreated: {StatusPaid, StatusCancelled},
+	StatusPaid:    {StatusShipped, StatusCancelled},
+	StatusShipped: {StatusDelivered},
+}
+
+// ParseOrderStatus проверяет, что строка является известным статусом заказа.
+func ParseOrderStatus(s string) (OrderStatus, error) {
+	switch status := OrderStatus(s); status {
+	case StatusCreated, StatusPaid, StatusShipped, StatusDelivered, StatusCancelled:
+		return status, nil
+	}
+	return "", fmt.Errorf("unknown order status %q", s)
+}
+
+// CanTransitionTo сообщает, можно ли перевести заказ из статуса `s` в `to`.
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Операции сообщений об изменении заказа.
+const (
+	OpUpdate = "update" // Замена данных существующего заказа.
+	OpCancel = "cancel" // Отмена заказа.
+)
+
+// OrderUpdate - сообщение об изменении существующего заказа из топика
+// обновлений (kafka.updates_topic). В отличие от сообщений о новых заказах,
+// оно не создает заказ, если его нет.
+type OrderUpdate struct {
+	Op       string     `json:"op"`               // Операция: update или cancel.
+	OrderUID string     `json:"order_uid"`        // Идентификатор изменяемого заказа.
+	Order    *OrderData `json:"order,omitempty"`  // Новые данные заказа, только для update.
+	Reason   string     `json:"reason,omitempty"` // Причина отмены, только для cancel.
+}
+
+// Validate проверяет операцию и данные сообщения. Данные заказа
+// для update проверяются правилами OrderData.Validate.
+func (u *OrderUpdate) Validate() error {
+	if u.OrderUID == "" {
+		return errors.New("order_uid is required")
+	}
+
+	switch u.Op {
+	case OpUpdate:
+		if u.Order == nil {
+			return errors.New("order is required for update")
+		}
+		if u.Order.OrderUID != u.OrderUID {
+			return fmt.Errorf("order.order_uid %q does not match order_uid %q", u.Order.OrderUID, u.OrderUID)
+		}
+		return u.Order.Validate()
+	case OpCancel:
+		return nil
+	default:
+		return fmt.Errorf("unknown op %q, supported: %s, %s", u.Op, OpUpdate, OpCancel)
+	}
+}
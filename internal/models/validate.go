@@ -0,0 +1,93 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validate - общий валидатор заказов. Он потокобезопасен и кэширует
+// разобранные теги структур, поэтому создается один раз.
+var validate = newValidator()
+
+// phoneRe - телефон в международном формате: необязательный "+" и 10-15 цифр.
+var phoneRe = regexp.MustCompile(`^\+?[0-9]{10,15}$`)
+
+// newValidator создает валидатор, который называет поля по JSON-тегам
+// (payment.amount вместо Payment.Amount) и знает правило `phone`.
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	// Правило зарегистрировано с корректным именем и функцией, ошибки быть не может.
+	_ = v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		return phoneRe.MatchString(fl.Field().String())
+	})
+
+	return v
+}
+
+// FieldError описывает нарушение одного правила валидации.
+type FieldError struct {
+	Field string // Путь к полю по JSON-именам, например payment.amount или items[0].rid.
+	Rule  string // Нарушенное правило, например required или email.
+	Param string // Параметр правила, например GoodsTotal для gtefield.
+}
+
+// String возвращает описание ошибки поля, например "payment.amount: gtefield=GoodsTotal".
+func (e FieldError) String() string {
+	if e.Param == "" {
+		return e.Field + ": " + e.Rule
+	}
+	return e.Field + ": " + e.Rule + "=" + e.Param
+}
+
+// ValidationError содержит все нарушения правил валидации заказа.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error перечисляет все поля с ошибками.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.String())
+	}
+	return "invalid order: " + strings.Join(msgs, ", ")
+}
+
+// Validate проверяет заказ по правилам из тегов `validate`: обязательные поля,
+// order_uid в формате UUID, хотя бы один товар, сумма оплаты не меньше
+// стоимости товаров, корректные email и телефон получателя.
+// Возвращает *ValidationError со всеми нарушениями.
+func (o *OrderData) Validate() error {
+	err := validate.Struct(o)
+	if err == nil {
+		return nil
+	}
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return fmt.Errorf("can't validate order: %v", err)
+	}
+
+	fields := make([]FieldError, 0, len(errs))
+	for _, e := range errs {
+		// Namespace начинается с имени корневой структуры: OrderData.payment.amount.
+		_, field, _ := strings.Cut(e.Namespace(), ".")
+		fields = append(fields, FieldError{Field: field, Rule: e.Tag(), Param: e.Param()})
+	}
+
+	return &ValidationError{Fields: fields}
+}
@@ -0,0 +1,141 @@
+package processor
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/metrics"
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/YusovID/order-service/lib/logger/sl"
+)
+
+// BatchStorage определяет интерфейс хранилища, которое умеет сохранять
+// несколько заказов в одной транзакции. Для устаревших версий заказов
+// в `applied` возвращается false.
+type BatchStorage interface {
+	SaveOrders(ctx context.Context, orders []*models.OrderData) (applied []bool, err error)
+}
+
+// EnableBatchWrites включает сохранение новых заказов пачками до `size`
+// заказов в одной транзакции. Если транзакция пачки не удалась, заказы
+// сохраняются по одному, чтобы ошибка одного заказа не задела остальные.
+// При size < 2 каждый заказ сохраняется в своей транзакции.
+//
+// Вызывается до запуска ProcessOrders.
+func (p *Processor) EnableBatchWrites(batchStorage BatchStorage, size int) {
+	if size < 2 {
+		return
+	}
+	p.batchStorage = batchStorage
+	p.batchSize = size
+}
+
+// processShardBatched обрабатывает шард, сохраняя новые заказы пачками.
+//
+// Сообщения топиков с отдельным обработчиком обрабатываются как обычно;
+// перед ними сохраняются уже накопленные заказы, чтобы сообщения одного
+// заказа применялись в порядке партиции.
+//
+// Новые заказы проходят через цепочку промежуточных обработчиков, но вместо
+// сохранения откладываются в пачку. Поэтому промежуточные обработчики видят
+// только декодирование и валидацию: сохранение происходит позже.
+func (p *Processor) processShardBatched(ctx context.Context, shard []*sarama.ConsumerMessage) {
+	pending := make([]*orderJob, 0, p.batchSize)
+	ctx = context.WithValue(ctx, pendingKey{}, &pending)
+	enqueue := p.chain(p.enqueueOrder)
+
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		p.saveBatch(ctx, pending)
+		for _, job := range pending {
+			p.markProcessed(ctx, job.msg)
+			p.commit(ctx, job.msg)
+		}
+		pending = pending[:0]
+	}
+
+	for _, order := range shard {
+		if _, ok := p.handlers[order.Topic]; ok {
+			flush()
+			p.route(ctx, order)
+			p.commit(ctx, order)
+			continue
+		}
+
+		if p.processed(ctx, order) {
+			p.commit(ctx, order)
+			continue
+		}
+		if p.quarantined(ctx, order) {
+			p.markProcessed(ctx, order)
+			p.commit(ctx, order)
+			continue
+		}
+
+		n := len(pending)
+		enqueue(ctx, order)
+		if len(pending) == n {
+			// Заказ не попал в пачку: сообщение отправлено в DLQ
+			// или отброшено промежуточным обработчиком.
+			p.markProcessed(ctx, order)
+			p.commit(ctx, order)
+			continue
+		}
+
+		if len(pending) >= p.batchSize {
+			flush()
+		}
+	}
+	flush()
+}
+
+// pendingKey - ключ контекста с пачкой заказов, ожидающих сохранения.
+type pendingKey struct{}
+
+// enqueueOrder декодирует и валидирует сообщение и откладывает заказ
+// в пачку из контекста (см. processShardBatched).
+func (p *Processor) enqueueOrder(ctx context.Context, order *sarama.ConsumerMessage) {
+	job := p.prepareOrder(ctx, order)
+	if job == nil {
+		return
+	}
+
+	pending := ctx.Value(pendingKey{}).(*[]*orderJob)
+	*pending = append(*pending, job)
+}
+
+// saveBatch сохраняет заказы пачки в одной транзакции и завершает их обработку.
+// Если транзакция не удалась, заказы сохраняются по одному с повторами.
+func (p *Processor) saveBatch(ctx context.Context, jobs []*orderJob) {
+	orders := make([]*models.OrderData, len(jobs))
+	for i, job := range jobs {
+		orders[i] = job.data
+	}
+
+	applied, err := p.batchStorage.SaveOrders(storage.WithActor(ctx, "kafka"), orders)
+	if err != nil {
+		p.log.Warn("failed to save order batch, saving orders one by one",
+			slog.Int("count", len(jobs)),
+			sl.Err(err),
+		)
+		metrics.ProcessorBatchFallbacks.Inc()
+		p.health.failure()
+
+		for _, job := range jobs {
+			p.finishOrder(job, p.saveOrder(job.ctx, job.log, job.data))
+		}
+		return
+	}
+
+	for i, job := range jobs {
+		var err error
+		if !applied[i] {
+			err = storage.ErrDuplicateOrder
+		}
+		p.finishOrder(job, err)
+	}
+}
@@ -0,0 +1,194 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/metrics"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/YusovID/order-service/lib/logger/sl"
+)
+
+// Стратегии подтверждения (коммита) обработанных сообщений.
+const (
+	// CommitPerMessage - каждое сообщение подтверждается сразу после
+	// обработки. Сообщения, которые не удалось сохранить, откладываются в DLQ.
+	CommitPerMessage = "message"
+	// CommitBatch - сообщения пачки подтверждаются, только когда все они
+	// успешно обработаны. Сообщения, которые не удалось сохранить, не
+	// уходят в DLQ, а обрабатываются повторно. Если пачку не удалось
+	// обработать полностью (паника или остановка сервиса), ее партиции
+	// перечитываются с наименьшего неподтвержденного офсета (см. SetRewinder).
+	CommitBatch = "batch"
+)
+
+// SetCommitStrategy задает стратегию подтверждения сообщений. По умолчанию
+// используется CommitPerMessage. Вызывается до запуска ProcessOrders.
+//
+// При CommitBatch сообщение с постоянно повторяющейся ошибкой останавливает
+// продвижение партиции; защиту дает карантин (см. EnableQuarantine), который
+// учитывает и повторы пачки.
+func (p *Processor) SetCommitStrategy(strategy string) error {
+	switch strategy {
+	case "", CommitPerMessage:
+		p.batchCommit = false
+	case CommitBatch:
+		p.batchCommit = true
+	default:
+		return fmt.Errorf("unknown commit strategy %q, supported: %s, %s", strategy, CommitPerMessage, CommitBatch)
+	}
+	return nil
+}
+
+// SetRewinder задает, как вернуть партиции не полностью обработанной пачки
+// к наименьшему неподтвержденному офсету (см. abandon). Обычно это пул
+// консьюмеров. Вызывается до запуска ProcessOrders.
+func (p *Processor) SetRewinder(rewinder Rewinder) {
+	p.rewinder = rewinder
+}
+
+// failures - сообщения пачки, обработка которых завершилась временной ошибкой.
+// Передается через контекст обработки пачки при CommitBatch.
+type failures struct {
+	mu   sync.Mutex
+	msgs map[*sarama.ConsumerMessage]error
+}
+
+// failuresKey - ключ контекста с failures.
+type failuresKey struct{}
+
+// commit подтверждает обработанное сообщение. При CommitBatch сообщения
+// пачки подтверждаются вместе после ее обработки (см. processBatchAtomic).
+func (p *Processor) commit(ctx context.Context, msg *sarama.ConsumerMessage) {
+	if _, ok := ctx.Value(failuresKey{}).(*failures); ok {
+		return
+	}
+	p.sendCommit(ctx, msg)
+}
+
+// fail завершает обработку сообщения временной ошибкой `reason` (например,
+// база недоступна). При CommitPerMessage сообщение откладывается в DLQ,
+// при CommitBatch запоминается для повторной обработки.
+func (p *Processor) fail(ctx context.Context, log *slog.Logger, msg *sarama.ConsumerMessage, reason error) {
+	f, ok := ctx.Value(failuresKey{}).(*failures)
+	if !ok {
+		p.sendToDLQ(ctx, log, msg, reason)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.msgs[msg] = reason
+}
+
+// temporary сообщает, что ошибка хранилища временная и повторная обработка
+// может пройти успешно: повторы хранилища исчерпаны или истек таймаут.
+func temporary(err error) bool {
+	return errors.Is(err, storage.ErrRetryExhausted) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// failed сообщает, что обработка сообщения в текущей пачке завершилась
+// временной ошибкой. Такое сообщение не отмечается как обработанное.
+func (p *Processor) failed(ctx context.Context, msg *sarama.ConsumerMessage) bool {
+	f, ok := ctx.Value(failuresKey{}).(*failures)
+	if !ok {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, failed := f.msgs[msg]
+	return failed
+}
+
+// processBatchAtomic обрабатывает пачку при CommitBatch. Сообщения с
+// временными ошибками обрабатываются повторно с паузой, пока не будут
+// обработаны все; после этого подтверждается вся пачка. Если обработка
+// прервалась паникой или контекст отменен раньше, пачка не подтверждается
+// и ее партиции перечитываются (см. abandon).
+func (p *Processor) processBatchAtomic(ctx context.Context, orders []*sarama.ConsumerMessage, pool IPool) {
+	f := &failures{msgs: make(map[*sarama.ConsumerMessage]error)}
+	panics := p.panics.Load()
+	p.runBatch(context.WithValue(ctx, failuresKey{}, f), orders, pool)
+
+	// Шард, обработка которого прервалась паникой, обработан не полностью.
+	if p.panics.Load() != panics {
+		p.abandon(orders, "batch processing panicked")
+		return
+	}
+
+	for retry := 1; len(f.msgs) > 0; retry++ {
+		// Повторяем в исходном порядке, чтобы сохранить порядок партиции.
+		failed := make([]*sarama.ConsumerMessage, 0, len(f.msgs))
+		var reason error
+		for _, order := range orders {
+			if err, ok := f.msgs[order]; ok {
+				failed = append(failed, order)
+				reason = err
+			}
+		}
+
+		saveRetry := p.saveRetry()
+		wait := saveRetry.backoff(min(retry, saveRetry.attempts))
+		p.log.Warn("batch has failed messages, retrying before commit",
+			slog.Int("batch", len(orders)),
+			slog.Int("failed", len(failed)),
+			slog.Int("retry", retry),
+			slog.Duration("backoff", wait),
+			sl.Err(reason),
+		)
+		metrics.ProcessorBatchRetries.Inc()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			p.abandon(orders, "context canceled before batch was processed")
+			return
+		case <-timer.C:
+		}
+
+		f = &failures{msgs: make(map[*sarama.ConsumerMessage]error)}
+		retryCtx := context.WithValue(ctx, failuresKey{}, f)
+		for _, order := range failed {
+			p.route(retryCtx, order)
+		}
+	}
+
+	for _, order := range orders {
+		p.sendCommit(ctx, order)
+	}
+}
+
+// abandon отказывается от пачки, которую не удалось обработать полностью.
+// Ее партиции возвращаются к наименьшему неподтвержденному офсету (см.
+// SetRewinder), даже если часть сообщений пачки уже подтверждена: иначе
+// подтверждения следующих сообщений закоммитили бы офсеты выше, и
+// необработанные сообщения были бы потеряны.
+func (p *Processor) abandon(orders []*sarama.ConsumerMessage, reason string) {
+	// Подтверждений сообщений пачки не будет, ждать их нельзя.
+	if p.marks != nil {
+		p.marks.reset(orders)
+	}
+
+	if p.rewinder == nil {
+		p.log.Error("batch is not committed and can't be rewound, its messages may be lost",
+			slog.Int("batch", len(orders)),
+			slog.String("reason", reason),
+		)
+		return
+	}
+
+	p.log.Warn("batch is not committed, rewinding its partitions to the lowest unacknowledged offset",
+		slog.Int("batch", len(orders)),
+		slog.String("reason", reason),
+	)
+	metrics.ProcessorBatchRewinds.Inc()
+	p.rewinder.Rewind(orders)
+}
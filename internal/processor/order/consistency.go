@@ -0,0 +1,74 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/YusovID/order-service/internal/metrics"
+	"github.com/YusovID/order-service/internal/models"
+)
+
+// Режимы проверки согласованности сумм заказа (см. models.OrderData.CheckConsistency).
+const (
+	ConsistencyFlag    = "flag"    // Расхождения пишутся в лог и метрики, заказ сохраняется как есть.
+	ConsistencyCorrect = "correct" // Суммы исправляются по товарам заказа.
+	ConsistencyReject  = "reject"  // Заказ с расхождениями отправляется в DLQ.
+)
+
+// ErrInconsistentOrder - причина отправки в DLQ заказа с расхождениями в суммах.
+var ErrInconsistentOrder = errors.New("inconsistent order")
+
+// SetConsistency задает режим проверки сумм заказа. По умолчанию
+// используется ConsistencyFlag. Вызывается до запуска ProcessOrders.
+func (p *Processor) SetConsistency(mode string) error {
+	switch mode {
+	case "":
+		p.consistency = ConsistencyFlag
+	case ConsistencyFlag, ConsistencyCorrect, ConsistencyReject:
+		p.consistency = mode
+	default:
+		return fmt.Errorf("unknown consistency mode %q, supported: %s, %s, %s",
+			mode, ConsistencyFlag, ConsistencyCorrect, ConsistencyReject)
+	}
+	return nil
+}
+
+// enrichOrder нормализует заказ и сверяет его суммы согласно режиму проверки.
+// Возвращает ошибку, если в режиме ConsistencyReject найдены расхождения.
+func (p *Processor) enrichOrder(log *slog.Logger, orderData *models.OrderData) error {
+	orderData.Normalize()
+
+	var issues []models.Inconsistency
+	if p.consistency == ConsistencyCorrect {
+		issues = orderData.FixConsistency()
+	} else {
+		issues = orderData.CheckConsistency()
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+
+	details := make([]string, len(issues))
+	for i, issue := range issues {
+		details[i] = issue.String()
+		metrics.ProcessorInconsistencies.WithLabelValues(issue.Field).Inc()
+	}
+
+	switch p.consistency {
+	case ConsistencyReject:
+		return fmt.Errorf("%w: %s", ErrInconsistentOrder, strings.Join(details, "; "))
+	case ConsistencyCorrect:
+		log.Warn("corrected inconsistent order totals",
+			slog.String("order_uid", orderData.OrderUID),
+			slog.Any("corrections", details),
+		)
+	default:
+		log.Warn("order totals are inconsistent",
+			slog.String("order_uid", orderData.OrderUID),
+			slog.Any("inconsistencies", details),
+		)
+	}
+	return nil
+}
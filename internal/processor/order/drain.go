@@ -0,0 +1,64 @@
+package processor
+
+import (
+	"context"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// defaultDrainTimeout - время на завершение обработки после отмены контекста,
+// если оно не задано через SetDrainTimeout. Должно превышать время, которое
+// консьюмер ждет подтверждений при завершении сессии.
+const defaultDrainTimeout = 30 * time.Second
+
+// SetDrainTimeout задает, сколько после отмены контекста обработчик
+// продолжает обрабатывать и подтверждать уже полученные сообщения
+// (см. ProcessOrders). 0 - значение по умолчанию.
+func (p *Processor) SetDrainTimeout(timeout time.Duration) {
+	p.drainTimeout = timeout
+}
+
+// drainContext возвращает контекст обработки сообщений. Он не отменяется
+// вместе с `ctx`: после отмены `ctx` у обработки есть еще drainTimeout,
+// чтобы завершить и подтвердить сообщения, уже переданные консьюмером.
+func (p *Processor) drainContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := p.drainTimeout
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+
+	drainCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(timeout, cancel)
+	})
+
+	return drainCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// sendCommit передает подтверждение консьюмеру. Если контекст обработки
+// отменен (истек срок завершения), подтверждение отбрасывается: консьюмер
+// уже не ждет его, и сообщение будет доставлено повторно.
+//
+// Если отслеживается порядок подтверждений (см. EnablePriority), сообщение
+// передается консьюмеру только вместе с сообщениями партиции до него.
+func (p *Processor) sendCommit(ctx context.Context, msg *sarama.ConsumerMessage) {
+	if p.marks == nil {
+		p.send(ctx, msg)
+		return
+	}
+	for _, released := range p.marks.done(msg) {
+		p.send(ctx, released)
+	}
+}
+
+// send передает подтверждение в commitChan, пока не отменен контекст.
+func (p *Processor) send(ctx context.Context, msg *sarama.ConsumerMessage) {
+	select {
+	case p.commitChan <- msg:
+	case <-ctx.Done():
+	}
+}
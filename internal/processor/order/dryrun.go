@@ -0,0 +1,23 @@
+package processor
+
+import (
+	"log/slog"
+
+	"github.com/YusovID/order-service/internal/metrics"
+)
+
+// SetDryRun включает режим проверки без записи: сообщения декодируются,
+// проверяются и учитываются в метриках, но заказы не сохраняются, в DLQ
+// ничего не отправляется, а учет обработанных сообщений не ведется.
+// Режим нужен для теневого запуска сервиса на боевых топиках.
+// Вызывается до запуска ProcessOrders.
+func (p *Processor) SetDryRun(enabled bool) {
+	p.dryRun = enabled
+}
+
+// dryRunOrder завершает обработку проверенного заказа без сохранения.
+func (p *Processor) dryRunOrder(job *orderJob) {
+	job.log.Info("dry run, order is valid but not saved", slog.String("order_uid", job.data.OrderUID))
+	metrics.ProcessorSkipped.WithLabelValues("dry_run").Inc()
+	job.observe(errTypeNone)
+}
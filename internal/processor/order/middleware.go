@@ -0,0 +1,134 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Middleware оборачивает обработчик сообщений дополнительным шагом
+// (логирование, трассировка, обогащение и т.д.). Промежуточный обработчик
+// сам решает, передавать ли сообщение дальше, вызывая `next`.
+type Middleware func(next Handler) Handler
+
+// MiddlewareFactory создает промежуточный обработчик для процессора `p`.
+type MiddlewareFactory func(p *Processor) Middleware
+
+// Встроенные промежуточные обработчики.
+const (
+	MiddlewareRecover = "recover"
+	MiddlewareLog     = "log"
+)
+
+// middlewares хранит фабрики промежуточных обработчиков по названию.
+var middlewares = struct {
+	sync.RWMutex
+	factories map[string]MiddlewareFactory
+}{
+	factories: map[string]MiddlewareFactory{
+		MiddlewareRecover: recoverMiddleware,
+		MiddlewareLog:     logMiddleware,
+	},
+}
+
+// RegisterMiddleware добавляет промежуточный обработчик `name`, после чего
+// его можно включить в `processor.middlewares`. Повторная регистрация
+// названия заменяет фабрику. Вызывается до UseNamed, обычно из init пакета.
+func RegisterMiddleware(name string, factory MiddlewareFactory) {
+	middlewares.Lock()
+	defer middlewares.Unlock()
+
+	middlewares.factories[name] = factory
+}
+
+// Use добавляет промежуточные обработчики вокруг всех обработчиков сообщений,
+// включая обработку новых заказов. Первый добавленный обработчик вызывается
+// первым. Метод нужно вызывать до запуска ProcessOrders.
+func (p *Processor) Use(mw ...Middleware) {
+	p.middlewares = append(p.middlewares, mw...)
+}
+
+// UseNamed добавляет зарегистрированные промежуточные обработчики по названиям
+// в указанном порядке (см. Use и RegisterMiddleware).
+func (p *Processor) UseNamed(names ...string) error {
+	middlewares.RLock()
+	defer middlewares.RUnlock()
+
+	mw := make([]Middleware, 0, len(names))
+	for _, name := range names {
+		factory, ok := middlewares.factories[name]
+		if !ok {
+			return fmt.Errorf("unknown middleware %q, supported: %v", name, middlewareNames())
+		}
+		mw = append(mw, factory(p))
+	}
+
+	p.Use(mw...)
+	return nil
+}
+
+// chain оборачивает `handler` промежуточными обработчиками процессора.
+func (p *Processor) chain(handler Handler) Handler {
+	for i := len(p.middlewares) - 1; i >= 0; i-- {
+		handler = p.middlewares[i](handler)
+	}
+	return handler
+}
+
+// middlewareNames возвращает отсортированный список зарегистрированных
+// промежуточных обработчиков. Вызывается под блокировкой реестра.
+func middlewareNames() []string {
+	names := make([]string, 0, len(middlewares.factories))
+	for name := range middlewares.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// recoverMiddleware перехватывает панику обработчика, чтобы один заказ не
+// останавливал сервис. Сообщение отправляется в DLQ вместе с текстом паники.
+func recoverMiddleware(p *Processor) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *sarama.ConsumerMessage) {
+			defer func() {
+				if r := recover(); r != nil {
+					err := fmt.Errorf("panic while handling message: %v", r)
+					p.log.Error("recovered from panic in message handler",
+						slog.String("topic", msg.Topic),
+						slog.Int("partition", int(msg.Partition)),
+						slog.Int64("offset", msg.Offset),
+						slog.Any("panic", r),
+						slog.String("stack", string(debug.Stack())),
+					)
+					p.sendToDLQ(ctx, p.log, msg, err)
+				}
+			}()
+
+			next(ctx, msg)
+		}
+	}
+}
+
+// logMiddleware пишет в отладочный лог каждое сообщение и время его обработки.
+func logMiddleware(p *Processor) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *sarama.ConsumerMessage) {
+			start := time.Now()
+			next(ctx, msg)
+
+			p.log.Debug("message handled",
+				slog.String("topic", msg.Topic),
+				slog.Int("partition", int(msg.Partition)),
+				slog.Int64("offset", msg.Offset),
+				slog.Duration("duration", time.Since(start)),
+			)
+		}
+	}
+}
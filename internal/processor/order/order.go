@@ -5,13 +5,18 @@ package processor
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/codec"
+	"github.com/YusovID/order-service/internal/metrics"
 	"github.com/YusovID/order-service/internal/models"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/YusovID/order-service/internal/storage/kafka"
 	"github.com/YusovID/order-service/lib/logger/sl"
 	wp "github.com/YusovID/order-service/lib/workerpool"
 )
@@ -23,12 +28,57 @@ type Storage interface {
 	SaveOrder(ctx context.Context, orderData *models.OrderData) error
 }
 
+// Codec определяет интерфейс для декодирования тела сообщения в заказ.
+// Конкретный формат (JSON, Protocol Buffers и любые другие, см. codec.Register)
+// и версия схемы (заголовок schema_version, см. codec.Versioned) выбираются
+// при создании Processor, логика обработки от них не зависит.
+// Если кодек не передан, используется JSON.
+type Codec interface {
+	Decode(schemaVersion string, data []byte) (*models.OrderData, error)
+}
+
+// Handler обрабатывает одно сообщение из Kafka.
+// Обработчики регистрируются для конкретных топиков через Processor.Handle.
+type Handler func(ctx context.Context, msg *sarama.ConsumerMessage)
+
+// DeadLetterQueue определяет интерфейс для отправки сообщений,
+// которые не удалось обработать, в отдельный топик.
+type DeadLetterQueue interface {
+	Send(ctx context.Context, msg *sarama.ConsumerMessage, reason error) error
+}
+
+// Cache определяет интерфейс кэша заказов. После сохранения заказа
+// его запись в кэше удаляется, чтобы API не отдавало устаревшую версию.
+type Cache interface {
+	DeleteOrder(ctx context.Context, orderUID string) error
+}
+
+// Ledger определяет интерфейс учета обработанных сообщений. Kafka доставляет
+// сообщения минимум один раз: после падения или ребалансировки сообщения
+// с незакоммиченными офсетами читаются снова, и учет позволяет не повторять
+// их обработку и побочные эффекты (публикацию событий, сброс кэша).
+type Ledger interface {
+	IsProcessed(ctx context.Context, topic string, partition int32, offset int64) (bool, error)
+	MarkProcessed(ctx context.Context, topic string, partition int32, offset int64) error
+}
+
+// Rewinder возвращает партиции сообщений к наименьшему неподтвержденному
+// офсету, чтобы консьюмер прочитал их заново (см. SetRewinder).
+type Rewinder interface {
+	Rewind(msgs []*sarama.ConsumerMessage)
+}
+
 // IPool определяет интерфейс для пула воркеров.
 // Это позволяет абстрагироваться от конкретной реализации worker pool.
 type IPool interface {
-	Create()
-	Handle(context.Context, *sarama.ConsumerMessage)
+	Handle(context.Context, []*sarama.ConsumerMessage) error
+	TrySubmit(context.Context, []*sarama.ConsumerMessage) error
 	Wait()
+
+	// Size, Grow и Shrink позволяют менять число воркеров во время работы.
+	Size() int
+	Grow(n int)
+	Shrink(n int)
 }
 
 // Processor инкапсулирует логику обработки заказов.
@@ -36,24 +86,161 @@ type IPool interface {
 // сообщения для коммита в `commitChan`.
 type Processor struct {
 	Storage    Storage
+	codec      Codec                          // Декодер тела сообщений.
+	dlq        DeadLetterQueue                // DLQ для необработанных сообщений. Может быть nil.
+	cache      Cache                          // Кэш заказов для инвалидации. Может быть nil.
+	ledger     Ledger                         // Учет обработанных сообщений. Может быть nil.
+	handlers   map[string]Handler             // Обработчики сообщений по топикам.
+	fallback   Handler                        // Обработчик для топиков без зарегистрированного обработчика.
+	health     health                         // Статистика ошибок хранилища для backpressure.
+	saturated  atomic.Bool                    // Пул воркеров и его очередь заполнены, для backpressure.
+	panics     atomic.Int64                   // Паники в пуле воркеров, перехваченные WithRecover.
+	workers    atomic.Int64                   // Лимит воркеров пула (см. SetWorkers). 0 - wp.MaxWorkersCount.
 	orderChan  <-chan *sarama.ConsumerMessage // Канал для получения сообщений от Kafka-консьюмера.
 	commitChan chan<- *sarama.ConsumerMessage // Канал для отправки подтверждений (коммитов) консьюмеру.
 	log        *slog.Logger
+
+	middlewares []Middleware // Промежуточные обработчики вокруг обработчиков сообщений.
+	consistency string       // Режим проверки сумм заказа (см. SetConsistency). Пустой - ConsistencyFlag.
+	updater     Updater      // Хранилище для изменения заказов (см. EnableUpdates).
+	batchCommit bool         // Подтверждать пачку целиком (см. SetCommitStrategy).
+	rewinder    Rewinder     // Перечитывание неподтвержденной пачки (см. SetRewinder). Может быть nil.
+	scrub       *scrubber    // Обезличивание полей доставки (см. EnableScrubbing). nil - отключено.
+	dryRun      bool         // Проверять сообщения без записи (см. SetDryRun).
+
+	drainTimeout    time.Duration // Время на завершение обработки после отмены контекста (см. SetDrainTimeout).
+	batchInterval   time.Duration // Период обработки накопленной пачки (см. SetBatching).
+	maxBatch        int           // Размер пачки, при котором она обрабатывается сразу. 0 - без ограничения.
+	retry           saveRetry     // Повторы сохранения заказа (см. SetSaveRetry).
+	priorityWorkers int           // Воркеры для срочных сообщений (см. EnablePriority). 0 - отключено.
+	marks           *watermark    // Порядок подтверждений внутри партиции (см. EnablePriority). nil - не отслеживается.
+	crashOnPanic    bool          // Не перехватывать паники в пуле воркеров (см. SetCrashOnPanic).
+
+	// Заполняются EnableQuarantine.
+	attempts        AttemptTracker  // Счетчик попыток обработки. nil - карантин отключен.
+	quarantine      DeadLetterQueue // Топик карантина.
+	quarantineAfter int             // Допустимое число прерванных попыток.
+
+	// Заполняются EnableBatchWrites.
+	batchStorage BatchStorage // Хранилище для пакетного сохранения. nil - транзакция на каждый заказ.
+	batchSize    int          // Максимум заказов в одной транзакции.
 }
 
 // New создает новый экземпляр Processor.
 func New(
 	storage Storage,
+	orderCodec Codec,
+	dlq DeadLetterQueue,
+	cache Cache,
+	ledger Ledger,
 	orderChan <-chan *sarama.ConsumerMessage,
 	commitChan chan<- *sarama.ConsumerMessage,
 	log *slog.Logger,
 ) *Processor {
-	return &Processor{
+	if orderCodec == nil {
+		orderCodec = codec.NewVersioned(codec.JSON{})
+	}
+
+	p := &Processor{
 		Storage:    storage,
+		codec:      orderCodec,
+		dlq:        dlq,
+		cache:      cache,
+		ledger:     ledger,
+		handlers:   make(map[string]Handler),
 		orderChan:  orderChan,
 		commitChan: commitChan,
 		log:        log,
 	}
+	// По умолчанию сообщения из любого топика считаются новыми заказами.
+	p.fallback = p.processOrder
+
+	return p
+}
+
+// Handle регистрирует обработчик для сообщений из топика `topic`.
+// Сообщения из топиков без обработчика обрабатываются как новые заказы.
+// Метод нужно вызывать до запуска ProcessOrders.
+func (p *Processor) Handle(topic string, handler Handler) {
+	p.handlers[topic] = handler
+}
+
+// route выбирает обработчик по топику сообщения и вызывает его
+// через цепочку промежуточных обработчиков (см. Use).
+// Сообщения, уже отмеченные в учете как обработанные, пропускаются,
+// а многократно прерванные отправляются в карантин (см. EnableQuarantine);
+// после обработки сообщение отмечается.
+func (p *Processor) route(ctx context.Context, msg *sarama.ConsumerMessage) {
+	if p.processed(ctx, msg) {
+		return
+	}
+	if p.quarantined(ctx, msg) {
+		p.markProcessed(ctx, msg)
+		return
+	}
+
+	handler, ok := p.handlers[msg.Topic]
+	if !ok {
+		handler = p.fallback
+	}
+	p.chain(handler)(ctx, msg)
+
+	p.markProcessed(ctx, msg)
+}
+
+// processed сообщает, что сообщение уже обработано. Если учет не настроен
+// или недоступен, сообщение считается необработанным: лучше обработать
+// его повторно, чем потерять. В режиме SetDryRun учет не используется.
+func (p *Processor) processed(ctx context.Context, msg *sarama.ConsumerMessage) bool {
+	if p.ledger == nil || p.dryRun {
+		return false
+	}
+
+	done, err := p.ledger.IsProcessed(ctx, msg.Topic, msg.Partition, msg.Offset)
+	if err != nil {
+		p.log.Warn("failed to check message in ledger", sl.Err(err))
+		return false
+	}
+	if done {
+		p.log.Info("message already processed, skipping",
+			slog.String("topic", msg.Topic),
+			slog.Int("partition", int(msg.Partition)),
+			slog.Int64("offset", msg.Offset),
+		)
+		metrics.ProcessorReplays.Inc()
+		metrics.ProcessorSkipped.WithLabelValues("replay").Inc()
+	}
+	return done
+}
+
+// markProcessed отмечает сообщение в учете как обработанное и сбрасывает
+// счетчик попыток его обработки.
+func (p *Processor) markProcessed(ctx context.Context, msg *sarama.ConsumerMessage) {
+	if p.failed(ctx, msg) {
+		return
+	}
+	p.clearAttempts(ctx, msg)
+
+	if p.ledger == nil || p.dryRun {
+		return
+	}
+
+	if err := p.ledger.MarkProcessed(ctx, msg.Topic, msg.Partition, msg.Offset); err != nil {
+		p.log.Warn("failed to mark message as processed", sl.Err(err))
+	}
+}
+
+// defaultBatchInterval - период обработки накопленной пачки, если он не
+// задан через SetBatching.
+const defaultBatchInterval = time.Second
+
+// SetBatching задает период `interval`, с которым обрабатывается накопленная
+// пачка сообщений, и размер `size`, при котором пачка обрабатывается сразу,
+// не дожидаясь периода. Нулевой период - значение по умолчанию, нулевой
+// размер - без ограничения. Вызывается до запуска ProcessOrders.
+func (p *Processor) SetBatching(interval time.Duration, size int) {
+	p.batchInterval = interval
+	p.maxBatch = size
 }
 
 // ProcessOrders запускает бесконечный цикл для чтения и обработки сообщений о заказах.
@@ -61,89 +248,324 @@ func New(
 // Функция работает как демон: она постоянно слушает канал `orderChan`.
 // Для повышения производительности сообщения обрабатываются пачками (батчами).
 // При накоплении достаточного количества сообщений или по истечении времени
-// они отправляются на параллельную обработку в пул воркеров.
+// они отправляются на параллельную обработку в пул воркеров. Срочные
+// сообщения (см. EnablePriority) обрабатываются сразу выделенными воркерами.
 //
-// Принимает `ctx` для graceful shutdown: при отмене контекста цикл завершается.
+// Принимает `ctx` для graceful shutdown. Отмена контекста не прерывает
+// обработку сразу: консьюмер еще дожидается подтверждений для сообщений,
+// переданных обработчику, поэтому цикл продолжает читать `orderChan`, пока
+// консьюмер не закроет его. После этого оставшиеся сообщения обрабатываются
+// последней пачкой, и цикл завершается. Вся обработка после отмены `ctx`
+// ограничена drainTimeout (см. SetDrainTimeout): по его истечении цикл
+// завершается, а неподтвержденные сообщения будут доставлены повторно.
 func (p *Processor) ProcessOrders(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	const fn = "processor.order.ProcessOrders"
 	log := p.log.With("fn", fn)
 
+	// Сообщения обрабатываются в контексте, который переживает `ctx` на drainTimeout.
+	drainCtx, cancel := p.drainContext(ctx)
+	defer cancel()
+	stop := ctx.Done()
+
 	// Слайс для накопления сообщений перед пакетной обработкой.
 	orders := make([]*sarama.ConsumerMessage, 0, wp.MaxWorkersCount)
-	pool := wp.New(p.processOrder) // Создаем пул воркеров с нашей функцией обработки.
+	// Создаем пул воркеров, каждый из которых обрабатывает шард сообщений.
+	// Пачки обрабатываются целиком, поэтому при остановке пул уже свободен.
+	pool := p.newPool()
+	pool.Start()
+	defer func() {
+		if undone := pool.Stop(drainCtx); len(undone) != 0 {
+			log.Warn("worker pool stopped with unprocessed shards", slog.Int("shards", len(undone)))
+		}
+	}()
+
+	// Выделенные воркеры срочных сообщений. Останавливаются при выходе из цикла.
+	priority := p.startPriority(drainCtx)
+	defer priority.stop()
 
-	ticker := time.NewTicker(1 * time.Second)
+	interval := p.batchInterval
+	if interval <= 0 {
+		interval = defaultBatchInterval
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		// Если контекст отменен, обрабатываем оставшиеся сообщения и выходим.
-		case <-ctx.Done():
-			if len(orders) != 0 {
-				p.processBatch(ctx, orders, pool)
-				// Очищаем слайс для следующей пачки.
-				orders = make([]*sarama.ConsumerMessage, 0, wp.MaxWorkersCount)
-			}
-			log.Info("stopping processing order by context")
+		// Контекст отменен: продолжаем обработку, пока консьюмер не закроет orderChan.
+		case <-stop:
+			stop = nil
+			log.Info("draining orders before shutdown", slog.Int("pending", len(orders)+len(p.orderChan)))
+
+		// Срок завершения истек: оставшиеся сообщения не подтверждаются.
+		case <-drainCtx.Done():
+			log.Warn("drain timeout exceeded, stopping processing orders",
+				slog.Int("unprocessed", len(orders)+len(p.orderChan)),
+			)
 			return
 
 		// Читаем новое сообщение из канала и добавляем его в слайс.
-		case order := <-p.orderChan:
+		case order, ok := <-p.orderChan:
+			if !ok {
+				// Консьюмер остановлен: обрабатываем последнюю пачку и выходим.
+				if len(orders) != 0 {
+					priority.wait()
+					p.processBatch(drainCtx, orders, pool)
+				}
+				log.Info("stopping processing orders, order channel closed")
+				return
+			}
+			if p.marks != nil {
+				p.marks.add(order)
+			}
+			// Срочные сообщения не ждут накопления пачки, если только в пачке
+			// нет более ранних сообщений того же заказа.
+			if priority != nil && highPriority(order) && !hasKey(orders, order.Key) {
+				priority.submit(drainCtx, order)
+				continue
+			}
 			orders = append(orders, order)
+			// Пачка набрана: обрабатываем, не дожидаясь тикера.
+			if p.maxBatch > 0 && len(orders) >= p.maxBatch {
+				priority.wait()
+				p.processBatch(drainCtx, orders, pool)
+				orders = make([]*sarama.ConsumerMessage, 0, wp.MaxWorkersCount)
+			}
 
-		// Раз в секунду вычитываем пачку.
+		// Раз в batchInterval вычитываем пачку.
 		case <-ticker.C:
-			p.processBatch(ctx, orders, pool)
+			priority.wait()
+			p.processBatch(drainCtx, orders, pool)
 			orders = make([]*sarama.ConsumerMessage, 0, wp.MaxWorkersCount)
 		}
 	}
 }
 
 // processBatch отправляет пачку сообщений на параллельную обработку в пул воркеров.
+//
+// Сообщения раскладываются по шардам по хэшу ключа (см. shardOrders), и каждый
+// шард целиком обрабатывается одним воркером. Поэтому сообщения одного заказа
+// никогда не обрабатываются параллельно и сохраняют порядок.
+//
+// При стратегии CommitBatch пачка подтверждается целиком (см. processBatchAtomic).
+// При CommitPerMessage сообщения подтверждаются по мере обработки; если
+// обработка шарда прервалась паникой, оставшиеся сообщения шарда не
+// подтверждены, и партиции пачки перечитываются (см. abandon).
 func (p *Processor) processBatch(ctx context.Context, orders []*sarama.ConsumerMessage, pool IPool) {
-	pool.Create() // Инициализируем (заполняем) пул воркерами.
-	wg := &sync.WaitGroup{}
+	if p.batchCommit {
+		p.processBatchAtomic(ctx, orders, pool)
+		return
+	}
 
-	for _, order := range orders {
-		wg.Add(1)
-		go func(currentOrder *sarama.ConsumerMessage) {
-			defer wg.Done()
-			// Передаем сообщение в пул. Handle заблокируется, пока не освободится воркер.
-			pool.Handle(ctx, currentOrder)
+	panics := p.panics.Load()
+	p.runBatch(ctx, orders, pool)
+	if p.panics.Load() != panics {
+		p.abandon(orders, "batch processing panicked")
+	}
+}
 
-			p.commitChan <- currentOrder
-		}(order)
+// runBatch раскладывает пачку по шардам и обрабатывает их в пуле воркеров.
+func (p *Processor) runBatch(ctx context.Context, orders []*sarama.ConsumerMessage, pool IPool) {
+	for _, shard := range shardOrders(orders, p.workerLimit()) {
+		if len(shard) == 0 {
+			continue
+		}
+		// Передаем шард в пул. Если заняты все воркеры и очередь, просим
+		// консьюмер приостановить чтение и ждем, пока освободится воркер.
+		err := pool.TrySubmit(ctx, shard)
+		if errors.Is(err, wp.ErrPoolSaturated) {
+			p.saturated.Store(true)
+			err = pool.Handle(ctx, shard)
+		}
+		if err != nil {
+			// Пул остановлен: обрабатываем шард сами, чтобы не потерять его.
+			p.processShard(ctx, shard)
+		}
 	}
 
-	wg.Wait()
 	pool.Wait() // Ожидаем, пока все воркеры в пуле завершат работу.
+	p.saturated.Store(false)
+
+	// Подстраиваем число воркеров для следующей пачки под состояние хранилища.
+	p.adjustWorkers(pool)
 }
 
 // processOrder является основной функцией-обработчиком одного сообщения.
-// Она десериализует JSON, валидирует данные и сохраняет их в хранилище.
+// Она декодирует тело сообщения, валидирует данные и сохраняет их в хранилище.
+//
+// Метаданные из заголовков сообщения (correlation_id, версия и т.д.) кладутся
+// в контекст обработки и добавляются ко всем записям лога.
+//
+// Длительность обработки и ее исход экспортируются в метриках processor.
 func (p *Processor) processOrder(ctx context.Context, order *sarama.ConsumerMessage) {
-	p.log.Info("received new order")
-
-	var orderData models.OrderData
-	// Десериализуем тело сообщения в структуру OrderData.
-	if err := json.Unmarshal(order.Value, &orderData); err != nil {
-		p.log.Error("can't unmarshal json, skipping message", sl.Err(err))
-		// Возвращаем nil, чтобы "пропустить" невалидное сообщение и подтвердить его,
-		// иначе оно будет постоянно повторяться
+	job := p.prepareOrder(ctx, order)
+	if job == nil {
 		return
 	}
 
-	p.log.Info("saving order in database", slog.String("order_uid", orderData.OrderUID))
+	if p.dryRun {
+		p.dryRunOrder(job)
+		return
+	}
+
+	// Сохраняем заказ в базу данных, повторяя неудачные попытки.
+	p.finishOrder(job, p.saveOrder(job.ctx, job.log, job.data))
+}
+
+// orderJob - заказ, прошедший декодирование и валидацию и ожидающий сохранения.
+type orderJob struct {
+	ctx   context.Context
+	log   *slog.Logger
+	msg   *sarama.ConsumerMessage
+	data  *models.OrderData
+	start time.Time // Начало обработки сообщения.
+}
+
+// observe записывает исход и длительность обработки сообщения в метрики.
+func (j *orderJob) observe(errType string) {
+	if errType != errTypeNone {
+		metrics.ProcessorFailed.WithLabelValues(errType).Inc()
+	}
+	metrics.ProcessorDuration.WithLabelValues(errType).Observe(time.Since(j.start).Seconds())
+}
+
+// prepareOrder декодирует и валидирует сообщение. Невалидные сообщения
+// отправляются в DLQ, и для них возвращается nil.
+func (p *Processor) prepareOrder(ctx context.Context, order *sarama.ConsumerMessage) *orderJob {
+	md := kafka.MetadataFromMessage(order)
+	job := &orderJob{
+		ctx:   kafka.WithMetadata(ctx, md),
+		log:   p.log.With(md.LogAttrs()...),
+		msg:   order,
+		start: time.Now(),
+	}
+
+	job.log.Info("received new order")
+	metrics.ProcessorReceived.Inc()
 
-	// Сохраняем заказ в базу данных.
-	if err := p.Storage.SaveOrder(ctx, &orderData); err != nil {
-		// TODO реализовать retry + DLQ
+	// Декодируем тело сообщения в структуру OrderData.
+	// Версия схемы выбирает декодер и преобразование в models.OrderData.
+	orderData, err := p.codec.Decode(md.SchemaVersion, order.Value)
+	if err != nil {
+		job.log.Error("can't decode message, skipping message", sl.Err(err))
+		// Невалидное сообщение не имеет смысла обрабатывать повторно,
+		// поэтому отправляем его в DLQ и подтверждаем, иначе оно будет постоянно повторяться.
+		p.sendToDLQ(job.ctx, job.log, order, err)
+		job.observe(errTypeDecode)
+		return nil
+	}
+
+	// Нормализуем заказ и сверяем суммы оплаты с товарами до валидации,
+	// чтобы исправленные суммы проходили ее правила.
+	if err := p.enrichOrder(job.log, orderData); err != nil {
+		job.log.Error("inconsistent order, skipping message",
+			slog.String("order_uid", orderData.OrderUID),
+			sl.Err(err),
+		)
+		p.sendToDLQ(job.ctx, job.log, order, err)
+		job.observe(errTypeConsistency)
+		return nil
+	}
 
-		p.log.Error("failed to save order in database", sl.Err(err))
+	// Заказ с нарушением правил валидации тоже не исправится при повторной
+	// обработке. Список полей с ошибками попадает в лог и в заголовок DLQ.
+	if err := orderData.Validate(); err != nil {
+		job.log.Error("invalid order, skipping message",
+			slog.String("order_uid", orderData.OrderUID),
+			sl.Err(err),
+		)
+		p.sendToDLQ(job.ctx, job.log, order, err)
+		job.observe(errTypeValidation)
+		return nil
+	}
+
+	// Обезличиваем персональные данные после валидации: хэши и маски
+	// не проходят правила формата телефона и почты.
+	p.scrubOrder(orderData)
+
+	// Время отправки сообщения служит версией заказа: более старые
+	// сообщения не перезапишут более новые данные.
+	orderData.UpdatedAt = md.ProducedAt
+	job.data = orderData
+
+	job.log.Info("saving order in database", slog.String("order_uid", orderData.OrderUID))
+
+	return job
+}
+
+// finishOrder завершает обработку заказа по результату сохранения `err`:
+// сбрасывает кэш или отправляет сообщение в DLQ. Событие order.created
+// хранилище записывает в outbox в одной транзакции с заказом.
+func (p *Processor) finishOrder(job *orderJob, err error) {
+	ctx, log, order, orderData := job.ctx, job.log, job.msg, job.data
+
+	if errors.Is(err, storage.ErrDuplicateOrder) {
+		log.Info("order already saved, skipping duplicate", slog.String("order_uid", orderData.OrderUID))
+		metrics.ProcessorDuplicates.Inc()
+		metrics.ProcessorSkipped.WithLabelValues("duplicate").Inc()
+		p.health.success()
+		job.observe(errTypeNone)
+		return
+	}
+	if err != nil {
+		log.Error("failed to save order in database", sl.Err(err))
+		// Сообщение будет подтверждено, поэтому после исчерпания повторов
+		// (или при остановке сервиса) оно откладывается в DLQ, чтобы его
+		// можно было переотправить. При CommitBatch сообщение с временной
+		// ошибкой вместо этого обрабатывается повторно.
+		if temporary(err) {
+			p.fail(ctx, log, order, err)
+		} else {
+			p.sendToDLQ(ctx, log, order, err)
+		}
+		job.observe(saveErrType(err))
+		return
+	}
+	p.health.success()
+	metrics.ProcessorSaved.Inc()
+
+	log.Info("saving was successful", slog.String("order_uid", orderData.OrderUID))
+
+	if p.cache != nil {
+		if err := p.cache.DeleteOrder(ctx, orderData.OrderUID); err != nil {
+			log.Error("failed to invalidate cached order", sl.Err(err))
+		}
+	}
+
+	job.observe(errTypeNone)
+}
+
+// Типы ошибок обработки заказа в метриках processor.
+const (
+	errTypeNone        = "none"
+	errTypeDecode      = "decode"
+	errTypeValidation  = "validation"
+	errTypeConsistency = "consistency"
+	errTypeStorage     = "storage"
+	errTypeCanceled    = "canceled"
+)
+
+// saveErrType возвращает тип ошибки сохранения заказа для метрик.
+func saveErrType(err error) string {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return errTypeCanceled
+	}
+	return errTypeStorage
+}
+
+// sendToDLQ отправляет сообщение в DLQ, если она настроена.
+func (p *Processor) sendToDLQ(ctx context.Context, log *slog.Logger, order *sarama.ConsumerMessage, reason error) {
+	if p.dlq == nil {
+		return
+	}
+	if p.dryRun {
+		log.Info("dry run, message is not sent to dlq", sl.Err(reason))
 		return
 	}
 
-	p.log.Info("saving was successful", slog.String("order_uid", orderData.OrderUID))
+	if err := p.dlq.Send(ctx, order, reason); err != nil {
+		log.Error("failed to send message to dlq", sl.Err(err))
+	}
 }
@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/metrics"
+	wp "github.com/YusovID/order-service/lib/workerpool"
+)
+
+// poolName - имя пула воркеров обработчика в метриках.
+const poolName = "processor"
+
+// SetWorkers задает максимальное число воркеров, обрабатывающих шарды пачки
+// параллельно. 0 - wp.MaxWorkersCount. Безопасен для вызова во время работы
+// (например, при перезагрузке конфигурации): пул подстраивается под новый
+// лимит после очередной пачки.
+func (p *Processor) SetWorkers(workers int) {
+	p.workers.Store(int64(max(workers, 0)))
+}
+
+// workerLimit возвращает максимальное число воркеров (см. SetWorkers).
+func (p *Processor) workerLimit() int {
+	if workers := int(p.workers.Load()); workers > 0 {
+		return workers
+	}
+	return wp.MaxWorkersCount
+}
+
+// SetCrashOnPanic отключает перехват паник в пуле воркеров. По умолчанию
+// паника при обработке шарда не роняет процесс: она пишется в лог и метрики,
+// а партиции пачки возвращаются к наименьшему неподтвержденному офсету и
+// читаются заново (см. SetRewinder), поэтому необработанные сообщения шарда
+// не будут подтверждены сообщениями других шардов.
+// Вызывается до запуска ProcessOrders.
+func (p *Processor) SetCrashOnPanic(crash bool) {
+	p.crashOnPanic = crash
+}
+
+// newPool создает пул воркеров для шардов пачки и регистрирует его метрики.
+// Очередь пула вмещает шарды пачки, которые не поместились в уменьшенный пул.
+func (p *Processor) newPool() *wp.Pool[[]*sarama.ConsumerMessage] {
+	opts := []wp.Option{
+		wp.WithQueue(p.workerLimit()),
+		wp.WithHooks(wp.Hooks{
+			TaskDone: func(duration time.Duration) {
+				metrics.WorkerPoolTaskDuration.WithLabelValues(poolName).Observe(duration.Seconds())
+			},
+			Panic: func(value any, stack []byte) {
+				p.panics.Add(1)
+				metrics.WorkerPoolPanics.WithLabelValues(poolName).Inc()
+				p.log.Error("panic in worker pool task",
+					slog.Any("panic", value),
+					slog.String("stack", string(stack)),
+				)
+			},
+		}),
+	}
+	if !p.crashOnPanic {
+		opts = append(opts, wp.WithRecover())
+	}
+	pool := wp.New(p.processShard, opts...)
+	if limit := p.workerLimit(); limit < pool.Size() {
+		pool.Shrink(pool.Size() - limit)
+	} else {
+		pool.Grow(limit - pool.Size())
+	}
+
+	metrics.RegisterWorkerPool(poolName, func() metrics.WorkerPoolStats {
+		s := pool.Stats()
+		return metrics.WorkerPoolStats{Size: s.Size, Busy: s.Busy, Queued: s.Queued}
+	})
+
+	return pool
+}
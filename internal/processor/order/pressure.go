@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Пороги заполнения orderChan (в долях от емкости канала), при которых
+// консьюмер приостанавливает и возобновляет чтение из Kafka.
+// Разные пороги дают гистерезис, чтобы чтение не "дребезжало".
+const (
+	highWatermark = 0.9
+	lowWatermark  = 0.5
+)
+
+// Параметры определения сбоев хранилища.
+const (
+	// failureThreshold - количество ошибок сохранения подряд, после которого
+	// хранилище считается недоступным.
+	failureThreshold = 3
+	// failureCooldown - время после последней ошибки, в течение которого хранилище
+	// считается недоступным. По его истечении чтение возобновляется, и первые
+	// же сообщения служат пробой: если хранилище не восстановилось, чтение снова остановится.
+	failureCooldown = 5 * time.Second
+)
+
+// health отслеживает ошибки сохранения заказов в хранилище.
+type health struct {
+	mu          sync.Mutex
+	failures    int
+	lastFailure time.Time
+}
+
+// success сбрасывает счетчик ошибок.
+func (h *health) success() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.failures = 0
+}
+
+// failure регистрирует очередную ошибку сохранения.
+func (h *health) failure() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.failures++
+	h.lastFailure = time.Now()
+}
+
+// failing сообщает, что хранилище сейчас считается недоступным.
+func (h *health) failing() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.failures >= failureThreshold && time.Since(h.lastFailure) < failureCooldown
+}
+
+// Overloaded сообщает, что обработчик не справляется с потоком сообщений:
+// orderChan почти заполнен, хранилище возвращает ошибки или пул воркеров
+// не принимает новые шарды. Используется консьюмером для приостановки чтения из Kafka.
+func (p *Processor) Overloaded() bool {
+	return p.queueRatio() >= highWatermark || p.health.failing() || p.saturated.Load()
+}
+
+// Relieved сообщает, что давление спало и чтение из Kafka можно возобновить.
+func (p *Processor) Relieved() bool {
+	return p.queueRatio() <= lowWatermark && !p.health.failing() && !p.saturated.Load()
+}
+
+// queueRatio возвращает долю заполнения orderChan.
+// Для небуферизированного канала всегда возвращает 0.
+func (p *Processor) queueRatio() float64 {
+	if cap(p.orderChan) == 0 {
+		return 0
+	}
+	return float64(len(p.orderChan)) / float64(cap(p.orderChan))
+}
+
+// adjustWorkers подстраивает число воркеров под состояние хранилища. Пока
+// хранилище считается недоступным, размер пула после каждой пачки уменьшается
+// вдвое, чтобы не добавлять нагрузки на базу. Затем он по одному воркеру
+// возвращается к лимиту (см. SetWorkers). Если лимит уменьшили, пул сразу
+// уменьшается до него.
+func (p *Processor) adjustWorkers(pool IPool) {
+	size, limit := pool.Size(), p.workerLimit()
+
+	switch failing := p.health.failing(); {
+	case size > limit:
+		pool.Shrink(size - limit)
+	case failing && size > 1:
+		pool.Shrink(size / 2)
+	case !failing && size < limit:
+		pool.Grow(1)
+	default:
+		return
+	}
+
+	p.log.Info("worker pool resized", slog.Int("from", size), slog.Int("to", pool.Size()))
+}
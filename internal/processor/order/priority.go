@@ -0,0 +1,137 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/metrics"
+	"github.com/YusovID/order-service/internal/storage/kafka"
+)
+
+// EnablePriority выделяет `workers` воркеров для срочных заказов - сообщений
+// с заголовком `priority: high` (kafka.PriorityHigh). Такие сообщения не ждут
+// накопления пачки и обрабатываются сразу, пока остальные сообщения идут
+// через обычную пакетную обработку. 0 отключает приоритетную обработку:
+// срочные сообщения обрабатываются вместе с остальными.
+//
+// Срочное сообщение обрабатывается раньше сообщений той же партиции,
+// полученных до него, но подтверждается только после них (см. watermark):
+// иначе при сбое они были бы потеряны. Порядок сообщений одного заказа
+// сохраняется: срочное сообщение с ключом, который уже есть в накопленной
+// пачке, обрабатывается вместе с пачкой, а срочные сообщения с одинаковым
+// ключом обрабатываются одним воркером по очереди.
+// Вызывается до запуска ProcessOrders.
+func (p *Processor) EnablePriority(workers int) {
+	p.priorityWorkers = max(workers, 0)
+	if p.priorityWorkers > 0 {
+		p.marks = newWatermark(p.log)
+	}
+}
+
+// highPriority сообщает, что сообщение помечено как срочное.
+func highPriority(msg *sarama.ConsumerMessage) bool {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == kafka.HeaderPriority {
+			return string(h.Value) == kafka.PriorityHigh
+		}
+	}
+	return false
+}
+
+// hasKey сообщает, что в пачке `orders` есть сообщение с ключом `key`.
+// Сообщения без ключа не упорядочиваются.
+func hasKey(orders []*sarama.ConsumerMessage, key []byte) bool {
+	if len(key) == 0 {
+		return false
+	}
+	for _, order := range orders {
+		if bytes.Equal(order.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// priorityLane - выделенные воркеры для срочных сообщений. У каждого
+// воркера своя очередь, и сообщения с одинаковым ключом всегда попадают
+// в одну очередь, как и в shardOrders.
+type priorityLane struct {
+	queues  []chan *sarama.ConsumerMessage
+	unkeyed int            // Счетчик сообщений без ключа для равномерного распределения.
+	pending sync.WaitGroup // Переданные, но еще не обработанные сообщения.
+	wg      sync.WaitGroup
+}
+
+// startPriority запускает воркеров для срочных сообщений.
+// Возвращает nil, если приоритетная обработка отключена.
+func (p *Processor) startPriority(ctx context.Context) *priorityLane {
+	if p.priorityWorkers == 0 {
+		return nil
+	}
+
+	lane := &priorityLane{queues: make([]chan *sarama.ConsumerMessage, p.priorityWorkers)}
+	for i := range lane.queues {
+		queue := make(chan *sarama.ConsumerMessage, 1)
+		lane.queues[i] = queue
+
+		lane.wg.Add(1)
+		go func() {
+			defer lane.wg.Done()
+			for msg := range queue {
+				// После истечения срока завершения сообщения не обрабатываются:
+				// они не подтверждены и будут доставлены повторно.
+				if ctx.Err() == nil {
+					p.route(ctx, msg)
+					p.commit(ctx, msg)
+				}
+				lane.pending.Done()
+			}
+		}()
+	}
+
+	return lane
+}
+
+// submit передает срочное сообщение воркеру, выбранному по ключу.
+// Блокируется, если очередь воркера занята.
+func (l *priorityLane) submit(ctx context.Context, msg *sarama.ConsumerMessage) {
+	metrics.ProcessorPriority.Inc()
+
+	var idx int
+	if len(msg.Key) == 0 {
+		idx = l.unkeyed % len(l.queues)
+		l.unkeyed++
+	} else {
+		idx = int(keyHash(msg.Key) % uint32(len(l.queues)))
+	}
+
+	l.pending.Add(1)
+	select {
+	case l.queues[idx] <- msg:
+	case <-ctx.Done():
+		l.pending.Done()
+	}
+}
+
+// wait дожидается обработки уже переданных срочных сообщений. Вызывается
+// перед обработкой пачки, чтобы сообщения пачки не обогнали срочные
+// сообщения того же заказа, полученные раньше них.
+func (l *priorityLane) wait() {
+	if l == nil {
+		return
+	}
+	l.pending.Wait()
+}
+
+// stop дожидается обработки переданных срочных сообщений и останавливает воркеров.
+func (l *priorityLane) stop() {
+	if l == nil {
+		return
+	}
+	for _, queue := range l.queues {
+		close(queue)
+	}
+	l.wg.Wait()
+}
@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/metrics"
+	"github.com/YusovID/order-service/lib/logger/sl"
+)
+
+// ErrPoisonMessage - причина отправки сообщения в карантин: его обработка
+// несколько раз подряд прерывалась, не дойдя до коммита.
+var ErrPoisonMessage = errors.New("poison message")
+
+// AttemptTracker определяет интерфейс счетчика попыток обработки сообщений.
+// Счетчик должен переживать перезапуск сервиса: сообщение, которое роняет
+// сервис, читается заново после каждого перезапуска и не дает партиции
+// продвигаться дальше.
+type AttemptTracker interface {
+	StartAttempt(ctx context.Context, topic string, partition int32, offset int64) (int64, error)
+	ClearAttempts(ctx context.Context, topic string, partition int32, offset int64) error
+}
+
+// EnableQuarantine включает карантин: сообщение, обработка которого
+// прервалась `after` раз, при следующем чтении не обрабатывается, а
+// отправляется в `quarantine` и подтверждается.
+//
+// Вызывается до запуска ProcessOrders.
+func (p *Processor) EnableQuarantine(attempts AttemptTracker, quarantine DeadLetterQueue, after int) {
+	if after < 1 {
+		after = 1
+	}
+	p.attempts = attempts
+	p.quarantine = quarantine
+	p.quarantineAfter = after
+}
+
+// quarantined начинает новую попытку обработки сообщения и сообщает, что
+// сообщение отправлено в карантин вместо обработки. Если счетчик попыток
+// или топик карантина недоступны, сообщение обрабатывается как обычно.
+func (p *Processor) quarantined(ctx context.Context, msg *sarama.ConsumerMessage) bool {
+	if p.attempts == nil {
+		return false
+	}
+
+	attempt, err := p.attempts.StartAttempt(ctx, msg.Topic, msg.Partition, msg.Offset)
+	if err != nil {
+		p.log.Warn("failed to count processing attempt", sl.Err(err))
+		return false
+	}
+	if attempt <= int64(p.quarantineAfter) {
+		return false
+	}
+
+	reason := fmt.Errorf("%w: processing interrupted %d times", ErrPoisonMessage, attempt-1)
+	p.log.Error("moving message to quarantine",
+		slog.String("topic", msg.Topic),
+		slog.Int("partition", int(msg.Partition)),
+		slog.Int64("offset", msg.Offset),
+		sl.Err(reason),
+	)
+	// Сообщение, не попавшее в карантин, нельзя подтверждать: оно было бы
+	// потеряно. Обрабатываем его как обычно, карантин повторится при
+	// следующем чтении.
+	if err := p.quarantine.Send(ctx, msg, reason); err != nil {
+		p.log.Error("failed to send message to quarantine, processing it instead", sl.Err(err))
+		return false
+	}
+	metrics.ProcessorQuarantined.Inc()
+
+	return true
+}
+
+// clearAttempts сбрасывает счетчик попыток обработки сообщения.
+func (p *Processor) clearAttempts(ctx context.Context, msg *sarama.ConsumerMessage) {
+	if p.attempts == nil {
+		return
+	}
+
+	if err := p.attempts.ClearAttempts(ctx, msg.Topic, msg.Partition, msg.Offset); err != nil {
+		p.log.Warn("failed to clear processing attempts", sl.Err(err))
+	}
+}
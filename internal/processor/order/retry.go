@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/YusovID/order-service/lib/logger/sl"
+)
+
+// Параметры повторного сохранения заказа обработчиком по умолчанию
+// (см. SetSaveRetry). Хранилище само быстро повторяет временные ошибки базы
+// (deadlock, обрыв соединения); здесь повторы реже и дольше, чтобы пережить
+// кратковременную недоступность базы, прежде чем отправлять сообщение в DLQ.
+const (
+	saveAttempts       = 3                      // Всего попыток, включая первую.
+	saveInitialBackoff = 500 * time.Millisecond // Пауза перед второй попыткой.
+	saveMaxBackoff     = 10 * time.Second       // Максимальная пауза.
+)
+
+// saveRetry - параметры повторного сохранения заказа.
+type saveRetry struct {
+	attempts       int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// SetSaveRetry задает число попыток сохранения заказа (включая первую)
+// и пределы паузы между ними. Нулевые значения оставляют значения по
+// умолчанию. Вызывается до запуска ProcessOrders.
+func (p *Processor) SetSaveRetry(attempts int, initialBackoff, maxBackoff time.Duration) {
+	p.retry = saveRetry{attempts: attempts, initialBackoff: initialBackoff, maxBackoff: maxBackoff}
+}
+
+// saveRetry (unexported) возвращает параметры повторов с подставленными
+// значениями по умолчанию.
+func (p *Processor) saveRetry() saveRetry {
+	r := p.retry
+	if r.attempts <= 0 {
+		r.attempts = saveAttempts
+	}
+	if r.initialBackoff <= 0 {
+		r.initialBackoff = saveInitialBackoff
+	}
+	if r.maxBackoff <= 0 {
+		r.maxBackoff = saveMaxBackoff
+	}
+	return r
+}
+
+// saveOrder сохраняет заказ, повторяя неудачные попытки с экспоненциальной
+// паузой и случайным разбросом (jitter), чтобы воркеры не повторяли запросы
+// к восстанавливающейся базе одновременно.
+//
+// Не повторяются ошибки, которые не исправятся сами: дубликат заказа и
+// отмена контекста. Возвращает ошибку последней попытки.
+func (p *Processor) saveOrder(ctx context.Context, log *slog.Logger, orderData *models.OrderData) error {
+	ctx = storage.WithActor(ctx, "kafka")
+
+	retry := p.saveRetry()
+
+	var err error
+	for attempt := 1; attempt <= retry.attempts; attempt++ {
+		if attempt > 1 {
+			wait := retry.backoff(attempt - 1)
+			log.Warn("retrying order save",
+				slog.Int("attempt", attempt),
+				slog.Duration("backoff", wait),
+				sl.Err(err),
+			)
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+
+		err = p.Storage.SaveOrder(ctx, orderData)
+		if err == nil || errors.Is(err, storage.ErrDuplicateOrder) || ctx.Err() != nil {
+			return err
+		}
+		p.health.failure()
+	}
+
+	return err
+}
+
+// backoff возвращает паузу перед повтором номер `retry` (начиная с 1):
+// экспоненциально растущая пауза, ограниченная maxBackoff, из которой
+// случайно выбирается значение от половины до полной (equal jitter).
+func (r saveRetry) backoff(retry int) time.Duration {
+	d := r.initialBackoff << (retry - 1)
+	if d <= 0 || d > r.maxBackoff {
+		d = r.maxBackoff
+	}
+
+	half := d / 2
+	return half + rand.N(half+1)
+}
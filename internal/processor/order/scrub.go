@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/YusovID/order-service/internal/metrics"
+	"github.com/YusovID/order-service/internal/models"
+)
+
+// Режимы обезличивания персональных данных заказа (см. EnableScrubbing).
+const (
+	ScrubHash = "hash" // Значение заменяется HMAC-SHA256 от него: одинаковые значения дают одинаковый хэш.
+	ScrubMask = "mask" // Значение маскируется звездочками, остаются только несколько символов.
+)
+
+// scrubFields - поля доставки, которые можно обезличивать, по имени в конфигурации.
+var scrubFields = map[string]func(d *models.Delivery) *string{
+	"name":    func(d *models.Delivery) *string { return &d.Name },
+	"phone":   func(d *models.Delivery) *string { return &d.Phone },
+	"email":   func(d *models.Delivery) *string { return &d.Email },
+	"address": func(d *models.Delivery) *string { return &d.Address },
+	"zip":     func(d *models.Delivery) *string { return &d.Zip },
+}
+
+// scrubber обезличивает выбранные поля доставки перед сохранением заказа.
+type scrubber struct {
+	mode   string
+	fields []string
+	salt   []byte
+}
+
+// EnableScrubbing включает обезличивание полей доставки `fields` (name,
+// phone, email, address, zip) перед сохранением заказа. Поля проверяются
+// валидацией до обезличивания, поэтому правила формата к ним не применяются.
+// `salt` - ключ HMAC для режима ScrubHash; без него одинаковые телефоны и
+// адреса легко подобрать по словарю. Пустой список полей отключает обезличивание.
+// Вызывается до запуска ProcessOrders.
+func (p *Processor) EnableScrubbing(mode string, fields []string, salt string) error {
+	if len(fields) == 0 {
+		p.scrub = nil
+		return nil
+	}
+
+	switch mode {
+	case "":
+		mode = ScrubHash
+	case ScrubHash, ScrubMask:
+	default:
+		return fmt.Errorf("unknown scrub mode %q, supported: %s, %s", mode, ScrubHash, ScrubMask)
+	}
+
+	for _, field := range fields {
+		if _, ok := scrubFields[field]; !ok {
+			supported := make([]string, 0, len(scrubFields))
+			for name := range scrubFields {
+				supported = append(supported, name)
+			}
+			slices.Sort(supported)
+			return fmt.Errorf("unknown scrub field %q, supported: %s", field, strings.Join(supported, ", "))
+		}
+	}
+
+	p.scrub = &scrubber{mode: mode, fields: fields, salt: []byte(salt)}
+	return nil
+}
+
+// scrubOrder обезличивает поля доставки заказа, если обезличивание включено.
+func (p *Processor) scrubOrder(orderData *models.OrderData) {
+	if p.scrub == nil {
+		return
+	}
+
+	for _, field := range p.scrub.fields {
+		value := scrubFields[field](&orderData.Delivery)
+		if *value == "" {
+			continue
+		}
+		*value = p.scrub.apply(field, *value)
+		metrics.ProcessorScrubbed.WithLabelValues(field).Inc()
+	}
+}
+
+// apply возвращает обезличенное значение поля `field`.
+func (s *scrubber) apply(field, value string) string {
+	if s.mode == ScrubMask {
+		if field == "email" {
+			return maskEmail(value)
+		}
+		return mask(value, 2)
+	}
+
+	h := hmac.New(sha256.New, s.salt)
+	h.Write([]byte(value))
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// mask заменяет звездочками все символы `value`, кроме последних `keep`.
+func mask(value string, keep int) string {
+	runes := []rune(value)
+	hidden := max(len(runes)-keep, len(runes)/2)
+	for i := range hidden {
+		runes[i] = '*'
+	}
+	return string(runes)
+}
+
+// maskEmail маскирует имя почтового ящика, оставляя первый символ и домен.
+func maskEmail(value string) string {
+	local, domain, ok := strings.Cut(value, "@")
+	if !ok {
+		return mask(value, 2)
+	}
+
+	runes := []rune(local)
+	if len(runes) == 0 {
+		return value
+	}
+	return string(runes[0]) + strings.Repeat("*", max(len(runes)-1, 3)) + "@" + domain
+}
@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"context"
+	"hash/fnv"
+
+	"github.com/IBM/sarama"
+)
+
+// shardOrders распределяет пачку сообщений по `n` шардам.
+//
+// Сообщения с одинаковым ключом (order_uid) всегда попадают в один шард,
+// а шард обрабатывается одним воркером последовательно. Так обновления
+// одного заказа применяются в том порядке, в котором лежат в партиции.
+// Сообщения без ключа упорядочивать не нужно, поэтому они распределяются
+// по шардам равномерно.
+func shardOrders(orders []*sarama.ConsumerMessage, n int) [][]*sarama.ConsumerMessage {
+	shards := make([][]*sarama.ConsumerMessage, n)
+
+	unkeyed := 0
+	for _, order := range orders {
+		var idx int
+		if len(order.Key) == 0 {
+			idx = unkeyed % n
+			unkeyed++
+		} else {
+			idx = int(keyHash(order.Key) % uint32(n))
+		}
+		shards[idx] = append(shards[idx], order)
+	}
+
+	return shards
+}
+
+// keyHash возвращает хэш FNV-1a ключа сообщения.
+func keyHash(key []byte) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write(key) // hash.Hash.Write никогда не возвращает ошибку.
+	return h.Sum32()
+}
+
+// processShard последовательно обрабатывает сообщения одного шарда
+// и подтверждает каждое после обработки. Если включено пакетное сохранение
+// (см. EnableBatchWrites), шард обрабатывается processShardBatched.
+func (p *Processor) processShard(ctx context.Context, shard []*sarama.ConsumerMessage) {
+	if p.batchStorage != nil && !p.dryRun {
+		p.processShardBatched(ctx, shard)
+		return
+	}
+
+	for _, order := range shard {
+		p.route(ctx, order)
+		p.commit(ctx, order)
+	}
+}
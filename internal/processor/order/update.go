@@ -0,0 +1,127 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/metrics"
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/YusovID/order-service/internal/storage/kafka"
+	"github.com/YusovID/order-service/lib/logger/sl"
+)
+
+// Updater определяет интерфейс хранилища для изменения существующих заказов.
+type Updater interface {
+	UpdateOrder(ctx context.Context, orderData *models.OrderData) error
+	CancelOrder(ctx context.Context, orderUID string) (models.OrderStatus, error)
+}
+
+// EnableUpdates регистрирует обработчик сообщений об изменении заказов
+// (models.OrderUpdate в JSON) для топика `topic`. Такие сообщения не
+// создают заказы, а изменяют или отменяют существующие через `updater`.
+//
+// Вызывается до запуска ProcessOrders.
+func (p *Processor) EnableUpdates(topic string, updater Updater) {
+	p.updater = updater
+	p.Handle(topic, p.processUpdate)
+}
+
+// processUpdate обрабатывает одно сообщение об изменении заказа. Сообщения,
+// которые нельзя применить (нет заказа, недопустимая отмена), отправляются
+// в DLQ: например, изменение могло обогнать создание заказа, и его можно
+// переотправить позже.
+func (p *Processor) processUpdate(ctx context.Context, msg *sarama.ConsumerMessage) {
+	md := kafka.MetadataFromMessage(msg)
+	ctx = storage.WithActor(kafka.WithMetadata(ctx, md), "kafka")
+	log := p.log.With(md.LogAttrs()...)
+
+	var update models.OrderUpdate
+	if err := json.Unmarshal(msg.Value, &update); err != nil {
+		log.Error("can't decode order update, skipping message", sl.Err(err))
+		metrics.ProcessorUpdates.WithLabelValues("unknown", "invalid").Inc()
+		p.sendToDLQ(ctx, log, msg, fmt.Errorf("can't unmarshal order update: %v", err))
+		return
+	}
+
+	log = log.With(slog.String("op", update.Op), slog.String("order_uid", update.OrderUID))
+	log.Info("received order update")
+
+	if update.Op == models.OpUpdate && update.Order != nil {
+		if err := p.enrichOrder(log, update.Order); err != nil {
+			p.rejectUpdate(ctx, log, msg, update, err)
+			return
+		}
+	}
+	if err := update.Validate(); err != nil {
+		p.rejectUpdate(ctx, log, msg, update, err)
+		return
+	}
+
+	if p.dryRun {
+		log.Info("dry run, order update is valid but not applied")
+		metrics.ProcessorUpdates.WithLabelValues(update.Op, "dry_run").Inc()
+		return
+	}
+
+	var err error
+	switch update.Op {
+	case models.OpUpdate:
+		// Как и для новых заказов, версией служит время отправки сообщения.
+		p.scrubOrder(update.Order)
+		update.Order.UpdatedAt = md.ProducedAt
+		err = p.updater.UpdateOrder(ctx, update.Order)
+	case models.OpCancel:
+		var from models.OrderStatus
+		from, err = p.updater.CancelOrder(ctx, update.OrderUID)
+		if err == nil {
+			log = log.With(slog.String("previous_status", string(from)))
+		}
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrDuplicateOrder):
+		log.Info("order update is outdated, skipping")
+		metrics.ProcessorUpdates.WithLabelValues(update.Op, "duplicate").Inc()
+		return
+	case err != nil:
+		log.Error("failed to apply order update", sl.Err(err))
+		metrics.ProcessorUpdates.WithLabelValues(update.Op, "failed").Inc()
+		if temporary(err) {
+			p.fail(ctx, log, msg, err)
+		} else {
+			p.sendToDLQ(ctx, log, msg, err)
+		}
+		return
+	}
+
+	log.Info("order update applied")
+	metrics.ProcessorUpdates.WithLabelValues(update.Op, "applied").Inc()
+
+	if p.cache != nil {
+		if err := p.cache.DeleteOrder(ctx, update.OrderUID); err != nil {
+			log.Error("failed to invalidate cached order", sl.Err(err))
+		}
+	}
+}
+
+// rejectUpdate отправляет в DLQ сообщение об изменении заказа с ошибкой в данных.
+func (p *Processor) rejectUpdate(ctx context.Context, log *slog.Logger, msg *sarama.ConsumerMessage, update models.OrderUpdate, err error) {
+	log.Error("invalid order update, skipping message", sl.Err(err))
+	metrics.ProcessorUpdates.WithLabelValues(opLabel(update.Op), "invalid").Inc()
+	p.sendToDLQ(ctx, log, msg, err)
+}
+
+// opLabel возвращает операцию для метрик. Неизвестные операции из
+// некорректных сообщений объединяются, чтобы не плодить метки.
+func opLabel(op string) string {
+	switch op {
+	case models.OpUpdate, models.OpCancel:
+		return op
+	}
+	return "unknown"
+}
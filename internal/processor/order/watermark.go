@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// stuckAfter - через сколько необработанное сообщение, задерживающее
+// подтверждения следующих сообщений партиции, считается зависшим.
+const stuckAfter = time.Minute
+
+// partitionKey определяет партицию топика.
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// watermark не дает подтвердить сообщение раньше сообщений той же партиции
+// с меньшими офсетами. Kafka хранит для партиции один офсет, поэтому
+// подтверждение сообщения означает и подтверждение всех сообщений до него:
+// если срочное сообщение обогнало пачку, его подтверждение откладывается,
+// пока не будут обработаны все полученные до него сообщения партиции.
+//
+// Если сообщение так и не обработано (например, обработчик его потерял),
+// подтверждения партиции копятся до перезапуска сессии: об этом пишется
+// предупреждение, когда сообщение задерживает их дольше stuckAfter.
+type watermark struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionMarks
+	log        *slog.Logger
+	now        func() time.Time
+}
+
+// partitionMarks - состояние подтверждений одной партиции.
+type partitionMarks struct {
+	pending map[int64]time.Time       // Офсеты полученных, но еще не обработанных сообщений, и время получения.
+	held    []*sarama.ConsumerMessage // Обработанные сообщения, ожидающие меньших офсетов, по возрастанию офсета.
+	warned  int64                     // Офсет, о зависании которого уже предупредили; -1 - не было.
+}
+
+// newWatermark создает пустой учет подтверждений.
+func newWatermark(log *slog.Logger) *watermark {
+	return &watermark{
+		partitions: make(map[partitionKey]*partitionMarks),
+		log:        log,
+		now:        time.Now,
+	}
+}
+
+// add регистрирует сообщение, полученное от консьюмера.
+func (w *watermark) add(msg *sarama.ConsumerMessage) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	key := partitionKey{msg.Topic, msg.Partition}
+	marks, ok := w.partitions[key]
+	if !ok {
+		marks = &partitionMarks{pending: make(map[int64]time.Time), warned: -1}
+		w.partitions[key] = marks
+	}
+	marks.pending[msg.Offset] = w.now()
+}
+
+// done отмечает сообщение обработанным и возвращает сообщения партиции,
+// которые теперь можно подтвердить: все полученные сообщения с меньшими
+// офсетами уже обработаны.
+func (w *watermark) done(msg *sarama.ConsumerMessage) []*sarama.ConsumerMessage {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	key := partitionKey{msg.Topic, msg.Partition}
+	marks, ok := w.partitions[key]
+	if !ok {
+		return []*sarama.ConsumerMessage{msg}
+	}
+	delete(marks.pending, msg.Offset)
+
+	i := len(marks.held)
+	for i > 0 && marks.held[i-1].Offset > msg.Offset {
+		i--
+	}
+	marks.held = append(marks.held, nil)
+	copy(marks.held[i+1:], marks.held[i:])
+	marks.held[i] = msg
+
+	low := int64(-1) // Наименьший необработанный офсет; -1 - таких нет.
+	for offset := range marks.pending {
+		if low == -1 || offset < low {
+			low = offset
+		}
+	}
+
+	n := len(marks.held)
+	if low != -1 {
+		n = 0
+		for n < len(marks.held) && marks.held[n].Offset < low {
+			n++
+		}
+	}
+	released := marks.held[:n:n]
+	marks.held = marks.held[n:]
+
+	if len(marks.held) > 0 && marks.warned != low {
+		if waiting := w.now().Sub(marks.pending[low]); waiting >= stuckAfter {
+			marks.warned = low
+			w.log.Warn("unprocessed message is holding back commits of its partition",
+				slog.String("topic", key.topic),
+				slog.Int("partition", int(key.partition)),
+				slog.Int64("offset", low),
+				slog.Duration("waiting", waiting),
+				slog.Int("held", len(marks.held)),
+			)
+		}
+	}
+
+	if len(marks.pending) == 0 && len(marks.held) == 0 {
+		delete(w.partitions, key)
+	}
+
+	return released
+}
+
+// reset забывает партиции сообщений `msgs`: их сообщения будут прочитаны
+// заново (см. SetRewinder), и ждать прежних подтверждений не нужно.
+func (w *watermark) reset(msgs []*sarama.ConsumerMessage) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, msg := range msgs {
+		delete(w.partitions, partitionKey{msg.Topic, msg.Partition})
+	}
+}
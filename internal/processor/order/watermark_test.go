@@ -0,0 +1,107 @@
+package processor
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// offsets возвращает офсеты сообщений.
+func offsets(msgs []*sarama.ConsumerMessage) []int64 {
+	got := make([]int64, 0, len(msgs))
+	for _, msg := range msgs {
+		got = append(got, msg.Offset)
+	}
+	return got
+}
+
+// TestWatermarkOutOfOrder проверяет, что сообщение, обработанное раньше
+// сообщений партиции с меньшими офсетами, подтверждается только после них,
+// а другие партиции его не ждут.
+func TestWatermarkOutOfOrder(t *testing.T) {
+	w := newWatermark(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	msgs := make([]*sarama.ConsumerMessage, 5)
+	for i := range msgs {
+		msgs[i] = &sarama.ConsumerMessage{Topic: "orders", Partition: 0, Offset: int64(10 + i)}
+		w.add(msgs[i])
+	}
+	other := &sarama.ConsumerMessage{Topic: "orders", Partition: 1, Offset: 3}
+	w.add(other)
+
+	steps := []struct {
+		done *sarama.ConsumerMessage
+		want []int64
+	}{
+		{msgs[3], nil},
+		{msgs[1], nil},
+		{other, []int64{3}},
+		{msgs[0], []int64{10, 11}},
+		{msgs[2], []int64{12, 13}},
+		{msgs[4], []int64{14}},
+	}
+	for _, step := range steps {
+		got := w.done(step.done)
+		if !slices.Equal(offsets(got), step.want) {
+			t.Fatalf("done(%d/%d) released %v, want %v",
+				step.done.Partition, step.done.Offset, offsets(got), step.want)
+		}
+	}
+
+	if len(w.partitions) != 0 {
+		t.Errorf("partitions = %d after all messages are done, want 0", len(w.partitions))
+	}
+}
+
+// TestWatermarkStuckOffset проверяет, что сообщение, которое так и не
+// обработано, задерживает подтверждения партиции и об этом один раз
+// пишется предупреждение, а после reset подтверждения не задерживаются.
+func TestWatermarkStuckOffset(t *testing.T) {
+	var buf bytes.Buffer
+	w := newWatermark(slog.New(slog.NewTextHandler(&buf, nil)))
+	now := time.Date(2025, 3, 14, 15, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+
+	msgs := make([]*sarama.ConsumerMessage, 4)
+	for i := range msgs {
+		msgs[i] = &sarama.ConsumerMessage{Topic: "orders", Partition: 0, Offset: int64(i)}
+		w.add(msgs[i])
+	}
+
+	// msgs[0] не обрабатывается никогда.
+	if got := w.done(msgs[1]); len(got) != 0 {
+		t.Fatalf("done(1) released %v, want nothing", offsets(got))
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected warning before %s: %s", stuckAfter, buf.String())
+	}
+
+	now = now.Add(stuckAfter)
+	if got := w.done(msgs[2]); len(got) != 0 {
+		t.Fatalf("done(2) released %v, want nothing", offsets(got))
+	}
+	if got := w.done(msgs[3]); len(got) != 0 {
+		t.Fatalf("done(3) released %v, want nothing", offsets(got))
+	}
+	if n := strings.Count(buf.String(), "holding back commits"); n != 1 {
+		t.Fatalf("got %d warnings, want 1: %s", n, buf.String())
+	}
+	if !strings.Contains(buf.String(), "offset=0") {
+		t.Errorf("warning doesn't name the stuck offset: %s", buf.String())
+	}
+
+	// После перемотки партиции ее сообщения прочитают заново.
+	w.reset(msgs[:1])
+	if len(w.partitions) != 0 {
+		t.Fatalf("partitions = %d after reset, want 0", len(w.partitions))
+	}
+	if got := w.done(msgs[0]); !slices.Equal(offsets(got), []int64{0}) {
+		t.Errorf("done(0) after reset released %v, want [0]", offsets(got))
+	}
+}
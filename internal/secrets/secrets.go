@@ -0,0 +1,67 @@
+// Package secrets загружает учетные данные сервиса (пароли PostgreSQL,
+// Redis и Kafka) из внешнего хранилища секретов при старте, чтобы они не хранились
+// в конфигурации и переменных окружения. Поддерживается HashiCorp Vault.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/YusovID/order-service/internal/config"
+)
+
+// Ключи секретов в хранилище.
+const (
+	KeyPostgresUsername      = "postgres_username"
+	KeyPostgresPassword      = "postgres_password"
+	KeyRedisUsername         = "redis_username"
+	KeyRedisPassword         = "redis_password"
+	KeyRedisSentinelPassword = "redis_sentinel_password"
+	KeyKafkaSASLUsername     = "kafka_sasl_username"
+	KeyKafkaSASLPassword     = "kafka_sasl_password"
+)
+
+// Provider - хранилище секретов.
+type Provider interface {
+	// Secrets возвращает секреты сервиса по ключам.
+	Secrets(ctx context.Context) (map[string]string, error)
+	// Run продлевает аренды (токена и секретов), пока не отменен контекст.
+	Run(ctx context.Context, wg *sync.WaitGroup)
+	// Healthy возвращает ошибку, если учетные данные, с которыми работает
+	// сервис, больше не действительны (например, аренду не удалось продлить).
+	Healthy(ctx context.Context) error
+}
+
+// Apply заполняет учетные данные в `cfg` секретами из `p`. Ключи,
+// отсутствующие в хранилище, оставляют значения из конфигурации.
+func Apply(ctx context.Context, p Provider, cfg *config.Config) error {
+	const fn = "secrets.Apply"
+
+	values, err := p.Secrets(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	fields := map[string]*string{
+		KeyPostgresUsername:      &cfg.Postgres.Username,
+		KeyPostgresPassword:      &cfg.Postgres.Password,
+		KeyRedisUsername:         &cfg.Redis.Username,
+		KeyRedisPassword:         &cfg.Redis.Password,
+		KeyRedisSentinelPassword: &cfg.Redis.Sentinel.Password,
+		KeyKafkaSASLUsername:     &cfg.Kafka.SASL.Username,
+		KeyKafkaSASLPassword:     &cfg.Kafka.SASL.Password,
+	}
+	for key, field := range fields {
+		if value, ok := values[key]; ok {
+			*field = value
+		}
+	}
+
+	if cfg.Postgres.Password == "" {
+		return fmt.Errorf("%s: %w", fn, errors.New("postgres password is not set"))
+	}
+
+	return nil
+}
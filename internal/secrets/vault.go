@@ -0,0 +1,362 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/lib/logger/sl"
+)
+
+// minRenewInterval - минимальная пауза между продлениями аренд.
+const minRenewInterval = 5 * time.Second
+
+// errStaleCredentials - Vault выдал новые учетные данные, а сервис работает
+// со старыми. Состояние не проходит само: нужен перезапуск экземпляра.
+var errStaleCredentials = errors.New("vault issued new credentials, restart required")
+
+// Vault читает секреты из KV-хранилища HashiCorp Vault через HTTP API.
+// Аутентификация - по токену или по AppRole (RoleID и SecretID).
+type Vault struct {
+	cfg    config.Vault
+	client *http.Client
+	log    *slog.Logger
+
+	mu        sync.Mutex
+	token     string
+	tokenTTL  time.Duration // 0 - токен бессрочный или не продлевается.
+	leaseID   string        // Аренда секрета (для динамических секретов).
+	leaseTTL  time.Duration
+	renewable bool
+	values    map[string]string // Секреты, прочитанные при старте и переданные сервису.
+	renewErr  error             // Ошибка последнего продления. nil - учетные данные действительны.
+}
+
+// vaultResponse - общий формат ответа Vault.
+type vaultResponse struct {
+	LeaseID       string          `json:"lease_id"`
+	LeaseDuration int             `json:"lease_duration"`
+	Renewable     bool            `json:"renewable"`
+	Data          json.RawMessage `json:"data"`
+	Auth          *vaultAuth      `json:"auth"`
+	Errors        []string        `json:"errors"`
+}
+
+// vaultAuth - результат входа или продления токена.
+type vaultAuth struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int    `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+}
+
+// NewVault создает клиента Vault с параметрами `cfg`.
+func NewVault(cfg config.Vault, log *slog.Logger) *Vault {
+	return &Vault{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		log:    log.With(slog.String("component", "vault")),
+	}
+}
+
+// Secrets входит в Vault и читает секреты по пути `cfg.Path` в KV-хранилище
+// `cfg.Mount` (версии 1 или 2). Нестроковые значения пропускаются.
+func (v *Vault) Secrets(ctx context.Context) (map[string]string, error) {
+	const fn = "secrets.Vault.Secrets"
+
+	if err := v.login(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	values, err := v.read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	v.mu.Lock()
+	v.values = values
+	v.mu.Unlock()
+
+	return values, nil
+}
+
+// read (unexported) читает секрет и запоминает его аренду.
+func (v *Vault) read(ctx context.Context) (map[string]string, error) {
+	path := fmt.Sprintf("/v1/%s/%s", v.cfg.Mount, strings.TrimPrefix(v.cfg.Path, "/"))
+	if v.cfg.KVVersion == 2 {
+		path = fmt.Sprintf("/v1/%s/data/%s", v.cfg.Mount, strings.TrimPrefix(v.cfg.Path, "/"))
+	}
+
+	resp, err := v.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data := resp.Data
+	if v.cfg.KVVersion == 2 {
+		var kv struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(resp.Data, &kv); err != nil {
+			return nil, fmt.Errorf("cannot decode secret: %w", err)
+		}
+		data = kv.Data
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("cannot decode secret: %w", err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if s, ok := value.(string); ok {
+			values[key] = s
+		}
+	}
+
+	v.mu.Lock()
+	v.leaseID = resp.LeaseID
+	v.leaseTTL = time.Duration(resp.LeaseDuration) * time.Second
+	v.mu.Unlock()
+
+	return values, nil
+}
+
+// Run продлевает токен и аренду секрета по истечении половины их срока,
+// пока не отменен контекст. Если токен продлить не удалось, клиент AppRole
+// входит заново. Если не удалось продлить аренду секрета, секрет читается
+// заново (см. renew).
+func (v *Vault) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		interval := v.renewInterval()
+		if interval == 0 {
+			return
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		v.renew(ctx)
+	}
+}
+
+// renewInterval (unexported) возвращает паузу до следующего продления:
+// половину наименьшего из сроков токена и аренды. 0 - продлевать нечего.
+func (v *Vault) renewInterval() time.Duration {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var ttl time.Duration
+	for _, d := range []time.Duration{v.tokenTTL, v.leaseTTL} {
+		if d > 0 && (ttl == 0 || d < ttl) {
+			ttl = d
+		}
+	}
+	if ttl == 0 {
+		return 0
+	}
+
+	return max(ttl/2, minRenewInterval)
+}
+
+// renew (unexported) продлевает токен и аренду секрета. Результат
+// продления отражается в Healthy.
+func (v *Vault) renew(ctx context.Context) {
+	err := v.renewAll(ctx)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !errors.Is(v.renewErr, errStaleCredentials) {
+		v.renewErr = err
+	}
+}
+
+// renewAll (unexported) продлевает токен и аренду секрета.
+//
+// Если аренду продлить не удалось (например, истек ее максимальный срок),
+// секрет читается заново с новой арендой. Сервис не умеет менять учетные
+// данные на ходу, поэтому, если Vault выдал другие значения, возвращается
+// ошибка: старые учетные данные скоро перестанут действовать, и экземпляр
+// нужно перезапустить.
+func (v *Vault) renewAll(ctx context.Context) error {
+	v.mu.Lock()
+	tokenTTL, renewable, leaseID := v.tokenTTL, v.renewable, v.leaseID
+	v.mu.Unlock()
+
+	if tokenTTL > 0 {
+		if err := v.renewToken(ctx, renewable); err != nil {
+			v.log.Error("failed to renew vault token", sl.Err(err))
+			return fmt.Errorf("can't renew vault token: %w", err)
+		}
+	}
+
+	if leaseID == "" {
+		return nil
+	}
+
+	resp, err := v.do(ctx, http.MethodPut, "/v1/sys/leases/renew", map[string]string{"lease_id": leaseID})
+	if err == nil {
+		v.mu.Lock()
+		v.leaseTTL = time.Duration(resp.LeaseDuration) * time.Second
+		v.mu.Unlock()
+		return nil
+	}
+	v.log.Warn("failed to renew vault lease, reading secret again", slog.String("lease_id", leaseID), sl.Err(err))
+
+	values, err := v.read(ctx)
+	if err != nil {
+		v.log.Error("failed to read secret from vault", sl.Err(err))
+		return fmt.Errorf("can't renew vault lease: %w", err)
+	}
+
+	v.mu.Lock()
+	applied := v.values
+	v.mu.Unlock()
+	if !maps.Equal(values, applied) {
+		v.log.Error("vault issued new credentials, restart the service to apply them")
+		return errStaleCredentials
+	}
+
+	return nil
+}
+
+// Healthy возвращает ошибку последнего продления токена или аренды секрета.
+// Подключается к проверке готовности (/readyz), чтобы экземпляр с
+// истекающими учетными данными выводился из балансировки.
+func (v *Vault) Healthy(context.Context) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.renewErr
+}
+
+// renewToken (unexported) продлевает токен, а если это невозможно -
+// входит заново по AppRole.
+func (v *Vault) renewToken(ctx context.Context, renewable bool) error {
+	if renewable {
+		resp, err := v.do(ctx, http.MethodPost, "/v1/auth/token/renew-self", nil)
+		if err == nil && resp.Auth != nil {
+			v.setToken(resp.Auth.ClientToken, resp.Auth.LeaseDuration, resp.Auth.Renewable)
+			return nil
+		}
+		if v.cfg.RoleID == "" {
+			return err
+		}
+	}
+
+	if v.cfg.RoleID == "" {
+		return errors.New("token is not renewable")
+	}
+
+	return v.login(ctx)
+}
+
+// login (unexported) получает токен: заданный в конфигурации (его срок
+// запрашивается у Vault) или выданный при входе по AppRole.
+func (v *Vault) login(ctx context.Context) error {
+	const fn = "secrets.Vault.login"
+
+	if v.cfg.Token != "" {
+		v.setToken(v.cfg.Token, 0, false)
+
+		resp, err := v.do(ctx, http.MethodGet, "/v1/auth/token/lookup-self", nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+
+		var self struct {
+			TTL       int  `json:"ttl"`
+			Renewable bool `json:"renewable"`
+		}
+		if err := json.Unmarshal(resp.Data, &self); err != nil {
+			return fmt.Errorf("%s: cannot decode token: %w", fn, err)
+		}
+		v.setToken(v.cfg.Token, self.TTL, self.Renewable)
+
+		return nil
+	}
+
+	if v.cfg.RoleID == "" {
+		return fmt.Errorf("%s: %w", fn, errors.New("neither token nor role_id is set"))
+	}
+
+	body := map[string]string{"role_id": v.cfg.RoleID, "secret_id": v.cfg.SecretID}
+	resp, err := v.do(ctx, http.MethodPost, fmt.Sprintf("/v1/auth/%s/login", v.cfg.AuthPath), body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return fmt.Errorf("%s: %w", fn, errors.New("no token in login response"))
+	}
+	v.setToken(resp.Auth.ClientToken, resp.Auth.LeaseDuration, resp.Auth.Renewable)
+
+	return nil
+}
+
+// setToken (unexported) запоминает токен и срок его действия в секундах.
+func (v *Vault) setToken(token string, ttl int, renewable bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.token = token
+	v.tokenTTL = time.Duration(ttl) * time.Second
+	v.renewable = renewable
+}
+
+// do (unexported) выполняет запрос к API Vault с текущим токеном.
+func (v *Vault) do(ctx context.Context, method, path string, body any) (*vaultResponse, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(v.cfg.Address, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	v.mu.Lock()
+	token := v.token
+	v.mu.Unlock()
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+
+	res, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var resp vaultResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil && res.StatusCode < 300 {
+		return nil, fmt.Errorf("cannot decode response: %w", err)
+	}
+	if res.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, res.Status, strings.Join(resp.Errors, "; "))
+	}
+
+	return &resp, nil
+}
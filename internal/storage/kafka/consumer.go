@@ -11,12 +11,27 @@ import (
 
 	"github.com/IBM/sarama"
 	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/correlation"
+	"github.com/YusovID/order-service/internal/metrics"
 	"github.com/YusovID/order-service/lib/logger/sl"
 )
 
-// batchsize - это количество сообщений, после обработки которых
-// будет произведен коммит офсетов.
-const batchsize = 100
+// drainTimeout - максимальное время ожидания подтверждений для сообщений,
+// находящихся в обработке, при завершении сессии (ребалансировке).
+const drainTimeout = 10 * time.Second
+
+// pressureCheckInterval - период проверки давления со стороны обработчика.
+const pressureCheckInterval = 500 * time.Millisecond
+
+// PressureSource сообщает консьюмеру о перегрузке обработчика сообщений.
+// Пока обработчик перегружен, консьюмер приостанавливает чтение из Kafka,
+// вместо того чтобы копить необработанные сообщения в памяти.
+type PressureSource interface {
+	// Overloaded возвращает true, если чтение нужно приостановить.
+	Overloaded() bool
+	// Relieved возвращает true, если чтение можно возобновить.
+	Relieved() bool
+}
 
 // Consumer представляет собой обертку над `sarama.ConsumerGroup` для
 // удобной интеграции в приложение. Он читает сообщения из Kafka и
@@ -25,24 +40,65 @@ type Consumer struct {
 	Consumer   sarama.ConsumerGroup
 	orderChan  chan<- *sarama.ConsumerMessage // Канал для отправки полученных сообщений обработчику.
 	commitChan <-chan *sarama.ConsumerMessage // Канал для получения сообщений, которые нужно "закоммитить".
+	pressure   PressureSource                 // Источник сигналов о перегрузке. Может быть nil.
+	clientID   string                         // ClientID участника группы, метка метрик сессии.
 	log        *slog.Logger
+
+	// Заполняются, если консьюмер работает в составе ConsumerPool.
+	owners      *partitionOwners               // Реестр владельцев партиций пула.
+	commitOwner chan<- *sarama.ConsumerMessage // Канал подтверждений этой сессии в реестре.
+
+	commitBatchSize int           // Количество сообщений, после которого коммитятся офсеты.
+	commitInterval  time.Duration // Период коммита офсетов при малом потоке сообщений.
+
+	reconnectThreshold int           // Количество ошибок за окно, после которого сессия перезапускается.
+	reconnectWindow    time.Duration // Окно подсчета ошибок.
+
+	mu            sync.Mutex
+	cancelSession context.CancelFunc // Завершает текущую сессию консьюмера.
+	handler       *consumerHandler   // Обработчик текущей сессии.
 }
 
 // NewConsumer создает и настраивает новую группу консьюмеров Kafka.
 // Он инициализирует конфигурацию sarama, устанавливая ручное управление
 // коммитами и другие важные параметры, после чего создает ConsumerGroup.
+//
+// Если передан `pressure`, консьюмер приостанавливает чтение всех партиций,
+// пока обработчик перегружен.
 func NewConsumer(
 	cfg config.Kafka,
 	orderChan chan<- *sarama.ConsumerMessage,
 	commitChan <-chan *sarama.ConsumerMessage,
+	pressure PressureSource,
 	log *slog.Logger,
 ) (*Consumer, error) {
-	config := sarama.NewConfig()
+	return newConsumer(cfg, cfg.Consumer.GroupId, orderChan, commitChan, pressure, log)
+}
 
-	config.Consumer.Return.Errors = true                  // Включаем возврат ошибок в канал Errors().
-	config.Consumer.Offsets.Initial = sarama.OffsetOldest // Начинаем чтение с самого старого сообщения, если нет сохраненного офсета.
-	config.Consumer.IsolationLevel = sarama.ReadCommitted // Читаем только "закоммиченные" сообщения от транзакционных продюсеров.
-	config.Consumer.Offsets.AutoCommit.Enable = false     // Отключаем автокоммит, так как управляем им вручную.
+// newConsumer создает консьюмера с указанным ClientID.
+func newConsumer(
+	cfg config.Kafka,
+	clientID string,
+	orderChan chan<- *sarama.ConsumerMessage,
+	commitChan <-chan *sarama.ConsumerMessage,
+	pressure PressureSource,
+	log *slog.Logger,
+) (*Consumer, error) {
+	config, err := newSaramaConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	config.ClientID = clientID
+
+	config.Consumer.Return.Errors = true // Включаем возврат ошибок в канал Errors().
+
+	if err := applyConsumerOffsets(config, cfg.Consumer); err != nil {
+		return nil, fmt.Errorf("invalid consumer config: %v", err)
+	}
+	if err := applyConsumerTuning(config, cfg.Consumer); err != nil {
+		return nil, fmt.Errorf("invalid consumer config: %v", err)
+	}
 
 	// Создаем новую группу консьюмеров.
 	cg, err := sarama.NewConsumerGroup(cfg.BootstrapServers, cfg.Consumer.GroupId, config)
@@ -54,33 +110,148 @@ func NewConsumer(
 		Consumer:   cg,
 		orderChan:  orderChan,
 		commitChan: commitChan,
+		pressure:   pressure,
+		clientID:   clientID,
 		log:        log,
+
+		commitBatchSize: cfg.Consumer.CommitBatchSize,
+		commitInterval:  cfg.Consumer.CommitInterval,
+
+		reconnectThreshold: cfg.Consumer.ReconnectErrorThreshold,
+		reconnectWindow:    cfg.Consumer.ReconnectWindow,
 	}, nil
 }
 
+// applyConsumerOffsets переносит в sarama.Config параметры чтения и
+// коммита офсетов:
+//   - auto.offset.reset: с какого сообщения читать партицию без сохраненного
+//     офсета (earliest - с самого старого, latest - только новые).
+//   - isolation.level: read_committed (1) - только сообщения завершенных
+//     транзакций продюсеров, read_uncommitted (0) - все сообщения.
+//   - enable.auto.commit: дополнительно коммитить офсеты в фоне раз в
+//     commit.interval. Коммитятся только офсеты сообщений, подтвержденных
+//     обработчиком, поэтому гарантия "хотя бы один раз" сохраняется.
+func applyConsumerOffsets(config *sarama.Config, cfg config.Consumer) error {
+	switch cfg.AutoOffsetReset {
+	case "", "earliest":
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	case "latest":
+		config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	default:
+		return fmt.Errorf("unknown auto.offset.reset %q", cfg.AutoOffsetReset)
+	}
+
+	switch cfg.IsolationLevel {
+	case "", "1", "read_committed":
+		config.Consumer.IsolationLevel = sarama.ReadCommitted
+	case "0", "read_uncommitted":
+		config.Consumer.IsolationLevel = sarama.ReadUncommitted
+	default:
+		return fmt.Errorf("unknown isolation.level %q", cfg.IsolationLevel)
+	}
+
+	// По commit.interval работает и таймер коммитов обработчика (ConsumeClaim),
+	// а time.NewTicker паникует на неположительном периоде.
+	if cfg.CommitInterval <= 0 {
+		return fmt.Errorf("commit.interval must be positive, got %s", cfg.CommitInterval)
+	}
+
+	config.Consumer.Offsets.AutoCommit.Enable = cfg.EnableAutoCommit
+	if cfg.EnableAutoCommit {
+		config.Consumer.Offsets.AutoCommit.Interval = cfg.CommitInterval
+	}
+
+	return nil
+}
+
+// applyConsumerTuning переносит параметры сессии и выборки из конфигурации
+// в sarama.Config. Нулевые значения не трогают умолчания sarama.
+//
+// Умолчания (10s session timeout) приводят к лишним ребалансировкам, если
+// запись в БД замедляется, поэтому таймауты стоит увеличивать под нагрузку.
+func applyConsumerTuning(config *sarama.Config, cfg config.Consumer) error {
+	if cfg.SessionTimeout > 0 {
+		config.Consumer.Group.Session.Timeout = cfg.SessionTimeout
+	}
+	if cfg.HeartbeatInterval > 0 {
+		config.Consumer.Group.Heartbeat.Interval = cfg.HeartbeatInterval
+	}
+	if cfg.MaxPollInterval > 0 {
+		config.Consumer.Group.Rebalance.Timeout = cfg.MaxPollInterval
+	}
+	if cfg.FetchMinBytes > 0 {
+		config.Consumer.Fetch.Min = cfg.FetchMinBytes
+	}
+	if cfg.FetchMaxBytes > 0 {
+		config.Consumer.Fetch.Max = cfg.FetchMaxBytes
+	}
+	if cfg.MaxPartitionFetch > 0 {
+		config.Consumer.Fetch.Default = cfg.MaxPartitionFetch
+	}
+	if cfg.FetchMaxWait > 0 {
+		config.Consumer.MaxWaitTime = cfg.FetchMaxWait
+	}
+
+	switch cfg.RebalanceStrategy {
+	case "", "range":
+		config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRange()}
+	case "roundrobin":
+		config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRoundRobin()}
+	case "sticky":
+		config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategySticky()}
+	default:
+		return fmt.Errorf("unknown rebalance strategy %q", cfg.RebalanceStrategy)
+	}
+
+	// Валидируем итоговую конфигурацию (например, heartbeat < session timeout).
+	return config.Validate()
+}
+
 // ProcessMessages запускает бесконечный цикл прослушивания сообщений из Kafka.
+// Консьюмер подписывается сразу на все переданные топики.
 // При отмене контекста `ctx` (graceful shutdown) цикл завершается.
 // Метод использует `consumerHandler` для фактической обработки сообщений.
-func (c *Consumer) ProcessMessages(ctx context.Context, topic string, wg *sync.WaitGroup) {
+func (c *Consumer) ProcessMessages(ctx context.Context, topics []string, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	const fn = "storage.kafka.ProcessMessages"
 	log := c.log.With("fn", fn)
 
+	if c.pressure != nil {
+		wg.Add(1)
+		go c.watchPressure(ctx, wg)
+	}
+
+	wg.Add(1)
+	go c.watchErrors(ctx, wg)
+
 	for {
 		select {
 		case <-ctx.Done():
 			log.Info("stopping message processing")
 			return
 		default:
+			// Контекст сессии позволяет завершить только текущую сессию
+			// (например, при перезапуске из-за ошибок), не останавливая цикл.
+			sessionCtx, cancel := context.WithCancel(ctx)
+			handler := &consumerHandler{
+				orderChan:       c.orderChan,
+				commitChan:      c.commitChan,
+				commitBatchSize: c.commitBatchSize,
+				commitInterval:  c.commitInterval,
+				inflight:        newInflight(),
+				clientID:        c.clientID,
+				owners:          c.owners,
+				commitOwner:     c.commitOwner,
+				Log:             c.log,
+			}
+			c.setSession(cancel, handler)
+
 			// `Consume` блокирует выполнение и запускает сессию консьюмера.
 			// Он будет выполняться до тех пор, пока не произойдет ошибка
 			// или не будет отменен контекст.
-			err := c.Consumer.Consume(ctx, []string{topic}, &consumerHandler{
-				orderChan:  c.orderChan,
-				commitChan: c.commitChan,
-				Log:        c.log,
-			})
+			err := c.Consumer.Consume(sessionCtx, topics, handler)
+			cancel()
 			if err != nil {
 				// sarama.ErrClosedConsumerGroup - это ожидаемая ошибка при штатном завершении.
 				if err == sarama.ErrClosedConsumerGroup {
@@ -93,31 +264,148 @@ func (c *Consumer) ProcessMessages(ctx context.Context, topic string, wg *sync.W
 	}
 }
 
+// watchPressure периодически опрашивает PressureSource и приостанавливает
+// (PauseAll) или возобновляет (ResumeAll) чтение всех партиций.
+func (c *Consumer) watchPressure(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	const fn = "storage.kafka.watchPressure"
+	log := c.log.With("fn", fn)
+
+	ticker := time.NewTicker(pressureCheckInterval)
+	defer ticker.Stop()
+
+	paused := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			switch {
+			case !paused && c.pressure.Overloaded():
+				log.Warn("processor is overloaded, pausing consumption")
+				c.Consumer.PauseAll()
+				paused = true
+			case paused && c.pressure.Relieved():
+				log.Info("pressure dropped, resuming consumption")
+				c.Consumer.ResumeAll()
+				paused = false
+			}
+		}
+	}
+}
+
 // consumerHandler реализует интерфейс `sarama.ConsumerGroupHandler`.
 // Sarama вызывает методы этого типа во время сессии консьюмера.
 type consumerHandler struct {
-	orderChan  chan<- *sarama.ConsumerMessage
-	commitChan <-chan *sarama.ConsumerMessage
-	Log        *slog.Logger
+	orderChan       chan<- *sarama.ConsumerMessage
+	commitChan      <-chan *sarama.ConsumerMessage
+	commitBatchSize int
+	commitInterval  time.Duration
+	inflight        *inflight // Сообщения текущей сессии, переданные обработчику.
+	clientID        string
+	owners          *partitionOwners // Реестр владельцев партиций пула. Может быть nil.
+	commitOwner     chan<- *sarama.ConsumerMessage
+	Log             *slog.Logger
+
+	mu      sync.Mutex
+	session sarama.ConsumerGroupSession // Сессия, для сброса офсетов (см. Consumer.Rewind).
+}
+
+// currentSession возвращает сессию обработчика или nil до вызова Setup.
+func (h *consumerHandler) currentSession() sarama.ConsumerGroupSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.session
 }
 
 // Setup вызывается один раз в начале сессии консьюмера, перед ConsumeClaim.
-func (h *consumerHandler) Setup(sarama.ConsumerGroupSession) error {
+// Учет сообщений в обработке у каждой сессии свой (обработчик создается
+// заново для каждого Consume): подтверждения для сообщений из предыдущих
+// сессий будут отброшены.
+func (h *consumerHandler) Setup(session sarama.ConsumerGroupSession) error {
+	h.mu.Lock()
+	h.session = session
+	h.mu.Unlock()
+
+	if h.owners != nil {
+		h.owners.assign(session.Claims(), h.commitOwner)
+	}
+
+	assigned := 0
+	for _, partitions := range session.Claims() {
+		assigned += len(partitions)
+	}
+	metrics.ConsumerAssignedPartitions.WithLabelValues(h.clientID).Set(float64(assigned))
+
+	h.Log.Info("consumer session started",
+		slog.Int("generation_id", int(session.GenerationID())),
+		slog.Any("claims", session.Claims()),
+	)
+
 	return nil
 }
 
 // Cleanup вызывается один раз в конце сессии, после завершения всех циклов ConsumeClaim.
-func (h *consumerHandler) Cleanup(sarama.ConsumerGroupSession) error {
+//
+// Пока партиции еще принадлежат сессии, Cleanup дожидается подтверждений
+// для сообщений, уже переданных обработчику, и коммитит их офсеты. Если за
+// drainTimeout обработка не завершилась, оставшиеся сообщения "отсекаются":
+// их подтверждения придут уже в другую сессию и не будут помечены, а сами
+// сообщения будут повторно доставлены новому владельцу партиции.
+func (h *consumerHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	timer := time.NewTimer(drainTimeout)
+	defer timer.Stop()
+
+	defer metrics.ConsumerAssignedPartitions.WithLabelValues(h.clientID).Set(0)
+	// Партиции освобождаются только после ожидания: до этого подтверждения
+	// должны приходить в эту сессию.
+	if h.owners != nil {
+		defer h.owners.release(session.Claims(), h.commitOwner)
+	}
+
+	for h.inflight.len() > 0 {
+		select {
+		case msg := <-h.commitChan:
+			h.mark(session, msg)
+		case <-timer.C:
+			h.Log.Warn("drain timeout exceeded, fencing in-flight messages",
+				slog.Int("count", h.inflight.len()),
+			)
+			session.Commit()
+			return nil
+		}
+	}
+
+	session.Commit()
 	return nil
 }
 
+// mark помечает сообщение как обработанное, если оно принадлежит текущей сессии.
+// Возвращает false для сообщений из прошлых сессий и отсеченных партиций.
+func (h *consumerHandler) mark(session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) bool {
+	if !h.inflight.done(msg) {
+		h.Log.Warn("skipping commit of message from previous session or fenced partition",
+			slog.String("topic", msg.Topic),
+			slog.Int("partition", int(msg.Partition)),
+			slog.Int64("offset", msg.Offset),
+		)
+		return false
+	}
+
+	// Помечаем сообщение как обработанное. Фактический коммит произойдет позже.
+	session.MarkMessage(msg, "")
+	return true
+}
+
 // ConsumeClaim является основным циклом обработки сообщений.
 // Он запускается для каждой партиции топика, назначенной этому консьюмеру.
 func (h *consumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	processed := 0 // Счетчик обработанных сообщений для батч-коммита.
 
 	// Тикер для периодического коммита, если сообщений мало.
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(h.commitInterval)
 	defer ticker.Stop()
 
 	for {
@@ -133,23 +421,59 @@ func (h *consumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 				"received message",
 				slog.Int("partition", int(msg.Partition)),
 				slog.Int("offset", int(msg.Offset)),
+				slog.String("correlation_id", correlationID(msg)),
 			)
-			// Отправляем сообщение на обработку в `Processor`.
-			h.orderChan <- msg
+			metrics.ConsumerMessages.WithLabelValues(h.clientID, msg.Topic).Inc()
+
+			// Отправляем сообщение на обработку в `Processor`. Если сессия
+			// завершилась раньше, чем обработчик принял сообщение, снимаем его
+			// с учета: оно будет доставлено повторно. Сообщения отсеченной
+			// партиции (см. Consumer.Rewind) не передаются вовсе.
+			//
+			// Пока orderChan заполнен, подтверждения продолжают вычитываться:
+			// иначе обработчик, ожидающий отправки подтверждения, не освободил
+			// бы orderChan, и конвейер встал бы навсегда.
+			if !h.inflight.add(msg) {
+				continue
+			}
+		send:
+			for {
+				select {
+				case h.orderChan <- msg:
+					break send
+				case ack := <-h.commitChan:
+					if h.mark(session, ack) {
+						processed++
+					}
+				case <-session.Context().Done():
+					h.inflight.done(msg)
+					session.Commit()
+					return nil
+				}
+			}
 
 		// Читаем из канала подтверждений.
 		case msg := <-h.commitChan:
-			// Помечаем сообщение как обработанное. Фактический коммит произойдет позже.
-			session.MarkMessage(msg, "")
+			if !h.mark(session, msg) {
+				continue
+			}
 			processed++
 
 			// Если накопили достаточное количество, делаем коммит.
-			if processed >= batchsize {
+			if processed >= h.commitBatchSize {
 				h.Log.Info("committing messages")
 				session.Commit()
 				processed = 0
 			}
 
+		// Периодически коммитим то, что успели обработать.
+		case <-ticker.C:
+			if processed > 0 {
+				h.Log.Info("committing messages by interval", slog.Int("count", processed))
+				session.Commit()
+				processed = 0
+			}
+
 		// Если контекст сессии завершен (например, при ребалансировке или shutdown).
 		case <-session.Context().Done():
 			// Коммитим все, что было обработано, и выходим.
@@ -158,3 +482,22 @@ func (h *consumerHandler) ConsumeClaim(session sarama.ConsumerGroupSession, clai
 		}
 	}
 }
+
+// headerValue возвращает значение заголовка сообщения или пустую строку.
+func headerValue(msg *sarama.ConsumerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// correlationID возвращает идентификатор корреляции сообщения: из заголовка
+// или, если его нет, тот же, что получит обработчик (см. MetadataFromMessage).
+func correlationID(msg *sarama.ConsumerMessage) string {
+	if id := headerValue(msg, HeaderCorrelationID); id != "" {
+		return id
+	}
+	return correlation.FromMessage(msg.Topic, msg.Partition, msg.Offset)
+}
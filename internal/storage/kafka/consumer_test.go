@@ -0,0 +1,79 @@
+package kafka
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/config"
+)
+
+// TestNewConsumerCommitInterval проверяет, что консьюмер не создается с
+// неположительным commit.interval: иначе time.NewTicker паникует в
+// ConsumeClaim уже после входа в группу.
+func TestNewConsumerCommitInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		cfg := config.Kafka{
+			// Недоступный адрес: до подключения дойти не должно.
+			BootstrapServers: []string{"127.0.0.1:1"},
+			Consumer: config.Consumer{
+				GroupId:        "test",
+				CommitInterval: interval,
+			},
+		}
+		log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		c, err := newConsumer(cfg, "test", nil, nil, nil, log)
+		if err == nil {
+			c.Consumer.Close()
+			t.Fatalf("newConsumer() with commit.interval %s: error = nil, want error", interval)
+		}
+	}
+}
+
+// TestApplyConsumerOffsetsCommitInterval проверяет перенос commit.interval
+// в период автокоммита sarama.
+func TestApplyConsumerOffsetsCommitInterval(t *testing.T) {
+	tests := []struct {
+		name       string
+		autoCommit bool
+		interval   time.Duration
+		wantErr    bool
+	}{
+		{name: "manual commit", interval: 5 * time.Second},
+		{name: "auto commit", autoCommit: true, interval: 2 * time.Second},
+		{name: "zero", interval: 0, wantErr: true},
+		{name: "negative", autoCommit: true, interval: -time.Second, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saramaCfg := sarama.NewConfig()
+			defaultInterval := saramaCfg.Consumer.Offsets.AutoCommit.Interval
+
+			err := applyConsumerOffsets(saramaCfg, config.Consumer{
+				EnableAutoCommit: tt.autoCommit,
+				CommitInterval:   tt.interval,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyConsumerOffsets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			want := defaultInterval
+			if tt.autoCommit {
+				want = tt.interval
+			}
+			if got := saramaCfg.Consumer.Offsets.AutoCommit.Interval; got != want {
+				t.Errorf("AutoCommit.Interval = %s, want %s", got, want)
+			}
+			if got := saramaCfg.Consumer.Offsets.AutoCommit.Enable; got != tt.autoCommit {
+				t.Errorf("AutoCommit.Enable = %v, want %v", got, tt.autoCommit)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/metrics"
+)
+
+// Заголовки, которые DLQ добавляет к исходному сообщению.
+const (
+	HeaderDLQError             = "dlq_error"              // Причина, по которой сообщение не удалось обработать.
+	HeaderDLQOriginalTopic     = "dlq_original_topic"     // Исходный топик.
+	HeaderDLQOriginalPartition = "dlq_original_partition" // Исходная партиция.
+	HeaderDLQOriginalOffset    = "dlq_original_offset"    // Исходный офсет.
+)
+
+// DLQ (dead letter queue) отправляет сообщения, которые не удалось обработать,
+// в отдельный топик. Исходные ключ, тело и заголовки (включая correlation_id)
+// сохраняются, поэтому сообщение можно проанализировать и переотправить.
+type DLQ struct {
+	producer sarama.SyncProducer
+	topic    string
+	log      *slog.Logger
+}
+
+// NewDLQ создает синхронного продюсера для топика `cfg.DLQTopic`.
+func NewDLQ(cfg config.Kafka, log *slog.Logger) (*DLQ, error) {
+	return newDLQ(cfg, cfg.DLQTopic, log)
+}
+
+// NewQuarantine создает синхронного продюсера для топика карантина
+// `cfg.QuarantineTopic`. Сообщения в нем имеют тот же вид, что и в DLQ.
+func NewQuarantine(cfg config.Kafka, log *slog.Logger) (*DLQ, error) {
+	return newDLQ(cfg, cfg.QuarantineTopic, log)
+}
+
+// newDLQ создает синхронного продюсера для топика `topic`.
+func newDLQ(cfg config.Kafka, topic string, log *slog.Logger) (*DLQ, error) {
+	config, err := newSaramaConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Producer.Return.Successes = true // Обязательно для SyncProducer.
+	config.Producer.RequiredAcks = sarama.WaitForAll
+
+	p, err := sarama.NewSyncProducer(cfg.BootstrapServers, config)
+	if err != nil {
+		return nil, fmt.Errorf("can't create dlq producer: %v", err)
+	}
+
+	return &DLQ{
+		producer: p,
+		topic:    topic,
+		log:      log,
+	}, nil
+}
+
+// Send отправляет сообщение в DLQ с указанием причины ошибки.
+// Если в исходном сообщении нет correlation_id, он берется из метаданных контекста.
+// Заголовок traceparent заменяется дочерним участком трассы обработки, если
+// она есть в контексте.
+func (d *DLQ) Send(ctx context.Context, msg *sarama.ConsumerMessage, reason error) error {
+	const fn = "storage.kafka.DLQ.Send"
+
+	md, hasMetadata := MetadataFromContext(ctx)
+
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+6)
+	hasCorrelationID := false
+	for _, h := range msg.Headers {
+		if h == nil {
+			continue
+		}
+		if string(h.Key) == HeaderCorrelationID {
+			hasCorrelationID = true
+		}
+		if string(h.Key) == HeaderTraceparent && hasMetadata {
+			continue
+		}
+		headers = append(headers, *h)
+	}
+	if hasMetadata {
+		if !hasCorrelationID {
+			headers = append(headers, sarama.RecordHeader{
+				Key: []byte(HeaderCorrelationID), Value: []byte(md.CorrelationID),
+			})
+		}
+		headers = append(headers, md.Trace.Child().Header())
+	}
+
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(HeaderDLQError), Value: []byte(reason.Error())},
+		sarama.RecordHeader{Key: []byte(HeaderDLQOriginalTopic), Value: []byte(msg.Topic)},
+		sarama.RecordHeader{Key: []byte(HeaderDLQOriginalPartition), Value: []byte(strconv.Itoa(int(msg.Partition)))},
+		sarama.RecordHeader{Key: []byte(HeaderDLQOriginalOffset), Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+	)
+
+	dlqMsg := &sarama.ProducerMessage{
+		Topic:   d.topic,
+		Key:     sarama.ByteEncoder(msg.Key),
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	}
+
+	partition, offset, err := d.producer.SendMessage(dlqMsg)
+	if err != nil {
+		metrics.DLQMessages.WithLabelValues(d.topic, "error").Inc()
+		return fmt.Errorf("%s: can't send message: %v", fn, err)
+	}
+	metrics.DLQMessages.WithLabelValues(d.topic, "ok").Inc()
+
+	d.log.Info("message sent to dlq",
+		slog.String("fn", fn),
+		slog.String("topic", d.topic),
+		slog.Int("partition", int(partition)),
+		slog.Int64("offset", offset),
+	)
+
+	return nil
+}
+
+// Close закрывает продюсера DLQ.
+func (d *DLQ) Close() error {
+	return d.producer.Close()
+}
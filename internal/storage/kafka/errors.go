@@ -0,0 +1,93 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/metrics"
+	"github.com/YusovID/order-service/lib/logger/sl"
+)
+
+// watchErrors читает канал ошибок группы консьюмеров до отмены контекста.
+//
+// Канал обязательно нужно вычитывать: при `Consumer.Return.Errors = true`
+// sarama складывает в него ошибки, и без читателя он переполняется.
+// Каждая ошибка логируется и учитывается в метрике metrics.ConsumerErrors.
+// Если включен перезапуск (reconnect.error_threshold > 0) и за окно
+// reconnect.window накопилось слишком много ошибок, текущая сессия
+// завершается, и консьюмер заново подключается к группе.
+func (c *Consumer) watchErrors(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	const fn = "storage.kafka.watchErrors"
+	log := c.log.With("fn", fn)
+
+	var recent []time.Time // Время последних ошибок в пределах окна.
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-c.Consumer.Errors():
+			if !ok {
+				return
+			}
+
+			topic := ""
+			attrs := []any{sl.Err(err)}
+			var consumerErr *sarama.ConsumerError
+			if errors.As(err, &consumerErr) {
+				topic = consumerErr.Topic
+				attrs = append(attrs,
+					slog.String("topic", consumerErr.Topic),
+					slog.Int("partition", int(consumerErr.Partition)),
+				)
+			}
+			log.Error("consumer group error", attrs...)
+			metrics.ConsumerErrors.WithLabelValues(c.clientID, topic).Inc()
+
+			if c.reconnectThreshold <= 0 {
+				continue
+			}
+
+			now := time.Now()
+			recent = append(recent, now)
+			for len(recent) > 0 && now.Sub(recent[0]) > c.reconnectWindow {
+				recent = recent[1:]
+			}
+
+			if len(recent) >= c.reconnectThreshold {
+				log.Warn("too many consumer errors, rejoining consumer group",
+					slog.Int("errors", len(recent)),
+					slog.String("window", c.reconnectWindow.String()),
+				)
+				c.restartSession()
+				recent = recent[:0]
+			}
+		}
+	}
+}
+
+// setSession сохраняет функцию отмены и обработчик текущей сессии.
+func (c *Consumer) setSession(cancel context.CancelFunc, handler *consumerHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cancelSession = cancel
+	c.handler = handler
+}
+
+// restartSession завершает текущую сессию консьюмера. Цикл ProcessMessages
+// после этого снова вызывает Consume, и консьюмер заново входит в группу.
+func (c *Consumer) restartSession() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancelSession != nil {
+		c.cancelSession()
+	}
+}
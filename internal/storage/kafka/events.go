@@ -0,0 +1,177 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/YusovID/order-service/lib/logger/sl"
+)
+
+// Заголовки сообщений в топике событий.
+const (
+	HeaderEventType = "event_type" // Тип события.
+	HeaderOutboxID  = "outbox_id"  // Номер события в outbox; по нему консьюмеры могут отбрасывать повторы.
+)
+
+// EventPublisher публикует события о заказах через транзакционного продюсера.
+//
+// События сохранения заказов записываются хранилищем в outbox в одной
+// транзакции с заказом и публикуются пачками (PublishOutbox). Если транзакция
+// Kafka прервана, события не увидят консьюмеры с read_committed, а сами
+// события останутся в outbox и будут опубликованы повторно.
+type EventPublisher struct {
+	producer sarama.SyncProducer
+	topic    string
+	log      *slog.Logger
+
+	// Транзакционный продюсер ведет одну транзакцию за раз,
+	// а сообщения обрабатываются несколькими воркерами.
+	mu sync.Mutex
+}
+
+// NewEventPublisher создает транзакционного продюсера для топика `cfg.Events.Topic`.
+//
+// `cfg.Events.TransactionalID` должен быть уникальным для каждого экземпляра
+// сервиса, иначе экземпляры будут прерывать транзакции друг друга (fencing).
+func NewEventPublisher(cfg config.Kafka, log *slog.Logger) (*EventPublisher, error) {
+	config, err := newSaramaConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Producer.Return.Successes = true // Обязательно для SyncProducer.
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Idempotent = true // Обязательно для транзакций.
+	config.Net.MaxOpenRequests = 1
+	config.Producer.Transaction.ID = cfg.Events.TransactionalID
+
+	p, err := sarama.NewSyncProducer(cfg.BootstrapServers, config)
+	if err != nil {
+		return nil, fmt.Errorf("can't create event producer: %v", err)
+	}
+
+	return &EventPublisher{
+		producer: p,
+		topic:    cfg.Events.Topic,
+		log:      log,
+	}, nil
+}
+
+// Send публикует событие, не связанное с обработкой сообщения из Kafka
+// (например, смену статуса через API), в отдельной транзакции.
+func (p *EventPublisher) Send(ctx context.Context, event models.OrderEvent) error {
+	const fn = "storage.kafka.EventPublisher.Send"
+
+	eventMsg, err := p.message(ctx, event)
+	if err != nil {
+		return fmt.Errorf("%s: %v", fn, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.producer.BeginTxn(); err != nil {
+		return fmt.Errorf("%s: can't begin transaction: %v", fn, err)
+	}
+
+	if _, _, err := p.producer.SendMessage(eventMsg); err != nil {
+		return p.abort(fmt.Errorf("%s: can't send event: %v", fn, err))
+	}
+
+	if err := p.producer.CommitTxn(); err != nil {
+		return p.abort(fmt.Errorf("%s: can't commit transaction: %v", fn, err))
+	}
+
+	return nil
+}
+
+// PublishOutbox публикует события из outbox в одной транзакции: консьюмеры
+// с read_committed увидят либо всю пачку, либо ничего.
+func (p *EventPublisher) PublishOutbox(ctx context.Context, events []storage.OutboxEvent) error {
+	const fn = "storage.kafka.EventPublisher.PublishOutbox"
+
+	msgs := make([]*sarama.ProducerMessage, 0, len(events))
+	for _, e := range events {
+		msgs = append(msgs, &sarama.ProducerMessage{
+			Topic: p.topic,
+			Key:   sarama.StringEncoder(e.Key),
+			Value: sarama.ByteEncoder(e.Payload),
+			Headers: append(NewHeaders("", e.CreatedAt),
+				sarama.RecordHeader{Key: []byte(HeaderEventType), Value: []byte(e.Type)},
+				sarama.RecordHeader{Key: []byte(HeaderOutboxID), Value: []byte(strconv.FormatInt(e.ID, 10))},
+			),
+		})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.producer.BeginTxn(); err != nil {
+		return fmt.Errorf("%s: can't begin transaction: %v", fn, err)
+	}
+
+	if err := p.producer.SendMessages(msgs); err != nil {
+		return p.abort(fmt.Errorf("%s: can't send events: %v", fn, err))
+	}
+
+	if err := p.producer.CommitTxn(); err != nil {
+		return p.abort(fmt.Errorf("%s: can't commit transaction: %v", fn, err))
+	}
+
+	return nil
+}
+
+// message строит сообщение Kafka для события. Ключом служит order_uid,
+// поэтому события одного заказа попадают в одну партицию по порядку.
+func (p *EventPublisher) message(ctx context.Context, event models.OrderEvent) (*sarama.ProducerMessage, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal event: %v", err)
+	}
+
+	correlationID := ""
+	var trace TraceContext
+	if md, ok := MetadataFromContext(ctx); ok {
+		correlationID = md.CorrelationID
+		trace = md.Trace
+	}
+
+	return &sarama.ProducerMessage{
+		Topic: p.topic,
+		Key:   sarama.StringEncoder(event.OrderUID),
+		Value: sarama.ByteEncoder(body),
+		Headers: append(NewHeaders(correlationID, event.OccurredAt),
+			sarama.RecordHeader{Key: []byte(HeaderEventType), Value: []byte(event.Type)},
+			trace.Child().Header(), // Событие продолжает трассу обработки заказа.
+		),
+	}, nil
+}
+
+// abort прерывает текущую транзакцию и возвращает исходную ошибку.
+// После фатальной ошибки продюсер нельзя использовать, и прервать
+// транзакцию уже не получится.
+func (p *EventPublisher) abort(err error) error {
+	if p.producer.TxnStatus()&sarama.ProducerTxnFlagFatalError != 0 {
+		p.log.Error("event producer is in fatal state", slog.String("status", p.producer.TxnStatus().String()))
+		return err
+	}
+
+	if abortErr := p.producer.AbortTxn(); abortErr != nil {
+		p.log.Error("can't abort transaction", sl.Err(abortErr))
+	}
+
+	return err
+}
+
+// Close закрывает продюсера событий.
+func (p *EventPublisher) Close() error {
+	return p.producer.Close()
+}
@@ -0,0 +1,174 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/correlation"
+)
+
+// Ключи заголовков сообщений Kafka, которые проставляет продюсер
+// и читает консьюмер.
+const (
+	HeaderMessageVersion = "message_version" // Версия формата сообщения.
+	HeaderProducedAt     = "produced_at"     // Время отправки сообщения (RFC3339Nano).
+	HeaderCorrelationID  = "correlation_id"  // Сквозной идентификатор для логов и DLQ.
+	HeaderPriority       = "priority"        // Приоритет обработки (см. PriorityHigh).
+
+	// Заголовки генератора заказов. По ним консьюмер и DLQ могут принимать
+	// решения о версии сообщения, не разбирая тело.
+	HeaderSchemaVersion = "schema_version" // Версия схемы тела сообщения (см. SchemaVersion).
+	HeaderProducerID    = "producer_id"    // Идентификатор экземпляра продюсера.
+	HeaderGeneratedAt   = "generated_at"   // Время генерации заказа (RFC3339Nano).
+
+	// HeaderInjectedInvalid помечает заведомо некорректные сообщения генератора.
+	// Значение - вид ошибки (см. orderGen.Invalid*).
+	HeaderInjectedInvalid = "injected_invalid"
+)
+
+// PriorityHigh - значение заголовка HeaderPriority для срочных заказов,
+// которые обрабатываются вне очереди.
+const PriorityHigh = "high"
+
+// MessageVersion - текущая версия формата сообщений о заказах.
+const MessageVersion = "1"
+
+// SchemaVersion - текущая версия схемы тела сообщения о заказе
+// (структура models.OrderData и api/proto/order/v1/order.proto).
+// Увеличивается при несовместимых изменениях схемы.
+const SchemaVersion = "1"
+
+// Metadata содержит метаданные сообщения: значения заголовков и
+// координаты сообщения в Kafka. Передается через контекст обработки,
+// чтобы попадать в логи и в DLQ.
+type Metadata struct {
+	MessageVersion string
+	SchemaVersion  string
+	ProducerID     string
+	ProducedAt     time.Time
+	GeneratedAt    time.Time
+	CorrelationID  string
+	Priority       string
+	Topic          string
+	Partition      int32
+	Offset         int64
+
+	// Trace - участок трассировки обработки сообщения, дочерний к участку
+	// продюсера из заголовка traceparent (см. TraceContext).
+	Trace TraceContext
+}
+
+// metadataKey - ключ для хранения Metadata в context.Context.
+type metadataKey struct{}
+
+// NewHeaders создает заголовки для нового сообщения.
+// Если `correlationID` пустой, генерируется новый идентификатор.
+func NewHeaders(correlationID string, producedAt time.Time) []sarama.RecordHeader {
+	if correlationID == "" {
+		correlationID = correlation.New()
+	}
+
+	return []sarama.RecordHeader{
+		{Key: []byte(HeaderMessageVersion), Value: []byte(MessageVersion)},
+		{Key: []byte(HeaderProducedAt), Value: []byte(producedAt.UTC().Format(time.RFC3339Nano))},
+		{Key: []byte(HeaderCorrelationID), Value: []byte(correlationID)},
+	}
+}
+
+// GeneratorHeaders создает заголовки, которые генератор добавляет к каждому
+// сообщению вдобавок к NewHeaders: версию схемы, идентификатор продюсера
+// и время генерации заказа.
+func GeneratorHeaders(producerID string, generatedAt time.Time) []sarama.RecordHeader {
+	return []sarama.RecordHeader{
+		{Key: []byte(HeaderSchemaVersion), Value: []byte(SchemaVersion)},
+		{Key: []byte(HeaderProducerID), Value: []byte(producerID)},
+		{Key: []byte(HeaderGeneratedAt), Value: []byte(generatedAt.UTC().Format(time.RFC3339Nano))},
+	}
+}
+
+// NewProducerID возвращает идентификатор экземпляра продюсера
+// в виде `<hostname>-<pid>`.
+func NewProducerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// MetadataFromMessage читает метаданные из заголовков сообщения.
+// Сообщения от старых продюсеров могут не содержать заголовков: в этом
+// случае идентификатор корреляции выводится из координат сообщения (один и
+// тот же при каждой попытке обработки), а трасса начинается новая.
+func MetadataFromMessage(msg *sarama.ConsumerMessage) Metadata {
+	md := Metadata{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+	}
+
+	for _, h := range msg.Headers {
+		if h == nil {
+			continue
+		}
+		switch string(h.Key) {
+		case HeaderMessageVersion:
+			md.MessageVersion = string(h.Value)
+		case HeaderProducedAt:
+			md.ProducedAt, _ = time.Parse(time.RFC3339Nano, string(h.Value))
+		case HeaderCorrelationID:
+			md.CorrelationID = string(h.Value)
+		case HeaderPriority:
+			md.Priority = string(h.Value)
+		case HeaderSchemaVersion:
+			md.SchemaVersion = string(h.Value)
+		case HeaderProducerID:
+			md.ProducerID = string(h.Value)
+		case HeaderGeneratedAt:
+			md.GeneratedAt, _ = time.Parse(time.RFC3339Nano, string(h.Value))
+		case HeaderTraceparent:
+			md.Trace, _ = ParseTraceparent(string(h.Value))
+		}
+	}
+
+	// Обработка - дочерний участок трассы продюсера. Без заголовка
+	// (или с некорректным) начинается новая трасса.
+	md.Trace = md.Trace.Child()
+
+	if md.CorrelationID == "" {
+		md.CorrelationID = correlation.FromMessage(msg.Topic, msg.Partition, msg.Offset)
+	}
+
+	return md
+}
+
+// LogAttrs возвращает атрибуты для логгера.
+// Использование: `log.With(md.LogAttrs()...)`.
+func (md Metadata) LogAttrs() []any {
+	return []any{
+		slog.String("correlation_id", md.CorrelationID),
+		slog.String("trace_id", md.Trace.TraceID),
+		slog.String("span_id", md.Trace.SpanID),
+		slog.String("message_version", md.MessageVersion),
+		slog.String("schema_version", md.SchemaVersion),
+		slog.String("producer_id", md.ProducerID),
+		slog.String("topic", md.Topic),
+		slog.Int("partition", int(md.Partition)),
+		slog.Int64("offset", md.Offset),
+	}
+}
+
+// WithMetadata возвращает копию контекста с метаданными сообщения.
+func WithMetadata(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// MetadataFromContext извлекает метаданные сообщения из контекста.
+func MetadataFromContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(metadataKey{}).(Metadata)
+	return md, ok
+}
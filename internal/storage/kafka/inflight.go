@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// messageID однозначно определяет сообщение внутри топика.
+type messageID struct {
+	topic     string
+	partition int32
+	offset    int64
+}
+
+// inflight отслеживает сообщения, которые консьюмер передал обработчику
+// в рамках текущей сессии, но которые еще не вернулись в commitChan.
+//
+// Набор создается заново для каждой сессии группы консьюмеров. Поэтому
+// подтверждение, пришедшее после ребалансировки для сообщения из прошлой
+// сессии, не найдется в наборе и не будет помечено (fencing): партиция
+// могла уже перейти к другому экземпляру сервиса.
+//
+// Так же отсекаются партиции, которые нужно перечитать (см. fence).
+type inflight struct {
+	mu       sync.Mutex
+	messages map[messageID]struct{}
+	fenced   map[partitionKey]struct{} // Партиции, подтверждения которых больше не помечаются.
+}
+
+// newInflight создает пустой набор сообщений в обработке.
+func newInflight() *inflight {
+	return &inflight{
+		messages: make(map[messageID]struct{}),
+		fenced:   make(map[partitionKey]struct{}),
+	}
+}
+
+// add регистрирует сообщение, переданное обработчику. Возвращает false,
+// если партиция сообщения отсечена и его не нужно передавать обработчику.
+func (f *inflight) add(msg *sarama.ConsumerMessage) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.fenced[partitionKey{msg.Topic, msg.Partition}]; ok {
+		return false
+	}
+	f.messages[idOf(msg)] = struct{}{}
+
+	return true
+}
+
+// done снимает сообщение с учета. Возвращает false, если сообщение
+// не принадлежит текущей сессии или его партиция отсечена, и его нельзя
+// помечать как обработанное.
+func (f *inflight) done(msg *sarama.ConsumerMessage) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := idOf(msg)
+	if _, ok := f.messages[id]; !ok {
+		return false
+	}
+	delete(f.messages, id)
+
+	_, fenced := f.fenced[partitionKey{msg.Topic, msg.Partition}]
+	return !fenced
+}
+
+// fence отсекает партицию: сообщения в обработке снимаются с учета, а их
+// подтверждения и новые сообщения партиции больше не принимаются, поэтому
+// офсет партиции не продвинется дальше уже помеченных сообщений. Возвращает
+// наименьший офсет среди неподтвержденных сообщений партиции и false, если
+// у сессии не было сообщений этой партиции в обработке.
+func (f *inflight) fence(topic string, partition int32) (int64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := partitionKey{topic, partition}
+	f.fenced[key] = struct{}{}
+
+	low, found := int64(0), false
+	for id := range f.messages {
+		if id.topic == topic && id.partition == partition {
+			delete(f.messages, id)
+			if !found || id.offset < low {
+				low = id.offset
+			}
+			found = true
+		}
+	}
+
+	return low, found
+}
+
+// len возвращает количество сообщений в обработке.
+func (f *inflight) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.messages)
+}
+
+// idOf возвращает идентификатор сообщения.
+func idOf(msg *sarama.ConsumerMessage) messageID {
+	return messageID{topic: msg.Topic, partition: msg.Partition, offset: msg.Offset}
+}
@@ -0,0 +1,129 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/metrics"
+	"github.com/YusovID/order-service/lib/logger/sl"
+)
+
+// LagExporter периодически вычисляет отставание (lag) группы консьюмеров
+// по каждой партиции и экспортирует его в метрику metrics.ConsumerLag.
+//
+// Лаг считается как разница между high watermark партиции и закоммиченным
+// офсетом группы. Если группа еще ничего не коммитила, за точку отсчета
+// берется самый старый доступный офсет партиции.
+type LagExporter struct {
+	client   sarama.Client
+	admin    sarama.ClusterAdmin
+	group    string
+	topics   []string
+	interval time.Duration
+	log      *slog.Logger
+}
+
+// NewLagExporter создает клиента и админ-клиента Kafka для опроса офсетов.
+func NewLagExporter(cfg config.Kafka, log *slog.Logger) (*LagExporter, error) {
+	saramaConfig, err := newSaramaConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sarama.NewClient(cfg.BootstrapServers, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("can't create kafka client: %v", err)
+	}
+
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("can't create cluster admin: %v", err)
+	}
+
+	return &LagExporter{
+		client:   client,
+		admin:    admin,
+		group:    cfg.Consumer.GroupId,
+		topics:   cfg.Topic,
+		interval: cfg.Consumer.LagInterval,
+		log:      log,
+	}, nil
+}
+
+// Run опрашивает офсеты с интервалом `interval` до отмены контекста.
+func (e *LagExporter) Run(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	const fn = "storage.kafka.LagExporter.Run"
+	log := e.log.With("fn", fn)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("stopping lag exporter")
+			return
+		case <-ticker.C:
+			if err := e.collect(); err != nil {
+				log.Error("failed to collect consumer lag", sl.Err(err))
+			}
+		}
+	}
+}
+
+// collect вычисляет лаг по всем партициям отслеживаемых топиков.
+func (e *LagExporter) collect() error {
+	partitions := make(map[string][]int32, len(e.topics))
+	for _, topic := range e.topics {
+		ps, err := e.client.Partitions(topic)
+		if err != nil {
+			return fmt.Errorf("can't get partitions of topic %s: %v", topic, err)
+		}
+		partitions[topic] = ps
+	}
+
+	offsets, err := e.admin.ListConsumerGroupOffsets(e.group, partitions)
+	if err != nil {
+		return fmt.Errorf("can't list consumer group offsets: %v", err)
+	}
+
+	for topic, ps := range partitions {
+		for _, partition := range ps {
+			highWatermark, err := e.client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return fmt.Errorf("can't get high watermark of %s/%d: %v", topic, partition, err)
+			}
+
+			committed := int64(-1)
+			if block := offsets.GetBlock(topic, partition); block != nil {
+				committed = block.Offset
+			}
+			if committed < 0 {
+				// Группа еще не коммитила офсеты в эту партицию.
+				committed, err = e.client.GetOffset(topic, partition, sarama.OffsetOldest)
+				if err != nil {
+					return fmt.Errorf("can't get oldest offset of %s/%d: %v", topic, partition, err)
+				}
+			}
+
+			lag := max(highWatermark-committed, 0)
+			metrics.ConsumerLag.WithLabelValues(topic, strconv.Itoa(int(partition))).Set(float64(lag))
+		}
+	}
+
+	return nil
+}
+
+// Close закрывает админ-клиента вместе с нижележащим клиентом Kafka.
+func (e *LagExporter) Close() error {
+	return e.admin.Close()
+}
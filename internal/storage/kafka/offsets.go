@@ -0,0 +1,177 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/config"
+)
+
+// ErrGroupActive возвращается при попытке сбросить офсеты группы,
+// у которой есть активные участники. Офсеты можно менять только у
+// остановленной группы, иначе консьюмеры перезапишут их своими коммитами.
+var ErrGroupActive = errors.New("consumer group has active members")
+
+// OffsetTarget описывает, куда нужно переместить офсеты группы.
+// Если задано Time, используется первый офсет с временной меткой не раньше
+// Time. Иначе используется Offset; допустимы и специальные значения
+// sarama.OffsetOldest и sarama.OffsetNewest.
+type OffsetTarget struct {
+	Time   time.Time
+	Offset int64
+}
+
+// PartitionOffset - результат сброса офсета одной партиции.
+type PartitionOffset struct {
+	Partition int32
+	Current   int64 // Закоммиченный офсет до сброса (-1, если коммитов не было).
+	Target    int64 // Новый офсет.
+}
+
+// ResetOffsets перемещает закоммиченные офсеты группы `group` в топике `topic`
+// на `target`. Это позволяет повторно прочитать сообщения за нужный период,
+// например после исправления ошибки в обработке.
+//
+// Если `partitions` пустой, сбрасываются все партиции топика. При `dryRun`
+// офсеты только вычисляются, но не коммитятся. Группа должна быть остановлена.
+func ResetOffsets(
+	cfg config.Kafka,
+	group, topic string,
+	partitions []int32,
+	target OffsetTarget,
+	dryRun bool,
+) ([]PartitionOffset, error) {
+	const fn = "storage.kafka.ResetOffsets"
+
+	saramaConfig, err := newSaramaConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+
+	client, err := sarama.NewClient(cfg.BootstrapServers, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't create kafka client: %v", fn, err)
+	}
+
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("%s: can't create cluster admin: %v", fn, err)
+	}
+	// Закрытие админ-клиента закрывает и нижележащий клиент.
+	defer admin.Close()
+
+	groups, err := admin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't describe consumer group: %v", fn, err)
+	}
+	for _, g := range groups {
+		if len(g.Members) > 0 {
+			return nil, fmt.Errorf("%s: %w (state %s, %d members)", fn, ErrGroupActive, g.State, len(g.Members))
+		}
+	}
+
+	all, err := client.Partitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't get partitions: %v", fn, err)
+	}
+	if len(partitions) == 0 {
+		partitions = all
+	}
+	for _, p := range partitions {
+		if !slices.Contains(all, p) {
+			return nil, fmt.Errorf("%s: topic %s has no partition %d", fn, topic, p)
+		}
+	}
+
+	current, err := admin.ListConsumerGroupOffsets(group, map[string][]int32{topic: partitions})
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't list consumer group offsets: %v", fn, err)
+	}
+
+	plan := make([]PartitionOffset, 0, len(partitions))
+	for _, p := range partitions {
+		offset, err := resolveOffset(client, topic, p, target)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fn, err)
+		}
+
+		po := PartitionOffset{Partition: p, Current: -1, Target: offset}
+		if block := current.GetBlock(topic, p); block != nil {
+			po.Current = block.Offset
+		}
+		plan = append(plan, po)
+	}
+
+	if dryRun {
+		return plan, nil
+	}
+
+	if err := commitOffsets(client, group, topic, plan); err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+
+	return plan, nil
+}
+
+// resolveOffset вычисляет конкретный офсет партиции для цели сброса.
+// Офсет ограничивается диапазоном сообщений, доступных в партиции.
+func resolveOffset(client sarama.Client, topic string, partition int32, target OffsetTarget) (int64, error) {
+	oldest, err := client.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return 0, fmt.Errorf("can't get oldest offset of %s/%d: %v", topic, partition, err)
+	}
+	newest, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, fmt.Errorf("can't get newest offset of %s/%d: %v", topic, partition, err)
+	}
+
+	if !target.Time.IsZero() {
+		offset, err := client.GetOffset(topic, partition, target.Time.UnixMilli())
+		if err != nil {
+			return 0, fmt.Errorf("can't get offset by time of %s/%d: %v", topic, partition, err)
+		}
+		// -1 означает, что после указанного времени сообщений нет.
+		if offset < 0 {
+			return newest, nil
+		}
+		return offset, nil
+	}
+
+	switch target.Offset {
+	case sarama.OffsetOldest:
+		return oldest, nil
+	case sarama.OffsetNewest:
+		return newest, nil
+	default:
+		return min(max(target.Offset, oldest), newest), nil
+	}
+}
+
+// commitOffsets коммитит новые офсеты от имени группы.
+func commitOffsets(client sarama.Client, group, topic string, plan []PartitionOffset) error {
+	om, err := sarama.NewOffsetManagerFromClient(group, client)
+	if err != nil {
+		return fmt.Errorf("can't create offset manager: %v", err)
+	}
+	defer om.Close()
+
+	for _, po := range plan {
+		pom, err := om.ManagePartition(topic, po.Partition)
+		if err != nil {
+			return fmt.Errorf("can't manage partition %d: %v", po.Partition, err)
+		}
+		// ResetOffset, в отличие от MarkOffset, позволяет сдвинуть офсет назад.
+		pom.ResetOffset(po.Target, "")
+		if err := pom.Close(); err != nil {
+			return fmt.Errorf("can't close partition offset manager %d: %v", po.Partition, err)
+		}
+	}
+
+	om.Commit()
+
+	return nil
+}
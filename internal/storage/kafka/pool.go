@@ -0,0 +1,195 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/config"
+)
+
+// ConsumerPool запускает несколько сессий группы консьюмеров в одном процессе
+// (`consumer.sessions`). Каждая сессия - отдельный участник группы со своим
+// ClientID, поэтому партиции топиков распределяются между ними, и сообщения
+// из разных партиций читаются параллельно. Обработчик заказов общий.
+//
+// Подтверждения от обработчика приходят в общий `commitChan` и
+// перенаправляются той сессии, которой сейчас принадлежит партиция сообщения.
+//
+// Когда все сессии остановлены, пул закрывает `orderChan`: так обработчик
+// узнает, что новых сообщений не будет, и может завершить работу.
+type ConsumerPool struct {
+	consumers  []*Consumer
+	orderChan  chan<- *sarama.ConsumerMessage // Канал сообщений обработчику. Закрывается после остановки сессий.
+	commits    []chan *sarama.ConsumerMessage // Каналы подтверждений каждой сессии.
+	commitChan <-chan *sarama.ConsumerMessage // Общий канал подтверждений от обработчика.
+	owners     *partitionOwners
+	log        *slog.Logger
+}
+
+// NewConsumerPool создает `cfg.Consumer.Sessions` консьюмеров одной группы.
+// ClientID каждого из них - `<group.id>-<номер сессии>`.
+func NewConsumerPool(
+	cfg config.Kafka,
+	orderChan chan<- *sarama.ConsumerMessage,
+	commitChan <-chan *sarama.ConsumerMessage,
+	pressure PressureSource,
+	log *slog.Logger,
+) (*ConsumerPool, error) {
+	sessions := max(cfg.Consumer.Sessions, 1)
+
+	p := &ConsumerPool{
+		orderChan:  orderChan,
+		commitChan: commitChan,
+		owners:     newPartitionOwners(),
+		log:        log,
+	}
+
+	for i := range sessions {
+		clientID := fmt.Sprintf("%s-%d", cfg.Consumer.GroupId, i)
+
+		// Буфер позволяет не блокировать остальные сессии, пока одна из них
+		// занята отправкой сообщения обработчику.
+		commits := make(chan *sarama.ConsumerMessage, max(cfg.Consumer.CommitBatchSize, 1))
+
+		c, err := newConsumer(cfg, clientID, orderChan, commits, pressure, log.With(slog.String("client_id", clientID)))
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		c.owners = p.owners
+		c.commitOwner = commits
+
+		p.consumers = append(p.consumers, c)
+		p.commits = append(p.commits, commits)
+	}
+
+	return p, nil
+}
+
+// ProcessMessages запускает все сессии и маршрутизацию подтверждений.
+// Завершается, когда остановлены все сессии.
+//
+// Подтверждения маршрутизируются и после отмены `ctx`, пока сессии
+// дожидаются обработки уже переданных сообщений (см. consumerHandler.Cleanup).
+// После остановки всех сессий `orderChan` закрывается.
+func (p *ConsumerPool) ProcessMessages(ctx context.Context, topics []string, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	stopped := make(chan struct{})
+
+	wg.Add(1)
+	go p.routeCommits(stopped, wg)
+
+	var sessions sync.WaitGroup
+	for _, c := range p.consumers {
+		sessions.Add(1)
+		go c.ProcessMessages(ctx, topics, &sessions)
+	}
+	sessions.Wait()
+
+	close(stopped)
+	close(p.orderChan)
+}
+
+// routeCommits передает подтверждения из общего канала сессии, которой
+// принадлежит партиция сообщения. Подтверждения для партиций, которые
+// уже не принадлежат ни одной сессии, отбрасываются: сообщения будут
+// повторно доставлены новому владельцу партиции.
+//
+// Маршрутизация работает, пока не закрыт `stopped`.
+func (p *ConsumerPool) routeCommits(stopped <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	const fn = "storage.kafka.ConsumerPool.routeCommits"
+	log := p.log.With("fn", fn)
+
+	for {
+		select {
+		case <-stopped:
+			return
+		case msg := <-p.commitChan:
+			owner, ok := p.owners.owner(msg.Topic, msg.Partition)
+			if !ok {
+				log.Warn("partition is not owned by any session, skipping commit",
+					slog.String("topic", msg.Topic),
+					slog.Int("partition", int(msg.Partition)),
+					slog.Int64("offset", msg.Offset),
+				)
+				continue
+			}
+
+			select {
+			case owner <- msg:
+			case <-stopped:
+				return
+			}
+		}
+	}
+}
+
+// Close закрывает все сессии группы консьюмеров.
+func (p *ConsumerPool) Close() error {
+	var firstErr error
+	for _, c := range p.consumers {
+		if err := c.Consumer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// partitionKey определяет партицию топика.
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// partitionOwners хранит, какой сессии принадлежит каждая партиция.
+type partitionOwners struct {
+	mu     sync.RWMutex
+	owners map[partitionKey]chan<- *sarama.ConsumerMessage
+}
+
+// newPartitionOwners создает пустой реестр владельцев партиций.
+func newPartitionOwners() *partitionOwners {
+	return &partitionOwners{owners: make(map[partitionKey]chan<- *sarama.ConsumerMessage)}
+}
+
+// assign закрепляет партиции за сессией с каналом подтверждений `commits`.
+func (o *partitionOwners) assign(claims map[string][]int32, commits chan<- *sarama.ConsumerMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for topic, partitions := range claims {
+		for _, partition := range partitions {
+			o.owners[partitionKey{topic, partition}] = commits
+		}
+	}
+}
+
+// release снимает партиции с сессии, если они все еще принадлежат ей.
+func (o *partitionOwners) release(claims map[string][]int32, commits chan<- *sarama.ConsumerMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for topic, partitions := range claims {
+		for _, partition := range partitions {
+			key := partitionKey{topic, partition}
+			if o.owners[key] == commits {
+				delete(o.owners, key)
+			}
+		}
+	}
+}
+
+// owner возвращает канал подтверждений сессии, которой принадлежит партиция.
+func (o *partitionOwners) owner(topic string, partition int32) (chan<- *sarama.ConsumerMessage, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	commits, ok := o.owners[partitionKey{topic, partition}]
+	return commits, ok
+}
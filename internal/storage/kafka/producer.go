@@ -6,15 +6,21 @@ import (
 	"log/slog"
 	"math/rand/v2"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/codec"
 	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/metrics"
 	orderGen "github.com/YusovID/order-service/lib/generator/order"
 	"github.com/YusovID/order-service/lib/logger/sl"
 )
 
 const (
+	// summaryInterval - период вывода сводки по отправке сообщений в лог.
+	summaryInterval = 10 * time.Second
+
 	// MaxTimeToSleep определяет максимальную случайную задержку (в миллисекундах)
 	// между отправкой сообщений. Это помогает эмулировать неравномерный поток данных.
 	MaxTimeToSleep = 1000
@@ -25,6 +31,38 @@ const (
 type Producer struct {
 	Producer sarama.AsyncProducer
 	Log      *slog.Logger
+	Load     Load        // Профиль нагрузки. По умолчанию - случайные задержки.
+	ID       string      // Идентификатор продюсера для заголовка producer_id.
+	codec    codec.Codec // Кодек для сериализации заказов в тело сообщения.
+
+	// Учет результатов отправки.
+	sent       atomic.Int64 // Сообщения, переданные продюсеру.
+	succeeded  atomic.Int64 // Сообщения, подтвержденные брокером.
+	failed     atomic.Int64 // Сообщения, которые не удалось отправить.
+	txnFailed  atomic.Int64 // Транзакции, которые не удалось закоммитить.
+	resultsMu  sync.Mutex
+	resultCond *sync.Cond // Сигнализирует о каждом полученном результате.
+}
+
+// Load описывает профиль нагрузки, который создает продюсер.
+//
+// По умолчанию между сообщениями делается случайная задержка до MaxTimeToSleep мс,
+// а при заданном Rate сообщения отправляются с постоянной скоростью.
+// В режиме пачек (BurstSize > 0) продюсер отправляет BurstSize сообщений
+// без задержек, затем ждет BurstPause, и так по кругу. Это позволяет
+// проверить поведение консьюмера при резких всплесках нагрузки.
+type Load struct {
+	BurstSize  int           // Количество сообщений в пачке. 0 отключает режим пачек.
+	BurstPause time.Duration // Пауза между пачками.
+
+	// InvalidPercent - доля (в процентах) заведомо некорректных сообщений:
+	// обрезанный JSON, отсутствующий order_uid, неверные типы полей.
+	// Такие сообщения всегда в JSON и помечаются заголовком HeaderInjectedInvalid.
+	InvalidPercent float64
+
+	Count    int           // Количество сообщений, после которого продюсер останавливается. 0 - без ограничения.
+	Rate     float64       // Скорость отправки, сообщений в секунду. 0 - случайные задержки.
+	Duration time.Duration // Время работы продюсера. 0 - без ограничения.
 }
 
 // NewProducer создает и настраивает нового асинхронного продюсера Kafka.
@@ -35,8 +73,18 @@ type Producer struct {
 //   - RequiredAcks: уровень подтверждения доставки от брокеров.
 //   - TransactionalID: позволяет отправлять сообщения в рамках транзакций,
 //     обеспечивая атомарность записи в несколько партиций.
+//
+// Формат тела сообщений определяется параметром `cfg.Encoding`.
 func NewProducer(cfg config.Kafka, log *slog.Logger) (*Producer, error) {
-	config := sarama.NewConfig()
+	orderCodec, err := codec.New(cfg.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("can't create codec: %v", err)
+	}
+
+	config, err := newSaramaConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	config.Producer.Return.Successes = true // Включаем получение подтверждений об успехе.
 	config.Producer.Return.Errors = true    // Включаем получение сообщений об ошибках.
@@ -46,29 +94,73 @@ func NewProducer(cfg config.Kafka, log *slog.Logger) (*Producer, error) {
 	config.Producer.Retry.Max = cfg.Producer.Retries
 	config.Producer.Transaction.ID = cfg.Producer.TransactionalId
 
+	if err := applyProducerTuning(config, cfg.Producer); err != nil {
+		return nil, fmt.Errorf("invalid producer config: %v", err)
+	}
+
 	p, err := sarama.NewAsyncProducer(cfg.BootstrapServers, config)
 	if err != nil {
 		return nil, fmt.Errorf("can't create producer: %v", err)
 	}
 
-	return &Producer{
+	producer := &Producer{
 		Producer: p,
 		Log:      log,
-	}, nil
+		ID:       NewProducerID(),
+		codec:    orderCodec,
+	}
+	producer.resultCond = sync.NewCond(&producer.resultsMu)
+
+	return producer, nil
 }
 
-// ProduceMessage запускает бесконечный цикл генерации и отправки сообщений.
+// applyProducerTuning переносит параметры сжатия и батчинга из конфигурации
+// в sarama.Config. Нулевые значения не трогают умолчания sarama.
+//
+// Сжатие эффективнее на крупных батчах, поэтому вместе с compression.type
+// стоит задавать linger: продюсер будет ждать, пока накопится батч.
+func applyProducerTuning(config *sarama.Config, cfg config.Producer) error {
+	if cfg.CompressionType != "" {
+		if err := config.Producer.Compression.UnmarshalText([]byte(cfg.CompressionType)); err != nil {
+			return err
+		}
+	}
+	if cfg.CompressionLevel != 0 {
+		config.Producer.CompressionLevel = cfg.CompressionLevel
+	}
+	if cfg.Linger > 0 {
+		config.Producer.Flush.Frequency = cfg.Linger
+	}
+	if cfg.BatchSize > 0 {
+		config.Producer.Flush.Bytes = cfg.BatchSize
+	}
+	if cfg.BatchMessages > 0 {
+		config.Producer.Flush.Messages = cfg.BatchMessages
+	}
+
+	// Валидируем итоговую конфигурацию (например, zstd требует Kafka 2.1+).
+	return config.Validate()
+}
+
+// ProduceMessage запускает цикл генерации и отправки сообщений.
 //
 // Логика работы:
 //  1. Начинает транзакцию в Kafka.
 //  2. В цикле генерирует новые данные о заказе.
 //  3. Отправляет их как сообщение в Kafka.
-//  4. Делает случайную задержку для эмуляции реального потока.
+//  4. Делает задержку в соответствии с профилем нагрузки (см. Load).
 //  5. Периодически (раз в секунду) коммитит текущую транзакцию и начинает новую.
-//  6. При отмене контекста (graceful shutdown) коммитит последнюю транзакцию и завершает работу.
+//  6. При отмене контекста (graceful shutdown), истечении Load.Duration или
+//     после отправки Load.Count сообщений коммитит последнюю транзакцию и завершает работу.
 func (p *Producer) ProduceMessage(ctx context.Context, topic string, wg *sync.WaitGroup) {
 	defer wg.Done()
 
+	if p.Load.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Load.Duration)
+		defer cancel()
+	}
+
 	// Начинаем первую транзакцию.
 	if err := p.Producer.BeginTxn(); err != nil {
 		p.Log.Error("can't begin transaction", sl.Err(err))
@@ -79,28 +171,28 @@ func (p *Producer) ProduceMessage(ctx context.Context, topic string, wg *sync.Wa
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
+	// При заданном Load.Rate сообщения отправляются по тикам лимитера.
+	var limiter <-chan time.Time
+	if p.Load.BurstSize <= 0 && p.Load.Rate > 0 {
+		rateTicker := time.NewTicker(time.Duration(float64(time.Second) / p.Load.Rate))
+		defer rateTicker.Stop()
+		limiter = rateTicker.C
+	}
+
+	sent := 0     // Количество сообщений в текущей пачке.
+	produced := 0 // Общее количество отправленных сообщений.
+
 	for {
 		select {
 		// Обработка сигнала завершения.
 		case <-ctx.Done():
 			// Пытаемся закоммитить последнюю пачку сообщений.
-			if err := p.Producer.CommitTxn(); err != nil {
-				// Если коммит не удался, откатываем транзакцию.
-				if abortErr := p.Producer.AbortTxn(); abortErr != nil {
-					p.Log.Error("can't abort transaction", sl.Err(abortErr))
-				}
-				p.Log.Error("can't commit transaction", sl.Err(err))
-			}
+			p.commitTxn()
 			return
 
 		// Периодический коммит по тикеру.
 		case <-ticker.C:
-			if err := p.Producer.CommitTxn(); err != nil {
-				if abortErr := p.Producer.AbortTxn(); abortErr != nil {
-					p.Log.Error("can't abort transaction", sl.Err(abortErr))
-				}
-				p.Log.Error("can't commit transaction", sl.Err(err))
-			}
+			p.commitTxn()
 
 			// Начинаем новую транзакцию.
 			if err := p.Producer.BeginTxn(); err != nil {
@@ -111,29 +203,112 @@ func (p *Producer) ProduceMessage(ctx context.Context, topic string, wg *sync.Wa
 
 		// Основной цикл генерации и отправки.
 		default:
-			// Генерируем случайные данные для заказа.
-			orderUID, order := orderGen.GenerateOrder()
+			if limiter != nil {
+				select {
+				case <-ctx.Done():
+					continue // Транзакция закоммитится на следующей итерации.
+				case <-limiter:
+				}
+			}
 
-			msg := &sarama.ProducerMessage{}
-			msg.Key = sarama.StringEncoder(orderUID) // Ключ сообщения для партиционирования.
-			msg.Value = sarama.StringEncoder(order)  // Тело сообщения.
+			msg, err := p.newMessage()
+			if err != nil {
+				p.Log.Error("can't encode order", sl.Err(err))
+				continue
+			}
 
-			err := p.PushMessageToQueue(topic, msg)
+			err = p.PushMessageToQueue(topic, msg)
 			if err != nil {
 				p.Log.Error("can't push message to queue", sl.Err(err))
 			}
 
-			// Создаем случайную задержку.
-			timeToSleep := rand.IntN(MaxTimeToSleep + 1)
-			time.Sleep(time.Duration(timeToSleep) * time.Millisecond)
+			produced++
+			if p.Load.Count > 0 && produced >= p.Load.Count {
+				p.Log.Info("all messages produced", slog.Int("count", produced))
+				p.commitTxn()
+				return
+			}
+
+			if limiter != nil {
+				continue
+			}
+
+			if p.Load.BurstSize <= 0 {
+				// Создаем случайную задержку.
+				timeToSleep := rand.IntN(MaxTimeToSleep + 1)
+				time.Sleep(time.Duration(timeToSleep) * time.Millisecond)
+				continue
+			}
+
+			// В режиме пачек ждем только после отправки всей пачки.
+			sent++
+			if sent < p.Load.BurstSize {
+				continue
+			}
+			sent = 0
+
+			p.Log.Info("burst sent", slog.Int("messages", p.Load.BurstSize))
+			select {
+			case <-ctx.Done():
+			case <-time.After(p.Load.BurstPause):
+			}
+		}
+	}
+}
+
+// commitTxn коммитит текущую транзакцию, а если это не удалось - откатывает ее.
+func (p *Producer) commitTxn() {
+	defer p.recordTxnState()
+
+	if err := p.Producer.CommitTxn(); err != nil {
+		p.txnFailed.Add(1)
+		metrics.ProducerTxnFailures.Inc()
+		// Если коммит не удался, откатываем транзакцию.
+		if abortErr := p.Producer.AbortTxn(); abortErr != nil {
+			p.Log.Error("can't abort transaction", sl.Err(abortErr))
 		}
+		p.Log.Error("can't commit transaction", sl.Err(err))
 	}
 }
 
+// newMessage генерирует сообщение о заказе. С вероятностью Load.InvalidPercent
+// вместо корректного заказа генерируется некорректное сообщение.
+func (p *Producer) newMessage() (*sarama.ProducerMessage, error) {
+	now := time.Now()
+	msg := &sarama.ProducerMessage{}
+	msg.Headers = NewHeaders("", now)                                 // Версия, время отправки и correlation_id.
+	msg.Headers = append(msg.Headers, GeneratorHeaders(p.ID, now)...) // Версия схемы, producer_id и время генерации.
+	msg.Headers = append(msg.Headers, NewTrace().Header())            // Начало трассы заказа.
+
+	if orderGen.Chance(p.Load.InvalidPercent) {
+		key, body, kind := orderGen.GenerateInvalidOrder()
+		if key != "" {
+			msg.Key = sarama.StringEncoder(key)
+		}
+		msg.Value = sarama.ByteEncoder(body)
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{
+			Key: []byte(HeaderInjectedInvalid), Value: []byte(kind),
+		})
+		return msg, nil
+	}
+
+	// Генерируем случайные данные для заказа и кодируем их в выбранном формате.
+	order := orderGen.GenerateOrderData()
+	body, err := p.codec.Encode(order)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Key = sarama.StringEncoder(order.OrderUID) // Ключ сообщения для партиционирования.
+	msg.Value = sarama.ByteEncoder(body)           // Тело сообщения.
+	return msg, nil
+}
+
 // PushMessageToQueue отправляет одно сообщение в очередь продюсера.
 // Так как продюсер асинхронный, эта функция не блокируется.
 func (p *Producer) PushMessageToQueue(topic string, message *sarama.ProducerMessage) error {
 	message.Topic = topic
+	p.sent.Add(1)
 	// Отправляем сообщение во внутренний канал (input channel) продюсера.
 	p.Producer.Input() <- message
 	return nil
@@ -142,23 +317,79 @@ func (p *Producer) PushMessageToQueue(topic string, message *sarama.ProducerMess
 // HandleResult обрабатывает результаты отправки сообщений (успехи и ошибки).
 // Эта функция должна работать в отдельной горутине, чтобы асинхронно
 // читать из каналов `Successes()` и `Errors()` продюсера.
+//
+// Результаты учитываются в метриках продюсера (metrics.Producer*), а раз в
+// summaryInterval в лог выводится сводка по отправке.
 func (p *Producer) HandleResult(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 
+	summary := time.NewTicker(summaryInterval)
+	defer summary.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
+			p.logSummary()
 			p.Log.Info("stopping to handle results")
 			return
+		case <-summary.C:
+			p.recordTxnState()
+			p.logSummary()
 		// Канал для успешных сообщений.
 		case success := <-p.Producer.Successes():
+			p.succeeded.Add(1)
+			p.signalResult()
+			metrics.ProducerMessages.WithLabelValues(success.Topic).Inc()
+			metrics.ProducerBytes.WithLabelValues(success.Topic).Add(float64(messageSize(success)))
 			p.Log.Info("message sent successfully",
 				slog.Int("partition", int(success.Partition)),
 				slog.Int64("offset", success.Offset),
 			)
 		// Канал для сообщений с ошибками.
 		case err := <-p.Producer.Errors():
+			p.failed.Add(1)
+			p.signalResult()
+			metrics.ProducerErrors.WithLabelValues(err.Msg.Topic, errorType(err.Err)).Inc()
 			p.Log.Error("failed to send message", sl.Err(err))
 		}
 	}
 }
+
+// signalResult будит горутины, ожидающие результатов в WaitResults.
+func (p *Producer) signalResult() {
+	p.resultsMu.Lock()
+	p.resultCond.Broadcast()
+	p.resultsMu.Unlock()
+}
+
+// WaitResults ждет, пока для каждого переданного продюсеру сообщения придет
+// результат (успех или ошибка), но не дольше `timeout`. Результаты читает
+// HandleResult, поэтому он должен работать все время ожидания.
+// Возвращает false, если результаты не дождались.
+func (p *Producer) WaitResults(timeout time.Duration) bool {
+	timer := time.AfterFunc(timeout, p.signalResult)
+	defer timer.Stop()
+	deadline := time.Now().Add(timeout)
+
+	p.resultsMu.Lock()
+	defer p.resultsMu.Unlock()
+
+	for p.Pending() > 0 {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		p.resultCond.Wait()
+	}
+
+	return true
+}
+
+// Pending возвращает количество сообщений, для которых еще нет результата отправки.
+func (p *Producer) Pending() int64 {
+	return p.sent.Load() - p.succeeded.Load() - p.failed.Load()
+}
+
+// Failed возвращает количество неудачных отправок и неудачных коммитов транзакций.
+func (p *Producer) Failed() (messages, transactions int64) {
+	return p.failed.Load(), p.txnFailed.Load()
+}
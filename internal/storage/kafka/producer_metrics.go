@@ -0,0 +1,96 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/metrics"
+)
+
+// Состояния транзакции продюсера для метрики metrics.ProducerTxnState.
+const (
+	txnStateReady         = "ready"
+	txnStateInTransaction = "in_transaction"
+	txnStateCommitting    = "committing"
+	txnStateAborting      = "aborting"
+	txnStateError         = "error"
+)
+
+// txnStates - все состояния транзакции в порядке проверки.
+var txnStates = []string{txnStateError, txnStateAborting, txnStateCommitting, txnStateInTransaction, txnStateReady}
+
+// txnState сводит флаги статуса транзакции sarama к одному состоянию.
+func txnState(status sarama.ProducerTxnStatusFlag) string {
+	switch {
+	case status&(sarama.ProducerTxnFlagInError|sarama.ProducerTxnFlagFatalError|sarama.ProducerTxnFlagAbortableError) != 0:
+		return txnStateError
+	case status&sarama.ProducerTxnFlagAbortingTransaction != 0:
+		return txnStateAborting
+	case status&(sarama.ProducerTxnFlagEndTransaction|sarama.ProducerTxnFlagCommittingTransaction) != 0:
+		return txnStateCommitting
+	case status&sarama.ProducerTxnFlagInTransaction != 0:
+		return txnStateInTransaction
+	default:
+		return txnStateReady
+	}
+}
+
+// recordTxnState обновляет метрику состояния транзакции:
+// текущее состояние получает значение 1, остальные - 0.
+func (p *Producer) recordTxnState() {
+	current := txnState(p.Producer.TxnStatus())
+	for _, state := range txnStates {
+		value := 0.0
+		if state == current {
+			value = 1
+		}
+		metrics.ProducerTxnState.WithLabelValues(state).Set(value)
+	}
+}
+
+// errorType классифицирует ошибку отправки для метки метрики.
+func errorType(err error) string {
+	var kerr sarama.KError
+	switch {
+	case errors.Is(err, sarama.ErrMessageSizeTooLarge):
+		return "message_too_large"
+	case errors.Is(err, sarama.ErrOutOfBrokers), errors.Is(err, sarama.ErrNotConnected),
+		errors.Is(err, sarama.ErrNotLeaderForPartition), errors.Is(err, sarama.ErrLeaderNotAvailable):
+		return "broker_unavailable"
+	case errors.Is(err, sarama.ErrRequestTimedOut), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, sarama.ErrTransactionNotReady), errors.Is(err, sarama.ErrNonTransactedProducer),
+		errors.Is(err, sarama.ErrTransitionNotAllowed), errors.Is(err, sarama.ErrProducerFenced):
+		return "transaction"
+	case errors.As(err, &kerr):
+		return "kafka"
+	default:
+		return "other"
+	}
+}
+
+// messageSize возвращает размер ключа и тела сообщения в байтах.
+func messageSize(msg *sarama.ProducerMessage) int {
+	size := 0
+	if msg.Key != nil {
+		size += msg.Key.Length()
+	}
+	if msg.Value != nil {
+		size += msg.Value.Length()
+	}
+	return size
+}
+
+// logSummary выводит в лог сводку по отправке сообщений.
+func (p *Producer) logSummary() {
+	p.Log.Info("producer summary",
+		slog.Int64("sent", p.sent.Load()),
+		slog.Int64("succeeded", p.succeeded.Load()),
+		slog.Int64("failed", p.failed.Load()),
+		slog.Int64("pending", p.Pending()),
+		slog.Int64("failed_transactions", p.txnFailed.Load()),
+		slog.String("transaction_state", txnState(p.Producer.TxnStatus())),
+	)
+}
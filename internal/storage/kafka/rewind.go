@@ -0,0 +1,68 @@
+package kafka
+
+import (
+	"log/slog"
+
+	"github.com/IBM/sarama"
+)
+
+// Rewind возвращает партиции сообщений `msgs` к наименьшему неподтвержденному
+// офсету, если они принадлежат текущей сессии консьюмера.
+//
+// Партиции отсекаются (см. inflight.fence), чтобы подтверждения следующих
+// сообщений не продвинули офсет дальше необработанных. Если сообщения с
+// большими офсетами уже успели пометить (например, их обработали другие
+// шарды пачки), офсет партиции сбрасывается назад (ResetOffset). После этого
+// сессия перезапускается: при повторном входе в группу чтение партиций
+// начнется с закоммиченного офсета, а сообщения отсеченных партиций, еще
+// находящиеся в обработке, не ожидаются.
+func (c *Consumer) Rewind(msgs []*sarama.ConsumerMessage) {
+	const fn = "storage.kafka.Rewind"
+	log := c.log.With("fn", fn)
+
+	c.mu.Lock()
+	handler := c.handler
+	c.mu.Unlock()
+	if handler == nil {
+		return
+	}
+	session := handler.currentSession()
+
+	fenced := make(map[partitionKey]struct{})
+	owned := 0
+	for _, msg := range msgs {
+		key := partitionKey{msg.Topic, msg.Partition}
+		if _, ok := fenced[key]; ok {
+			continue
+		}
+		fenced[key] = struct{}{}
+
+		offset, ok := handler.inflight.fence(msg.Topic, msg.Partition)
+		if !ok {
+			continue
+		}
+		owned++
+		log.Warn("rewinding partition to the lowest unacknowledged offset",
+			slog.String("topic", key.topic),
+			slog.Int("partition", int(key.partition)),
+			slog.Int64("offset", offset),
+		)
+		if session != nil {
+			session.ResetOffset(key.topic, key.partition, offset, "")
+		}
+	}
+	// Сообщения пачки могли быть прочитаны другой сессией, а эта сессия
+	// могла уже смениться: тогда их и так прочитают заново.
+	if owned > 0 {
+		c.restartSession()
+	}
+}
+
+// Rewind возвращает партиции сообщений `msgs` к наименьшему неподтвержденному
+// офсету: каждая сессия пула перезапускается, если ей принадлежит хотя бы
+// одна из партиций (см. Consumer.Rewind).
+func (p *ConsumerPool) Rewind(msgs []*sarama.ConsumerMessage) {
+	for _, c := range p.consumers {
+		c.Rewind(msgs)
+	}
+}
@@ -0,0 +1,185 @@
+package kafka
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/config"
+)
+
+// newSaramaConfig создает sarama.Config с общими для всех клиентов Kafka
+// настройками подключения (аутентификация SASL).
+func newSaramaConfig(cfg config.Kafka) (*sarama.Config, error) {
+	config := sarama.NewConfig()
+
+	if err := applySASL(config, cfg.SASL); err != nil {
+		return nil, fmt.Errorf("invalid sasl config: %v", err)
+	}
+
+	return config, nil
+}
+
+// applySASL включает аутентификацию SASL, если задан пользователь.
+// Поддерживаются механизмы PLAIN, SCRAM-SHA-256 и SCRAM-SHA-512.
+//
+// PLAIN передает пароль в открытом виде, поэтому без TLS его стоит
+// использовать только во внутренней сети.
+func applySASL(config *sarama.Config, cfg config.SASL) error {
+	if cfg.Username == "" {
+		return nil
+	}
+
+	config.Net.SASL.Enable = true
+	config.Net.SASL.Handshake = true
+	config.Net.SASL.User = cfg.Username
+	config.Net.SASL.Password = cfg.Password
+
+	switch strings.ToUpper(cfg.Mechanism) {
+	case "", sarama.SASLTypePlaintext:
+		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case sarama.SASLTypeSCRAMSHA256:
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hash: sha256.New}
+		}
+	case sarama.SASLTypeSCRAMSHA512:
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hash: sha512.New}
+		}
+	default:
+		return fmt.Errorf("unknown sasl mechanism %q", cfg.Mechanism)
+	}
+
+	return nil
+}
+
+// scramClient реализует клиентскую сторону SCRAM (RFC 5802) для sarama.
+// Обмен состоит из трех шагов: client-first, client-final с доказательством
+// знания пароля и проверка подписи сервера.
+type scramClient struct {
+	hash func() hash.Hash
+
+	user, password string
+	nonce          string // Клиентская часть nonce.
+	firstBare      string // client-first-message без заголовка GS2.
+	serverSig      []byte // Ожидаемая подпись сервера.
+	step           int
+	done           bool
+}
+
+// Begin начинает новый обмен для пользователя `user`.
+func (c *scramClient) Begin(user, password, _ string) error {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("can't generate nonce: %v", err)
+	}
+
+	c.user = user
+	c.password = password
+	c.nonce = base64.RawStdEncoding.EncodeToString(nonce)
+	c.step = 0
+	c.done = false
+
+	return nil
+}
+
+// Step возвращает ответ клиента на сообщение сервера `challenge`.
+func (c *scramClient) Step(challenge string) (string, error) {
+	defer func() { c.step++ }()
+
+	switch c.step {
+	case 0:
+		name := strings.NewReplacer("=", "=3D", ",", "=2C").Replace(c.user)
+		c.firstBare = "n=" + name + ",r=" + c.nonce
+		return "n,," + c.firstBare, nil
+	case 1:
+		return c.clientFinal(challenge)
+	case 2:
+		c.done = true
+		attrs := scramAttributes(challenge)
+		if e, ok := attrs["e"]; ok {
+			return "", fmt.Errorf("server rejected authentication: %s", e)
+		}
+		sig, err := base64.StdEncoding.DecodeString(attrs["v"])
+		if err != nil || !hmac.Equal(sig, c.serverSig) {
+			return "", errors.New("invalid server signature")
+		}
+		return "", nil
+	default:
+		return "", errors.New("unexpected scram challenge")
+	}
+}
+
+// Done сообщает, что обмен завершен.
+func (c *scramClient) Done() bool {
+	return c.done
+}
+
+// clientFinal вычисляет client-final-message по server-first-message.
+func (c *scramClient) clientFinal(serverFirst string) (string, error) {
+	attrs := scramAttributes(serverFirst)
+
+	nonce := attrs["r"]
+	if !strings.HasPrefix(nonce, c.nonce) {
+		return "", errors.New("server nonce doesn't match client nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(attrs["s"])
+	if err != nil {
+		return "", fmt.Errorf("invalid salt: %v", err)
+	}
+	iterations, err := strconv.Atoi(attrs["i"])
+	if err != nil || iterations <= 0 {
+		return "", fmt.Errorf("invalid iteration count %q", attrs["i"])
+	}
+
+	salted, err := pbkdf2.Key(c.hash, c.password, salt, iterations, c.hash().Size())
+	if err != nil {
+		return "", fmt.Errorf("can't derive key: %v", err)
+	}
+
+	withoutProof := "c=biws,r=" + nonce
+	authMessage := c.firstBare + "," + serverFirst + "," + withoutProof
+
+	clientKey := c.hmac(salted, "Client Key")
+	h := c.hash()
+	h.Write(clientKey)
+	clientSig := c.hmac(h.Sum(nil), authMessage)
+
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ clientSig[i]
+	}
+
+	c.serverSig = c.hmac(c.hmac(salted, "Server Key"), authMessage)
+
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+// hmac возвращает HMAC сообщения `msg` с ключом `key`.
+func (c *scramClient) hmac(key []byte, msg string) []byte {
+	mac := hmac.New(c.hash, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
+
+// scramAttributes разбирает сообщение SCRAM вида `a=1,b=2`.
+func scramAttributes(msg string) map[string]string {
+	attrs := make(map[string]string)
+	for _, part := range strings.Split(msg, ",") {
+		if key, value, ok := strings.Cut(part, "="); ok {
+			attrs[key] = value
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,75 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/IBM/sarama"
+	"github.com/YusovID/order-service/internal/config"
+)
+
+// EnsureTopics создает топики сервиса, которых еще нет в кластере:
+// все топики из `cfg.Topic`, топик DLQ и топик событий (если они заданы).
+//
+// Новые топики создаются с количеством партиций и фактором репликации
+// из конфигурации. Существующие топики не изменяются.
+func EnsureTopics(cfg config.Kafka, log *slog.Logger) error {
+	const fn = "storage.kafka.EnsureTopics"
+
+	saramaConfig, err := newSaramaConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("%s: %v", fn, err)
+	}
+
+	admin, err := sarama.NewClusterAdmin(cfg.BootstrapServers, saramaConfig)
+	if err != nil {
+		return fmt.Errorf("%s: can't create cluster admin: %v", fn, err)
+	}
+	defer admin.Close()
+
+	existing, err := admin.ListTopics()
+	if err != nil {
+		return fmt.Errorf("%s: can't list topics: %v", fn, err)
+	}
+
+	detail := &sarama.TopicDetail{
+		NumPartitions:     cfg.TopicPartitions,
+		ReplicationFactor: cfg.TopicReplicationFactor,
+	}
+
+	for _, topic := range serviceTopics(cfg) {
+		if _, ok := existing[topic]; ok {
+			continue
+		}
+
+		err := admin.CreateTopic(topic, detail, false)
+		// Топик мог создать другой экземпляр сервиса между ListTopics и CreateTopic.
+		if err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+			return fmt.Errorf("%s: can't create topic %s: %v", fn, topic, err)
+		}
+
+		log.Info("topic created",
+			slog.String("topic", topic),
+			slog.Int("partitions", int(detail.NumPartitions)),
+			slog.Int("replication_factor", int(detail.ReplicationFactor)),
+		)
+	}
+
+	return nil
+}
+
+// serviceTopics возвращает список всех топиков, с которыми работает сервис.
+func serviceTopics(cfg config.Kafka) []string {
+	topics := append([]string{}, cfg.Topic...)
+	if cfg.DLQTopic != "" {
+		topics = append(topics, cfg.DLQTopic)
+	}
+	if cfg.QuarantineTopic != "" {
+		topics = append(topics, cfg.QuarantineTopic)
+	}
+	if cfg.Events.Topic != "" {
+		topics = append(topics, cfg.Events.Topic)
+	}
+	return topics
+}
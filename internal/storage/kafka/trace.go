@@ -0,0 +1,101 @@
+package kafka
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/IBM/sarama"
+)
+
+// HeaderTraceparent - заголовок W3C Trace Context
+// (https://www.w3.org/TR/trace-context/), по которому генератор, DLQ,
+// события и обработчик заказов связываются в одно дерево трассировки.
+const HeaderTraceparent = "traceparent"
+
+// traceVersion - поддерживаемая версия формата traceparent.
+const traceVersion = "00"
+
+// flagSampled - флаг traceparent "трасса записывается".
+const flagSampled = "01"
+
+// TraceContext - контекст трассировки W3C: идентификатор трассы, общий для
+// всех участников, и идентификатор текущего участка (span).
+type TraceContext struct {
+	TraceID string // 32 шестнадцатеричных символа.
+	SpanID  string // 16 шестнадцатеричных символов.
+	Flags   string // 2 шестнадцатеричных символа.
+}
+
+// NewTrace начинает новую трассу с корневым участком.
+func NewTrace() TraceContext {
+	return TraceContext{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Flags:   flagSampled,
+	}
+}
+
+// Child возвращает контекст дочернего участка той же трассы. Для пустого
+// контекста начинается новая трасса.
+func (tc TraceContext) Child() TraceContext {
+	if !tc.Valid() {
+		return NewTrace()
+	}
+	return TraceContext{TraceID: tc.TraceID, SpanID: randomHex(8), Flags: tc.Flags}
+}
+
+// Valid сообщает, задан ли контекст.
+func (tc TraceContext) Valid() bool {
+	return tc.TraceID != "" && tc.SpanID != ""
+}
+
+// String возвращает значение заголовка traceparent.
+func (tc TraceContext) String() string {
+	return traceVersion + "-" + tc.TraceID + "-" + tc.SpanID + "-" + tc.Flags
+}
+
+// Header возвращает заголовок traceparent для сообщения.
+func (tc TraceContext) Header() sarama.RecordHeader {
+	return sarama.RecordHeader{Key: []byte(HeaderTraceparent), Value: []byte(tc.String())}
+}
+
+// ParseTraceparent разбирает значение заголовка traceparent. Возвращает
+// false для некорректного значения, в том числе для нулевых идентификаторов.
+// Значения более новых версий формата принимаются по первым четырем полям.
+func ParseTraceparent(s string) (TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || (parts[0] == traceVersion && len(parts) != 4) {
+		return TraceContext{}, false
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" ||
+		!isHex(traceID, 32) || traceID == strings.Repeat("0", 32) ||
+		!isHex(spanID, 16) || spanID == strings.Repeat("0", 16) ||
+		!isHex(flags, 2) {
+		return TraceContext{}, false
+	}
+
+	return TraceContext{TraceID: traceID, SpanID: spanID, Flags: flags}, true
+}
+
+// isHex сообщает, состоит ли `s` ровно из `n` строчных шестнадцатеричных символов.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// randomHex возвращает `n` случайных байт в шестнадцатеричном виде.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b) // crypto/rand.Read не возвращает ошибок.
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent - событие, записанное в таблицу outbox в одной транзакции
+// с изменением данных и ожидающее публикации в Kafka.
+type OutboxEvent struct {
+	ID        int64           // Порядковый номер события.
+	Type      string          // Тип события (models.EventOrderSaved и др.).
+	Key       string          // Ключ сообщения Kafka (order_uid).
+	Payload   json.RawMessage // Тело события в JSON.
+	CreatedAt time.Time       // Время записи события.
+}
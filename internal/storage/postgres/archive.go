@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/jackc/pgx/v5"
+)
+
+// ArchiveOrders переносит в `orders_archive` не больше `limit` заказов,
+// созданных раньше `before`, и удаляет их из рабочих таблиц. Все действия
+// выполняются в одной транзакции. Возвращает order_uid перенесенных заказов.
+//
+// Заказы выбираются с `FOR UPDATE SKIP LOCKED`, поэтому несколько экземпляров
+// сервиса могут архивировать одновременно, не мешая друг другу.
+func (s *Storage) ArchiveOrders(ctx context.Context, before time.Time, limit int) (orderUIDs []string, err error) {
+	const fn = "storage.postgres.ArchiveOrders"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't start transaction: %w", fn, err)
+	}
+	defer func() {
+		if err != nil {
+			if txErr := tx.Rollback(ctx); txErr != nil {
+				s.log.Error("can't rollback transaction", slog.String("fn", fn), sl.Err(txErr))
+			}
+		}
+	}()
+
+	if err = setActor(storage.WithActor(ctx, "archiver"), tx); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	rows, err := tx.Query(ctx,
+		"SELECT order_uid FROM orders WHERE date_created < $1 ORDER BY date_created LIMIT $2 FOR UPDATE SKIP LOCKED",
+		before, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't select orders: %w", fn, err)
+	}
+	if orderUIDs, err = pgx.CollectRows(rows, pgx.RowTo[string]); err != nil {
+		return nil, fmt.Errorf("%s: can't read order uids: %w", fn, err)
+	}
+	if len(orderUIDs) == 0 {
+		return nil, tx.Commit(ctx)
+	}
+
+	orders, err := s.getOrdersByUID(ctx, tx, orderUIDs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	// Заказы без товаров или без доставки/оплаты не попадают в выборку
+	// getOrdersByUID; их не удаляем, чтобы не потерять данные.
+	if len(orders) < len(orderUIDs) {
+		s.log.Warn("some orders can't be loaded and are left in place",
+			slog.String("fn", fn),
+			slog.Int("skipped", len(orderUIDs)-len(orders)),
+		)
+	}
+	orderUIDs = orderUIDs[:0]
+
+	batch := &pgx.Batch{}
+	for _, order := range orders {
+		orderUIDs = append(orderUIDs, order.OrderUID)
+
+		data, err := json.Marshal(order)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't marshal order %s: %w", fn, order.OrderUID, err)
+		}
+		batch.Queue(
+			"INSERT INTO orders_archive (order_uid, date_created, data) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+			order.OrderUID, order.DateCreated, data,
+		)
+	}
+	// Товары удаляются вместе с заказом (ON DELETE CASCADE).
+	batch.Queue("DELETE FROM deliveries WHERE order_uid = ANY($1)", orderUIDs)
+	batch.Queue("DELETE FROM payments WHERE order_uid = ANY($1)", orderUIDs)
+	batch.Queue("DELETE FROM orders WHERE order_uid = ANY($1)", orderUIDs)
+
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, fmt.Errorf("%s: can't archive orders: %w", fn, err)
+	}
+
+	return orderUIDs, tx.Commit(ctx)
+}
+
+// RunArchiver периодически переносит в архив заказы старше
+// `cfg.RetentionDays` дней пачками по `cfg.BatchSize`, пока не отменен
+// контекст. После каждой пачки вызывается `onArchived` (например, чтобы
+// удалить заказы из кэша).
+func (s *Storage) RunArchiver(ctx context.Context, cfg config.Archive, onArchived func(ctx context.Context, orderUIDs []string), wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if cfg.RetentionDays <= 0 || cfg.Interval <= 0 || cfg.BatchSize <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.archive(ctx, cfg, onArchived)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// archive (unexported) архивирует пачки заказов, пока старые заказы не закончатся.
+func (s *Storage) archive(ctx context.Context, cfg config.Archive, onArchived func(ctx context.Context, orderUIDs []string)) {
+	before := time.Now().AddDate(0, 0, -cfg.RetentionDays)
+
+	total := 0
+	for ctx.Err() == nil {
+		orderUIDs, err := s.ArchiveOrders(ctx, before, cfg.BatchSize)
+		if err != nil {
+			s.log.Error("failed to archive orders", sl.Err(err))
+			return
+		}
+		if len(orderUIDs) > 0 {
+			onArchived(ctx, orderUIDs)
+			total += len(orderUIDs)
+		}
+		if len(orderUIDs) < cfg.BatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		s.log.Info("orders archived", slog.Int("count", total), slog.Time("before", before))
+	}
+}
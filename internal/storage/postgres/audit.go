@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// defaultActor - автор изменений, если он не передан в контексте.
+const defaultActor = "order-service"
+
+// setActor (unexported) передает триггеру журнала аудита автора изменений
+// (см. storage.WithActor). Параметр действует до конца транзакции.
+func setActor(ctx context.Context, tx pgx.Tx) error {
+	actor, ok := storage.ActorFromContext(ctx)
+	if !ok {
+		actor = defaultActor
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('app.actor', $1, true)", actor); err != nil {
+		return fmt.Errorf("can't set audit actor: %w", err)
+	}
+	return nil
+}
+
+// GetOrderHistory возвращает журнал изменений заказа из таблицы
+// `orders_audit` от старых записей к новым. Если записей нет,
+// возвращает storage.ErrNoOrder.
+func (s *Storage) GetOrderHistory(ctx context.Context, orderUID string) ([]models.OrderChange, error) {
+	const fn = "storage.postgres.GetOrderHistory"
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	query, args, err := s.sq.Select("operation", "old_data", "new_data", "actor", "changed_at").
+		From("orders_audit").
+		Where("order_uid = ?", orderUID).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to build history query: %w", fn, err)
+	}
+
+	var history []models.OrderChange
+	err = s.read(ctx, fn, func(db *pgxpool.Pool) error {
+		rows, err := db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		history, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.OrderChange, error) {
+			var c models.OrderChange
+			err := row.Scan(&c.Operation, &c.OldData, &c.NewData, &c.Actor, &c.ChangedAt)
+			return c, err
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to execute history query: %w", fn, err)
+	}
+
+	if len(history) == 0 {
+		return nil, storage.ErrNoOrder
+	}
+
+	return history, nil
+}
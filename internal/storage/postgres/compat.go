@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// legacyWriter записывает заказ в "старую" раскладку колонок в рамках
+// уже открытой транзакции SaveOrder.
+type legacyWriter func(ctx context.Context, tx pgx.Tx, orderData *models.OrderData) error
+
+// legacyWriters - список функций записи в старую раскладку для текущего перехода схемы.
+//
+// Миграция, меняющая способ хранения данных, добавляет сюда функцию, которая
+// продолжает заполнять старые колонки/таблицы. После закрытия окна совместимости
+// и удаления старой раскладки функция удаляется вместе с ней.
+var legacyWriters = []legacyWriter{
+	// Миграция 4: доставка и оплата перенесены из JSONB в таблицы deliveries и payments.
+	writeJSONBDeliveryPayment,
+}
+
+// compatMode описывает режим двойной записи после миграции схемы.
+// Пока режим активен, предыдущая версия сервиса продолжает видеть
+// актуальные данные, и откат бинарника не приводит к их потере.
+type compatMode struct {
+	enabled bool
+	until   time.Time
+	writers []legacyWriter
+}
+
+// newCompatMode создает режим совместимости по конфигурации.
+func newCompatMode(cfg config.Compat) compatMode {
+	return compatMode{
+		enabled: cfg.Enabled,
+		until:   cfg.Until,
+		writers: legacyWriters,
+	}
+}
+
+// active сообщает, нужно ли в момент `now` дублировать запись в старую раскладку.
+func (c compatMode) active(now time.Time) bool {
+	if !c.enabled || len(c.writers) == 0 {
+		return false
+	}
+	return c.until.IsZero() || now.Before(c.until)
+}
+
+// writeLegacy вызывает все функции записи в старую раскладку.
+func (c compatMode) writeLegacy(ctx context.Context, tx pgx.Tx, orderData *models.OrderData) error {
+	for _, write := range c.writers {
+		if err := write(ctx, tx, orderData); err != nil {
+			return err
+		}
+	}
+	return nil
+}
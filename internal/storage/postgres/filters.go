@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// JSONBColumn - это имя JSONB-колонки таблицы `orders`, по которой можно фильтровать.
+// Имя колонки нельзя передать как параметр запроса, поэтому оно подставляется
+// в SQL напрямую и обязательно проверяется по белому списку.
+type JSONBColumn string
+
+// Допустимые JSONB-колонки таблицы `orders`.
+//
+// После миграции 4 PaymentData и DeliveryData заполняются только в режиме
+// совместимости; для фильтров по оплате используйте PaymentProviderEq и PaymentBankEq.
+const (
+	PaymentData    JSONBColumn = "payment_data"
+	DeliveryData   JSONBColumn = "delivery_data"
+	AdditionalData JSONBColumn = "additional_data"
+)
+
+// jsonbKeyRe описывает допустимые ключи JSON-объекта. Ключ подставляется
+// в запрос литералом, поэтому разрешены только "безопасные" идентификаторы.
+var jsonbKeyRe = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// valid проверяет, что колонка входит в белый список.
+func (c JSONBColumn) valid() bool {
+	switch c {
+	case PaymentData, DeliveryData, AdditionalData:
+		return true
+	}
+	return false
+}
+
+// jsonbFilter реализует `squirrel.Sqlizer`. Ошибка валидации откладывается
+// до вызова ToSql, чтобы хелперы можно было сразу передавать в `Where`.
+type jsonbFilter struct {
+	sql  string
+	args []any
+	err  error
+}
+
+// ToSql возвращает SQL-фрагмент условия и его параметры.
+func (f jsonbFilter) ToSql() (string, []any, error) {
+	if f.err != nil {
+		return "", nil, f.err
+	}
+	return f.sql, f.args, nil
+}
+
+// JSONBContains строит условие `column @> $1::jsonb`.
+//
+// Значение сериализуется в JSON и передается параметром запроса. Оператор
+// containment (`@>`) умеет использовать GIN-индекс по колонке, поэтому
+// для фильтров на равенство предпочтителен именно он.
+func JSONBContains(column JSONBColumn, value map[string]any) squirrel.Sqlizer {
+	if !column.valid() {
+		return jsonbFilter{err: fmt.Errorf("unknown jsonb column %q", column)}
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return jsonbFilter{err: fmt.Errorf("can't marshal jsonb filter: %v", err)}
+	}
+
+	return jsonbFilter{
+		sql:  fmt.Sprintf("%s @> ?::jsonb", column),
+		args: []any{string(b)},
+	}
+}
+
+// JSONBFieldEq строит условие `column->>'key' = $1`.
+//
+// Ключ проверяется и подставляется литералом, а не параметром: только так
+// выражение совпадет с функциональным индексом вида `((payment_data->>'provider'))`.
+// Сравниваемое значение всегда передается параметром.
+func JSONBFieldEq(column JSONBColumn, key string, value string) squirrel.Sqlizer {
+	if !column.valid() {
+		return jsonbFilter{err: fmt.Errorf("unknown jsonb column %q", column)}
+	}
+	if !jsonbKeyRe.MatchString(key) {
+		return jsonbFilter{err: fmt.Errorf("invalid jsonb key %q", key)}
+	}
+
+	return jsonbFilter{
+		sql:  fmt.Sprintf("%s->>'%s' = ?", column, key),
+		args: []any{value},
+	}
+}
+
+// PaymentProviderEq фильтрует заказы по платежному провайдеру.
+// Использует индекс `payments_provider_idx` таблицы `payments`.
+func PaymentProviderEq(provider string) squirrel.Sqlizer {
+	return squirrel.Expr("order_uid IN (SELECT order_uid FROM payments WHERE provider = ?)", provider)
+}
+
+// PaymentBankEq фильтрует заказы по банку, через который прошел платеж.
+// Использует индекс `payments_bank_idx` таблицы `payments`.
+func PaymentBankEq(bank string) squirrel.Sqlizer {
+	return squirrel.Expr("order_uid IN (SELECT order_uid FROM payments WHERE bank = ?)", bank)
+}
+
+// LocaleEq фильтрует заказы по языку пользователя.
+func LocaleEq(locale string) squirrel.Sqlizer {
+	return JSONBContains(AdditionalData, map[string]any{"locale": locale})
+}
+
+// EntryEq фильтрует заказы по источнику (полю `entry`).
+func EntryEq(entry string) squirrel.Sqlizer {
+	return JSONBContains(AdditionalData, map[string]any{"entry": entry})
+}
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Ограничения размера страницы ListOrders.
+const (
+	DefaultPageLimit = 50  // Размер страницы, если он не задан.
+	MaxPageLimit     = 500 // Максимальный размер страницы.
+)
+
+// OrderFilter описывает условия отбора заказов для ListOrders.
+// Пустые поля не участвуют в фильтрации.
+//
+// Под CustomerID, CreatedFrom/CreatedTo и TrackNumber есть индексы
+// (см. миграцию 5_filter_indexes).
+type OrderFilter struct {
+	CustomerID      string
+	DeliveryService string
+	TrackNumber     string    // Трек-номер хотя бы одного товара заказа.
+	CreatedFrom     time.Time // Нижняя граница date_created (включительно).
+	CreatedTo       time.Time // Верхняя граница date_created (не включительно).
+
+	// Extra - дополнительные условия на таблицу `orders`,
+	// например JSONB-фильтры (PaymentProviderEq, LocaleEq и т.д.).
+	Extra []squirrel.Sqlizer
+}
+
+// Page задает страницу результатов.
+type Page struct {
+	Limit  uint64 // 0 означает DefaultPageLimit; больше MaxPageLimit обрезается.
+	Offset uint64
+}
+
+// where собирает условия фильтра.
+func (f OrderFilter) where() squirrel.And {
+	where := squirrel.And{}
+	if f.CustomerID != "" {
+		where = append(where, squirrel.Eq{"customer_id": f.CustomerID})
+	}
+	if f.DeliveryService != "" {
+		where = append(where, squirrel.Eq{"delivery_service": f.DeliveryService})
+	}
+	if f.TrackNumber != "" {
+		where = append(where, squirrel.Expr(
+			"order_uid IN (SELECT order_uid FROM order_items WHERE track_number = ?)", f.TrackNumber,
+		))
+	}
+	if !f.CreatedFrom.IsZero() {
+		where = append(where, squirrel.GtOrEq{"date_created": f.CreatedFrom})
+	}
+	if !f.CreatedTo.IsZero() {
+		where = append(where, squirrel.Lt{"date_created": f.CreatedTo})
+	}
+	return append(where, f.Extra...)
+}
+
+// limit возвращает размер страницы с учетом умолчания и ограничения.
+func (p Page) limit() uint64 {
+	switch {
+	case p.Limit == 0:
+		return DefaultPageLimit
+	case p.Limit > MaxPageLimit:
+		return MaxPageLimit
+	default:
+		return p.Limit
+	}
+}
+
+// ListOrders возвращает страницу заказов, подходящих под фильтр, от новых к старым.
+//
+// Сначала выбираются order_uid заказов страницы (LIMIT/OFFSET применяются
+// к заказам, а не к строкам JOIN), затем одним запросом загружаются сами
+// заказы с товарами. Пустой результат ошибкой не считается.
+// Оба запроса выполняются на реплике, если она настроена.
+func (s *Storage) ListOrders(ctx context.Context, filter OrderFilter, page Page) (_ []*models.OrderData, err error) {
+	const fn = "storage.postgres.ListOrders"
+	defer func(start time.Time) { observe("ListOrders", start, err) }(time.Now())
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	query, args, err := s.sq.Select("order_uid").
+		From("orders").
+		Where(filter.where()).
+		OrderBy("date_created DESC", "order_uid").
+		Limit(page.limit()).
+		Offset(page.Offset).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to build list orders query: %w", fn, err)
+	}
+
+	var orders []*models.OrderData
+	err = s.read(ctx, fn, func(db *pgxpool.Pool) error {
+		orders, err = s.listOrders(ctx, db, query, args)
+		return err
+	})
+	return orders, err
+}
+
+// CountOrders возвращает число заказов, подходящих под фильтр
+// (например, для общего количества в ответе ListOrders).
+// Запрос выполняется на реплике, если она настроена.
+func (s *Storage) CountOrders(ctx context.Context, filter OrderFilter) (count int64, err error) {
+	const fn = "storage.postgres.CountOrders"
+	defer func(start time.Time) { observe("CountOrders", start, err) }(time.Now())
+
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	query, args, err := s.sq.Select("count(*)").
+		From("orders").
+		Where(filter.where()).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to build count orders query: %w", fn, err)
+	}
+
+	err = s.read(ctx, fn, func(db *pgxpool.Pool) error {
+		return db.QueryRow(ctx, query, args...).Scan(&count)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to execute count orders query: %w", fn, err)
+	}
+
+	return count, nil
+}
+
+// listOrders (unexported) выполняет запрос order_uid страницы и загружает заказы.
+func (s *Storage) listOrders(ctx context.Context, db querier, query string, args []any) ([]*models.OrderData, error) {
+	const fn = "storage.postgres.ListOrders"
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to execute list orders query: %w", fn, err)
+	}
+
+	var orderUIDs []string
+	for rows.Next() {
+		var orderUID string
+		if err := rows.Scan(&orderUID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("%s: can't scan order uid: %w", fn, err)
+		}
+		orderUIDs = append(orderUIDs, orderUID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: can't read order uids: %w", fn, err)
+	}
+
+	if len(orderUIDs) == 0 {
+		return []*models.OrderData{}, nil
+	}
+
+	return s.getOrdersByUID(ctx, db, orderUIDs)
+}
+
+// getOrdersByUID загружает заказы с товарами и возвращает их
+// в порядке `orderUIDs`.
+func (s *Storage) getOrdersByUID(ctx context.Context, db querier, orderUIDs []string) ([]*models.OrderData, error) {
+	const fn = "storage.postgres.getOrdersByUID"
+
+	query, args, err := s.selectOrders().
+		Where(squirrel.Eq{"o.order_uid": orderUIDs}).
+		OrderBy("i.id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to build get orders query: %w", fn, err)
+	}
+
+	joinedRows, err := selectJoined(ctx, db, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to execute get orders query: %w", fn, err)
+	}
+
+	ordersMap := make(map[string]*models.OrderData, len(orderUIDs))
+	for _, row := range joinedRows {
+		orderData, exists := ordersMap[row.OrderDB.OrderUID]
+		if !exists {
+			if orderData, err = fillOrderData(row); err != nil {
+				return nil, fmt.Errorf("%s: can't fill order data: %w", fn, err)
+			}
+			ordersMap[row.OrderDB.OrderUID] = orderData
+		}
+		appendItems(row, orderData)
+	}
+
+	orders := make([]*models.OrderData, 0, len(ordersMap))
+	for _, orderUID := range orderUIDs {
+		if order, ok := ordersMap[orderUID]; ok {
+			orders = append(orders, order)
+		}
+	}
+
+	return orders, nil
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/YusovID/order-service/internal/metrics"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// observe записывает длительность операции `method` в метрики.
+// Отсутствие заказа ошибкой не считается, пропущенный дубликат учитывается
+// отдельно.
+func observe(method string, start time.Time, err error) {
+	result := "ok"
+	switch {
+	case errors.Is(err, storage.ErrDuplicateOrder):
+		result = "duplicate"
+	case err != nil && !errors.Is(err, storage.ErrNoOrder):
+		result = "error"
+	}
+	metrics.DBQueryDuration.WithLabelValues(method, result).Observe(time.Since(start).Seconds())
+}
+
+// registerPoolMetrics экспортирует состояние пула `db` под именем `pool`.
+func registerPoolMetrics(pool string, db *pgxpool.Pool) {
+	metrics.RegisterDBPool(pool, func() metrics.DBPoolStats {
+		stat := db.Stat()
+		return metrics.DBPoolStats{
+			TotalConns:      stat.TotalConns(),
+			AcquiredConns:   stat.AcquiredConns(),
+			IdleConns:       stat.IdleConns(),
+			MaxConns:        stat.MaxConns(),
+			AcquireCount:    stat.AcquireCount(),
+			WaitCount:       stat.EmptyAcquireCount(),
+			AcquireDuration: stat.AcquireDuration(),
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/repository/migrations"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres" // Драйвер для поддержки PostgreSQL в migrate
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Migrate применяет к базе все еще не примененные миграции, встроенные
+// в бинарный файл (см. пакет migrations). История миграций хранится в той же
+// таблице, что и у cmd/migrator (cfg.MigrationsTable), поэтому способы
+// можно совмещать.
+func Migrate(cfg config.Postgres, log *slog.Logger) error {
+	const fn = "storage.postgres.Migrate"
+
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return fmt.Errorf("%s: can't open embedded migrations: %w", fn, err)
+	}
+
+	m, err := migrate.NewWithSourceInstance(
+		"iofs", src,
+		fmt.Sprintf("%s&x-migrations-table=%s", ConnString(cfg), url.QueryEscape(cfg.MigrationsTable)),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: can't create migrator: %w", fn, err)
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			log.Info("no migrations to apply")
+			return nil
+		}
+		return fmt.Errorf("%s: can't apply migrations: %w", fn, err)
+	}
+
+	version, _, err := m.Version()
+	if err != nil {
+		return fmt.Errorf("%s: can't get migration version: %w", fn, err)
+	}
+	log.Info("migrations applied successfully", slog.Uint64("version", uint64(version)))
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/jackc/pgx/v5"
+)
+
+// OrdersChangedChannel - канал LISTEN/NOTIFY, в который SaveOrder после
+// фиксации транзакции отправляет order_uid измененного заказа.
+const OrdersChangedChannel = "orders_changed"
+
+// Паузы между попытками восстановить соединение слушателя.
+const (
+	listenInitialBackoff = time.Second
+	listenMaxBackoff     = 30 * time.Second
+)
+
+// notifyChanged (unexported) ставит в очередь уведомление об изменении заказа.
+// Уведомление доставляется слушателям только после фиксации транзакции
+// и не доставляется при откате.
+func notifyChanged(ctx context.Context, tx pgx.Tx, orderUID string) error {
+	if _, err := tx.Exec(ctx, "SELECT pg_notify($1, $2)", OrdersChangedChannel, orderUID); err != nil {
+		return fmt.Errorf("can't notify order change: %w", err)
+	}
+	return nil
+}
+
+// ListenOrderChanges подписывается на канал OrdersChangedChannel и вызывает
+// `handle` для каждого измененного заказа, в том числе сохраненного другими
+// экземплярами сервиса. Блокируется до отмены контекста.
+//
+// Слушатель держит отдельное соединение вне пула. При обрыве соединение
+// восстанавливается с экспоненциальной паузой; уведомления, отправленные
+// за время обрыва, теряются.
+func (s *Storage) ListenOrderChanges(ctx context.Context, handle func(ctx context.Context, orderUID string)) {
+	const fn = "storage.postgres.ListenOrderChanges"
+
+	log := s.log.With(slog.String("fn", fn))
+	backoff := listenInitialBackoff
+
+	for {
+		err := s.listen(ctx, log, handle, func() { backoff = listenInitialBackoff })
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Error("order changes listener failed, reconnecting",
+			slog.Duration("backoff", backoff),
+			sl.Err(err),
+		)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, listenMaxBackoff)
+	}
+}
+
+// listen (unexported) открывает соединение, подписывается на канал и читает
+// уведомления до ошибки или отмены контекста. `connected` вызывается после
+// успешной подписки.
+func (s *Storage) listen(ctx context.Context, log *slog.Logger, handle func(ctx context.Context, orderUID string), connected func()) error {
+	conn, err := pgx.ConnectConfig(ctx, s.db.Config().ConnConfig.Copy())
+	if err != nil {
+		return fmt.Errorf("can't connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+OrdersChangedChannel); err != nil {
+		return fmt.Errorf("can't listen channel: %w", err)
+	}
+	connected()
+	log.Info("listening order changes", slog.String("channel", OrdersChangedChannel))
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("can't wait for notification: %w", err)
+		}
+		handle(ctx, n.Payload)
+	}
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/jackc/pgx/v5"
+)
+
+// writeOutbox (unexported) записывает событие в таблицу `outbox` в рамках
+// транзакции изменения данных.
+func writeOutbox(ctx context.Context, tx pgx.Tx, event models.OrderEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("can't marshal outbox event: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		"INSERT INTO outbox (event_type, event_key, payload) VALUES ($1, $2, $3)",
+		event.Type, event.OrderUID, payload,
+	)
+	if err != nil {
+		return fmt.Errorf("can't write outbox event: %w", err)
+	}
+	return nil
+}
+
+// RelayOutbox передает в `publish` не больше `limit` самых старых событий
+// из outbox и удаляет их, если публикация прошла успешно. Возвращает
+// количество опубликованных событий.
+//
+// Строки блокируются (`FOR UPDATE SKIP LOCKED`) до конца транзакции, поэтому
+// несколько экземпляров сервиса не публикуют одно событие одновременно.
+// Если публикация или удаление не удались, события остаются в outbox и будут
+// опубликованы повторно: доставка - at-least-once.
+func (s *Storage) RelayOutbox(ctx context.Context, limit int, publish func(ctx context.Context, events []storage.OutboxEvent) error) (n int, err error) {
+	const fn = "storage.postgres.RelayOutbox"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: can't start transaction: %w", fn, err)
+	}
+	defer func() {
+		if err != nil {
+			if txErr := tx.Rollback(ctx); txErr != nil {
+				s.log.Error("can't rollback transaction", slog.String("fn", fn), sl.Err(txErr))
+			}
+		}
+	}()
+
+	rows, err := tx.Query(ctx,
+		"SELECT id, event_type, event_key, payload, created_at FROM outbox ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED",
+		limit,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: can't select events: %w", fn, err)
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (storage.OutboxEvent, error) {
+		var e storage.OutboxEvent
+		err := row.Scan(&e.ID, &e.Type, &e.Key, &e.Payload, &e.CreatedAt)
+		return e, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: can't read events: %w", fn, err)
+	}
+	if len(events) == 0 {
+		return 0, tx.Commit(ctx)
+	}
+
+	if err = publish(ctx, events); err != nil {
+		return 0, fmt.Errorf("%s: can't publish events: %w", fn, err)
+	}
+
+	ids := make([]int64, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	if _, err = tx.Exec(ctx, "DELETE FROM outbox WHERE id = ANY($1)", ids); err != nil {
+		return 0, fmt.Errorf("%s: can't delete published events: %w", fn, err)
+	}
+
+	return len(events), tx.Commit(ctx)
+}
+
+// RunOutboxRelay публикует события из outbox каждые `interval` пачками
+// по `batchSize`, пока не отменен контекст. Если событий накопилось больше
+// пачки, следующая пачка публикуется без ожидания.
+func (s *Storage) RunOutboxRelay(ctx context.Context, interval time.Duration, batchSize int, publish func(ctx context.Context, events []storage.OutboxEvent) error, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if interval <= 0 || batchSize <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.RelayOutbox(ctx, batchSize, publish)
+		if err != nil && ctx.Err() == nil {
+			s.log.Error("failed to relay outbox events", sl.Err(err))
+		}
+		if err == nil && n == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
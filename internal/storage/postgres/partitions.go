@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/lib/logger/sl"
+)
+
+// partitionedTables - таблицы, секционированные по месяцам date_created.
+var partitionedTables = []string{"orders", "order_items"}
+
+// EnsurePartitions создает месячные секции таблиц orders и order_items
+// с текущего месяца на `ahead` месяцев вперед. Уже существующие секции
+// пропускаются.
+//
+// Секцию нужно создать до того, как в нее придут заказы: если строки
+// нового месяца уже попали в секцию по умолчанию, создать секцию не получится.
+func (s *Storage) EnsurePartitions(ctx context.Context, now time.Time, ahead int) error {
+	const fn = "storage.postgres.EnsurePartitions"
+
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= ahead; i++ {
+		from, to := month.AddDate(0, i, 0), month.AddDate(0, i+1, 0)
+
+		for _, table := range partitionedTables {
+			name := fmt.Sprintf("%s_y%04dm%02d", table, from.Year(), from.Month())
+			query := fmt.Sprintf(
+				"CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+				name, table, from.Format(time.RFC3339), to.Format(time.RFC3339),
+			)
+			if _, err := s.db.Exec(ctx, query); err != nil {
+				return fmt.Errorf("%s: can't create partition %s: %w", fn, name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RunPartitionMaintenance создает секции при старте и затем каждые
+// `cfg.Interval`, пока не отменен контекст. Отрицательный `cfg.Ahead`
+// отключает обслуживание секций.
+func (s *Storage) RunPartitionMaintenance(ctx context.Context, cfg config.Partitions, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if cfg.Ahead < 0 || cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.EnsurePartitions(ctx, time.Now().UTC(), cfg.Ahead); err != nil {
+			s.log.Error("partition maintenance failed", sl.Err(err))
+		} else {
+			s.log.Debug("partitions are up to date", slog.Int("months_ahead", cfg.Ahead))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
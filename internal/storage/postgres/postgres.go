@@ -1,14 +1,18 @@
 // Package postgres предоставляет реализацию хранилища данных с использованием
 // базы данных PostgreSQL. Он отвечает за все операции CRUD (Create, Read, Update, Delete)
-// связанные с заказами. Пакет использует `sqlx` для удобной работы с SQL и
+// связанные с заказами. Пакет использует пул соединений `pgxpool` (pgx v5) и
 // `squirrel` для декларативного построения запросов.
 package postgres
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/Masterminds/squirrel"
@@ -16,30 +20,36 @@ import (
 	"github.com/YusovID/order-service/internal/models"
 	"github.com/YusovID/order-service/internal/storage"
 	"github.com/YusovID/order-service/lib/logger/sl"
-	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq" // Драйвер PostgreSQL.
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Storage инкапсулирует подключение к базе данных и предоставляет методы
 // для работы с данными заказов.
 type Storage struct {
-	db  *sqlx.DB
-	log *slog.Logger
-	sq  squirrel.StatementBuilderType // Построитель запросов squirrel.
+	db      *pgxpool.Pool // Пул соединений с основным сервером (primary).
+	replica *replica      // Реплика для чтения. Может быть nil.
+	log     *slog.Logger
+	sq      squirrel.StatementBuilderType // Построитель запросов squirrel.
+
+	compat       compatMode    // Режим двойной записи после миграций схемы.
+	queryTimeout time.Duration // Ограничение времени одной операции, не больше 0 - без ограничения.
+	retryPolicy  retryPolicy   // Повторы при временных ошибках.
 }
 
 // OrderDB представляет структуру таблицы `orders` в базе данных.
 // Поля, хранящиеся в формате JSONB, представлены как json.RawMessage
-// для отложенного анмаршалинга.
+// для отложенного анмаршалинга. Доставка и оплата хранятся в отдельных
+// таблицах (см. DeliveryDB и PaymentDB).
 type OrderDB struct {
 	OrderUID        string          `db:"order_uid"`
 	TrackNumber     string          `db:"track_number"`
 	CustomerID      string          `db:"customer_id"`
 	DeliveryService string          `db:"delivery_service"`
 	DateCreated     time.Time       `db:"date_created"`
-	PaymentData     json.RawMessage `db:"payment_data"`
-	DeliveryData    json.RawMessage `db:"delivery_data"`
+	Status          string          `db:"status"`
 	AdditionalData  json.RawMessage `db:"additional_data"`
+	UpdatedAt       time.Time       `db:"updated_at"`
 }
 
 // ItemDB представляет структуру таблицы `order_items` в базе данных.
@@ -60,116 +70,488 @@ type ItemDB struct {
 }
 
 // JoinedRow используется для сканирования результатов JOIN-запроса между
-// таблицами `orders` и `order_items`. Она встраивает обе структуры.
+// таблицами `orders`, `deliveries`, `payments` и `order_items`.
+// Она встраивает структуры всех таблиц.
 type JoinedRow struct {
 	OrderDB
+	DeliveryDB
+	PaymentDB
 	ItemDB
 }
 
+// defaultConnectTimeout - время установки соединения с базой при старте,
+// если connect_timeout не задан.
+const defaultConnectTimeout = 10 * time.Second
+
 // New создает и возвращает новый экземпляр Storage, устанавливая
 // соединение с базой данных PostgreSQL.
 func New(cfg config.Postgres, log *slog.Logger) (*Storage, error) {
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.Database,
-	)
+	connectTimeout := cfg.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+
+	db, err := newPool(ctx, cfg, cfg.Host, cfg.Port)
+	if err != nil {
+		return nil, err
+	}
+	// Пул подключается лениво, поэтому проверяем соединение сразу.
+	if err := db.Ping(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can't connect to database: %w", err)
+	}
 
-	db, err := sqlx.Connect("postgres", connStr)
+	replica, err := newReplica(ctx, cfg, log)
 	if err != nil {
-		return nil, fmt.Errorf("can't connect to database: %v", err)
+		db.Close()
+		return nil, err
+	}
+
+	registerPoolMetrics("primary", db)
+	if replica != nil {
+		registerPoolMetrics("replica", replica.db)
+	}
+
+	compat := newCompatMode(cfg.Compat)
+	if compat.enabled {
+		log.Info("schema compat mode enabled",
+			slog.Time("until", compat.until),
+			slog.Int("legacy_writers", len(compat.writers)),
+		)
 	}
 
 	return &Storage{
-		db:  db,
-		log: log,
-		sq:  squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		db:           db,
+		replica:      replica,
+		log:          log,
+		sq:           squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		compat:       compat,
+		queryTimeout: cfg.QueryTimeout,
+		retryPolicy:  newRetryPolicy(cfg.Retry),
 	}, nil
 }
 
 // SaveOrder сохраняет полную информацию о заказе (заказ и его товары)
 // в базу данных в рамках одной транзакции.
 // Если любая из операций вставки завершается ошибкой, вся транзакция откатывается.
+//
+// Если заказ уже есть в базе, он обновляется, а его товары заменяются, но только
+// когда пришедшая версия новее сохраненной (по `UpdatedAt`). Для устаревших версий
+// (например, повторно доставленных сообщений) ничего не записывается и
+// возвращается ошибка, оборачивающая storage.ErrDuplicateOrder.
+//
+// Дата создания заказа после первого сохранения не меняется: если версия
+// пришла с другой `DateCreated`, в `orderData` подставляется сохраненная дата.
+//
+// Временные ошибки (deadlock, конфликт сериализации, обрыв соединения)
+// повторяются с экспоненциальной паузой; если повторы исчерпаны, возвращается
+// ошибка, оборачивающая storage.ErrRetryExhausted.
 func (s *Storage) SaveOrder(ctx context.Context, orderData *models.OrderData) (err error) {
 	const fn = "storage.postgres.SaveOrder"
+	defer func(start time.Time) { observe("SaveOrder", start, err) }(time.Now())
+
+	return s.retry(ctx, fn, func(ctx context.Context) error {
+		return s.saveOrderTx(ctx, orderData)
+	})
+}
+
+// saveOrderTx (unexported) выполняет одну попытку сохранения заказа в транзакции.
+func (s *Storage) saveOrderTx(ctx context.Context, orderData *models.OrderData) (err error) {
+	const fn = "storage.postgres.SaveOrder"
 
-	tx, err := s.db.Beginx()
+	tx, err := s.db.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("%s: can't start transaction: %v", fn, err)
+		return fmt.Errorf("%s: can't start transaction: %w", fn, err)
 	}
 	// `defer` с именованным возвращаемым значением `err` гарантирует,
 	// что откат транзакции произойдет только в случае ошибки.
 	defer func() {
 		if err != nil {
-			if txErr := tx.Rollback(); txErr != nil {
+			if txErr := tx.Rollback(ctx); txErr != nil {
 				s.log.Error("can't rollback transaction", slog.String("fn", fn), sl.Err(txErr))
 			}
 		}
 	}()
 
-	if err = s.saveOrder(ctx, tx, orderData); err != nil {
-		return fmt.Errorf("%s: can't save order: %v", fn, err)
+	if err = setActor(ctx, tx); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	applied, err := s.writeOrder(ctx, tx, orderData)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
 	}
-	if err = s.saveItems(ctx, tx, orderData.Items, orderData.OrderUID); err != nil {
-		return fmt.Errorf("%s: can't save items: %v", fn, err)
+	if !applied {
+		return fmt.Errorf("%s: %w", fn, storage.ErrDuplicateOrder)
 	}
 
-	return tx.Commit()
+	return tx.Commit(ctx)
 }
 
-// saveOrder (unexported) выполняет вставку одной записи в таблицу `orders`.
-// Использует `ON CONFLICT DO NOTHING` для игнорирования дубликатов по `order_uid`.
-func (s *Storage) saveOrder(ctx context.Context, tx *sqlx.Tx, orderData *models.OrderData) error {
-	order, err := convertOrder(orderData)
+// SaveOrders сохраняет несколько заказов в одной транзакции: при потоке
+// заказов это намного быстрее, чем транзакция на каждый заказ.
+//
+// Для каждого заказа действуют те же правила версий, что и в SaveOrder, но
+// устаревшие версии не прерывают транзакцию: для них в `applied` будет false.
+// Любая другая ошибка откатывает все заказы пачки; вызывающий может сохранить
+// их по одному через SaveOrder, чтобы найти проблемный заказ.
+func (s *Storage) SaveOrders(ctx context.Context, orders []*models.OrderData) (applied []bool, err error) {
+	const fn = "storage.postgres.SaveOrders"
+	defer func(start time.Time) { observe("SaveOrders", start, err) }(time.Now())
+
+	err = s.retry(ctx, fn, func(ctx context.Context) error {
+		applied, err = s.saveOrdersTx(ctx, orders)
+		return err
+	})
 	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// saveOrdersTx (unexported) выполняет одну попытку сохранения пачки заказов в транзакции.
+func (s *Storage) saveOrdersTx(ctx context.Context, orders []*models.OrderData) (applied []bool, err error) {
+	const fn = "storage.postgres.SaveOrders"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't start transaction: %w", fn, err)
+	}
+	defer func() {
+		if err != nil {
+			if txErr := tx.Rollback(ctx); txErr != nil {
+				s.log.Error("can't rollback transaction", slog.String("fn", fn), sl.Err(txErr))
+			}
+		}
+	}()
+
+	if err = setActor(ctx, tx); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	applied = make([]bool, len(orders))
+	for i, orderData := range orders {
+		if applied[i], err = s.writeOrder(ctx, tx, orderData); err != nil {
+			return nil, fmt.Errorf("%s: order %s: %w", fn, orderData.OrderUID, err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: can't commit transaction: %w", fn, err)
+	}
+	return applied, nil
+}
+
+// writeOrder записывает заказ со всеми связанными данными в транзакции `tx`.
+// Возвращает false без ошибки, если сохраненная версия заказа не старее
+// пришедшей и ничего не записано.
+func (s *Storage) writeOrder(ctx context.Context, tx pgx.Tx, orderData *models.OrderData) (bool, error) {
+	if err := s.pinDateCreated(ctx, tx, orderData); err != nil {
+		return false, fmt.Errorf("can't save order: %w", err)
+	}
+	applied, created, err := s.saveOrder(ctx, tx, orderData)
+	if err != nil {
+		return false, fmt.Errorf("can't save order: %w", err)
+	}
+	if !applied {
+		return false, nil
+	}
+	if err = s.saveDelivery(ctx, tx, orderData.OrderUID, orderData.Delivery); err != nil {
+		return false, fmt.Errorf("can't save delivery: %w", err)
+	}
+	if err = s.savePayment(ctx, tx, orderData.OrderUID, orderData.Payment); err != nil {
+		return false, fmt.Errorf("can't save payment: %w", err)
+	}
+	if err = s.replaceItems(ctx, tx, orderData); err != nil {
+		return false, fmt.Errorf("can't save items: %w", err)
+	}
+	// В окне совместимости дублируем запись в старую раскладку колонок.
+	if s.compat.active(time.Now()) {
+		if err = s.compat.writeLegacy(ctx, tx, orderData); err != nil {
+			return false, fmt.Errorf("can't save order in legacy layout: %w", err)
+		}
+	}
+	// Событие публикуется в Kafka из outbox, только если заказ сохранен.
+	err = writeOutbox(ctx, tx, models.OrderEvent{
+		Type:        models.EventOrderSaved,
+		OrderUID:    orderData.OrderUID,
+		TrackNumber: orderData.TrackNumber,
+		CustomerID:  orderData.CustomerID,
+		OccurredAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return false, err
+	}
+	// Для новых заказов дополнительно публикуется компактное событие order.created.
+	if created {
+		err = writeOutbox(ctx, tx, models.OrderEvent{
+			Type:       models.EventOrderCreated,
+			OrderUID:   orderData.OrderUID,
+			CustomerID: orderData.CustomerID,
+			Amount:     orderData.Payment.Amount,
+			OccurredAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	// Сообщаем другим экземплярам сервиса, что их кэш этого заказа устарел.
+	if err = notifyChanged(ctx, tx, orderData.OrderUID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// withQueryTimeout ограничивает контекст операции значением query_timeout.
+// Для SaveOrder и GetOrder ограничение действует на каждую попытку отдельно.
+// Массовые чтения (GetOrders, StreamOrders) его не используют.
+func (s *Storage) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// withoutStatementTimeout выполняет `do` в транзакции только для чтения
+// с отключенным statement_timeout. Используется для массовых чтений,
+// время которых зависит от размера таблицы.
+func (s *Storage) withoutStatementTimeout(ctx context.Context, db *pgxpool.Pool, do func(tx pgx.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("can't start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if _, err = tx.Exec(ctx, "SET LOCAL statement_timeout = 0"); err != nil {
+		return fmt.Errorf("can't disable statement timeout: %w", err)
+	}
+	if err = do(tx); err != nil {
 		return err
 	}
 
+	return tx.Commit(ctx)
+}
+
+// Healthy проверяет доступность основного сервера. Недоступная реплика
+// ошибкой не считается: чтение переключается на основной сервер.
+func (s *Storage) Healthy(ctx context.Context) error {
+	if err := s.db.Ping(ctx); err != nil {
+		return fmt.Errorf("can't ping postgres: %w", err)
+	}
+	return nil
+}
+
+// Close закрывает все соединения пулов.
+func (s *Storage) Close() {
+	s.db.Close()
+	if s.replica != nil {
+		s.replica.db.Close()
+	}
+}
+
+// ConnString возвращает DSN основного сервера с параметрами соединения
+// из конфигурации (sslmode, connect_timeout, search_path).
+func ConnString(cfg config.Postgres) string {
+	return connString(cfg, cfg.Host, cfg.Port)
+}
+
+// connString собирает DSN для сервера `host:port`. Имя пользователя
+// и пароль экранируются, поэтому могут содержать спецсимволы.
+func connString(cfg config.Postgres, host, port string) string {
+	params := url.Values{}
+	params.Set("sslmode", cfg.SSLMode)
+	if cfg.SSLRootCert != "" {
+		params.Set("sslrootcert", cfg.SSLRootCert)
+	}
+	if cfg.ConnectTimeout > 0 {
+		// libpq принимает connect_timeout в целых секундах.
+		params.Set("connect_timeout", strconv.Itoa(max(1, int(cfg.ConnectTimeout.Seconds()))))
+	}
+	if cfg.SearchPath != "" {
+		params.Set("search_path", cfg.SearchPath)
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.Username, cfg.Password),
+		Host:     net.JoinHostPort(host, port),
+		Path:     "/" + cfg.Database,
+		RawQuery: params.Encode(),
+	}
+	return dsn.String()
+}
+
+// newPool создает пул соединений с сервером `host:port` с общими настройками
+// пула и таймаутов из конфигурации.
+func newPool(ctx context.Context, cfg config.Postgres, host, port string) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(connString(cfg, host, port))
+	if err != nil {
+		return nil, fmt.Errorf("can't parse database config: %w", err)
+	}
+
+	// Ограничиваем пул, чтобы под нагрузкой не упираться в max_connections сервера.
+	poolCfg.MaxConns = cfg.Pool.MaxConns
+	poolCfg.MinConns = cfg.Pool.MinConns
+	poolCfg.MaxConnLifetime = cfg.Pool.ConnMaxLifetime
+	poolCfg.MaxConnIdleTime = cfg.Pool.ConnMaxIdleTime
+	// Серверный лимит на выполнение запроса: медленная база не должна
+	// бесконечно держать соединение и воркер процессора.
+	if cfg.StatementTimeout > 0 {
+		poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	db, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to database: %w", err)
+	}
+
+	return db, nil
+}
+
+// saveOrder (unexported) вставляет или обновляет запись в таблице `orders`.
+// Использует `ON CONFLICT DO UPDATE ... WHERE`, поэтому существующая запись
+// обновляется, только если пришедшая версия новее. Возвращает `applied`
+// false, если запись не изменилась (версия устарела), и `created` true,
+// если заказа раньше не было.
+//
+// Ключ заказа в секционированной таблице - (order_uid, date_created):
+// дата создания заказа считается неизменной (см. pinDateCreated).
+func (s *Storage) saveOrder(ctx context.Context, tx pgx.Tx, orderData *models.OrderData) (applied, created bool, err error) {
+	order, err := convertOrder(orderData)
+	if err != nil {
+		return false, false, err
+	}
+
+	// CTE видит таблицу до вставки, поэтому по нему можно понять,
+	// был ли заказ создан или обновлен.
 	query, args, err := s.sq.Insert("orders").
+		Prefix("WITH existing AS (SELECT 1 FROM orders WHERE order_uid = ? AND date_created = ?)",
+			order.OrderUID, order.DateCreated,
+		).
 		Columns(
 			"order_uid", "track_number", "customer_id", "delivery_service", "date_created",
-			"payment_data", "delivery_data", "additional_data",
+			"additional_data", "updated_at",
 		).
 		Values(
 			order.OrderUID, order.TrackNumber, order.CustomerID, order.DeliveryService,
-			order.DateCreated, order.PaymentData, order.DeliveryData, order.AdditionalData,
+			order.DateCreated, order.AdditionalData, order.UpdatedAt,
 		).
-		Suffix("ON CONFLICT (order_uid) DO NOTHING").
+		Suffix(`ON CONFLICT (order_uid, date_created) DO UPDATE SET
+			track_number = EXCLUDED.track_number,
+			customer_id = EXCLUDED.customer_id,
+			delivery_service = EXCLUDED.delivery_service,
+			additional_data = EXCLUDED.additional_data,
+			updated_at = EXCLUDED.updated_at
+		WHERE orders.updated_at < EXCLUDED.updated_at
+		RETURNING NOT EXISTS (SELECT 1 FROM existing)`).
 		ToSql()
 	if err != nil {
-		return fmt.Errorf("failed to build save order query: %v", err)
+		return false, false, fmt.Errorf("failed to build save order query: %w", err)
+	}
+
+	// Если условие WHERE не выполнено, RETURNING не возвращает строк.
+	err = tx.QueryRow(ctx, query, args...).Scan(&created)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to execute save order query: %w", err)
 	}
 
-	_, err = tx.ExecContext(ctx, query, args...)
+	return true, created, nil
+}
+
+// pinDateCreated (unexported) подставляет в заказ дату создания, с которой он
+// уже сохранен. Ключ заказа - (order_uid, date_created), поэтому версия
+// заказа с другой `DateCreated` иначе вставилась бы вторым заказом.
+//
+// Блокировка по order_uid до конца транзакции не дает параллельным
+// транзакциям вставить первую версию заказа с разными датами.
+func (s *Storage) pinDateCreated(ctx context.Context, tx pgx.Tx, orderData *models.OrderData) error {
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", orderData.OrderUID); err != nil {
+		return fmt.Errorf("failed to lock order: %w", err)
+	}
+
+	var dateCreated time.Time
+	err := tx.QueryRow(ctx,
+		"SELECT date_created FROM orders WHERE order_uid = $1 ORDER BY date_created LIMIT 1", orderData.OrderUID,
+	).Scan(&dateCreated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to execute save order query: %v", err)
+		return fmt.Errorf("failed to get order date: %w", err)
+	}
+
+	if !dateCreated.Equal(orderData.DateCreated) {
+		s.log.Warn("order date_created differs from the saved one, keeping the saved date",
+			slog.String("order_uid", orderData.OrderUID),
+			slog.Time("date_created", orderData.DateCreated),
+			slog.Time("saved_date_created", dateCreated),
+		)
+		orderData.DateCreated = dateCreated
 	}
 
 	return nil
 }
 
-// saveItems (unexported) выполняет пакетную вставку товаров заказа в таблицу `order_items`.
-// Использует `NamedExecContext` для удобного маппинга полей структуры на параметры запроса.
-func (s *Storage) saveItems(ctx context.Context, tx *sqlx.Tx, itemsData []models.Item, orderUID string) error {
-	if len(itemsData) == 0 {
+// replaceItems (unexported) заменяет товары заказа: удаляет сохраненные
+// ранее и вставляет новые.
+func (s *Storage) replaceItems(ctx context.Context, tx pgx.Tx, orderData *models.OrderData) error {
+	// Условие по date_created ограничивает удаление одной секцией.
+	query, args, err := s.sq.Delete("order_items").
+		Where(squirrel.Eq{"order_uid": orderData.OrderUID, "date_created": orderData.DateCreated}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete items query: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to execute delete items query: %w", err)
+	}
+
+	return s.saveItems(ctx, tx, orderData)
+}
+
+// saveItems (unexported) выполняет вставку товаров заказа в таблицу `order_items`.
+// Все вставки отправляются на сервер одним пакетом (pgx.Batch) за один round-trip.
+// Товары хранятся в той же секции, что и заказ, поэтому вместе с ними
+// записывается date_created заказа. Товар с уже сохраненным в заказе `rid`
+// пропускается (ON CONFLICT DO NOTHING).
+func (s *Storage) saveItems(ctx context.Context, tx pgx.Tx, orderData *models.OrderData) error {
+	if len(orderData.Items) == 0 {
 		return nil
 	}
 
-	items, err := convertItems(orderUID, itemsData)
+	items, err := convertItems(orderData.OrderUID, orderData.Items)
 	if err != nil {
 		return err
 	}
 
 	query := `
 		INSERT INTO order_items (
-			order_uid, chrt_id, track_number, price, rid, name,
+			order_uid, date_created, chrt_id, track_number, price, rid, name,
 			sale, size, total_price, nm_id, brand, status
-		) VALUES (
-			:order_uid, :chrt_id, :track_number, :price, :rid, :name,
-			:sale, :size, :total_price, :nm_id, :brand, :status
-		)`
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (order_uid, date_created, rid) DO NOTHING`
+
+	batch := &pgx.Batch{}
+	for _, item := range items {
+		batch.Queue(query,
+			item.OrderUID, orderData.DateCreated, item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name,
+			item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status,
+		)
+	}
 
-	_, err = tx.NamedExecContext(ctx, query, items)
-	if err != nil {
-		return fmt.Errorf("failed to execute save items query: %v", err)
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to execute save items query: %w", err)
 	}
 
 	return nil
@@ -177,26 +559,38 @@ func (s *Storage) saveItems(ctx context.Context, tx *sqlx.Tx, itemsData []models
 
 // GetOrder извлекает один заказ вместе со всеми его товарами по `order_uid`.
 // Выполняет JOIN-запрос и затем агрегирует результаты в одну структуру `models.OrderData`.
-func (s *Storage) GetOrder(ctx context.Context, orderUID string) (*models.OrderData, error) {
+//
+// Временные ошибки повторяются так же, как в SaveOrder. Читает с реплики,
+// если она настроена (см. Storage.read).
+func (s *Storage) GetOrder(ctx context.Context, orderUID string) (orderData *models.OrderData, err error) {
 	const fn = "storage.postgres.GetOrder"
+	defer func(start time.Time) { observe("GetOrder", start, err) }(time.Now())
 
-	query, args, err := s.sq.Select(
-		"o.order_uid", "o.track_number", "o.customer_id", "o.delivery_service",
-		"o.date_created", "o.payment_data", "o.delivery_data", "o.additional_data",
-		"i.id", "i.chrt_id", "i.track_number", "i.price", "i.rid", "i.name",
-		"i.sale", "i.size", "i.total_price", "i.nm_id", "i.brand", "i.status",
-	).
-		From("orders o").
-		Join("order_items i ON o.order_uid = i.order_uid").
+	err = s.retry(ctx, fn, func(ctx context.Context) error {
+		orderData, err = s.getOrder(ctx, orderUID)
+		return err
+	})
+	return orderData, err
+}
+
+// getOrder (unexported) выполняет одну попытку чтения заказа.
+func (s *Storage) getOrder(ctx context.Context, orderUID string) (*models.OrderData, error) {
+	const fn = "storage.postgres.GetOrder"
+
+	query, args, err := s.selectOrders().
 		Where(squirrel.Eq{"o.order_uid": orderUID}).
 		ToSql()
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to build get order query: %v", fn, err)
+		return nil, fmt.Errorf("%s: failed to build get order query: %w", fn, err)
 	}
 
 	var joinedRows []JoinedRow
-	if err := s.db.SelectContext(ctx, &joinedRows, query, args...); err != nil {
-		return nil, fmt.Errorf("%s: failed to execute get order query: %v", fn, err)
+	err = s.read(ctx, fn, func(db *pgxpool.Pool) error {
+		joinedRows, err = selectJoined(ctx, db, query, args...)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to execute get order query: %w", fn, err)
 	}
 
 	if len(joinedRows) == 0 {
@@ -207,7 +601,7 @@ func (s *Storage) GetOrder(ctx context.Context, orderUID string) (*models.OrderD
 	firstRow := joinedRows[0]
 	orderData, err := fillOrderData(firstRow)
 	if err != nil {
-		return nil, fmt.Errorf("%s: can't fill order data: %v", fn, err)
+		return nil, fmt.Errorf("%s: can't fill order data: %w", fn, err)
 	}
 
 	// Добавляем все товары из всех полученных строк.
@@ -218,27 +612,49 @@ func (s *Storage) GetOrder(ctx context.Context, orderUID string) (*models.OrderD
 	return orderData, nil
 }
 
-// GetOrders извлекает все заказы из базы данных.
-// Используется для первоначального заполнения кэша при старте сервиса.
-func (s *Storage) GetOrders(ctx context.Context) ([]*models.OrderData, error) {
+// OrderExists проверяет, есть ли заказ с `order_uid` в базе, не загружая
+// его товары, доставку и оплату. Временные ошибки повторяются так же,
+// как в GetOrder. Читает с реплики, если она настроена.
+func (s *Storage) OrderExists(ctx context.Context, orderUID string) (exists bool, err error) {
+	const fn = "storage.postgres.OrderExists"
+	defer func(start time.Time) { observe("OrderExists", start, err) }(time.Now())
+
+	err = s.retry(ctx, fn, func(ctx context.Context) error {
+		return s.read(ctx, fn, func(db *pgxpool.Pool) error {
+			return db.QueryRow(ctx,
+				"SELECT EXISTS (SELECT 1 FROM orders WHERE order_uid = $1)", orderUID,
+			).Scan(&exists)
+		})
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return exists, nil
+}
+
+// GetOrders извлекает все заказы из базы данных в память.
+// Для больших таблиц следует использовать StreamOrders. Читает с реплики,
+// если она настроена.
+func (s *Storage) GetOrders(ctx context.Context) (_ []*models.OrderData, err error) {
 	const fn = "storage.postgres.GetOrders"
+	defer func(start time.Time) { observe("GetOrders", start, err) }(time.Now())
 
-	query, args, err := s.sq.Select(
-		"o.order_uid", "o.track_number", "o.customer_id", "o.delivery_service",
-		"o.date_created", "o.payment_data", "o.delivery_data", "o.additional_data",
-		"i.id", "i.chrt_id", "i.track_number", "i.price", "i.rid", "i.name",
-		"i.sale", "i.size", "i.total_price", "i.nm_id", "i.brand", "i.status",
-	).
-		From("orders o").
-		Join("order_items i ON o.order_uid = i.order_uid").
+	query, args, err := s.selectOrders().
 		ToSql()
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to build get orders query: %v", fn, err)
+		return nil, fmt.Errorf("%s: failed to build get orders query: %w", fn, err)
 	}
 
 	var joinedRows []JoinedRow
-	if err := s.db.SelectContext(ctx, &joinedRows, query, args...); err != nil {
-		return nil, fmt.Errorf("%s: failed to execute get orders query: %v", fn, err)
+	err = s.read(ctx, fn, func(db *pgxpool.Pool) error {
+		return s.withoutStatementTimeout(ctx, db, func(tx pgx.Tx) error {
+			joinedRows, err = selectJoined(ctx, tx, query, args...)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to execute get orders query: %w", fn, err)
 	}
 
 	if len(joinedRows) == 0 {
@@ -252,7 +668,7 @@ func (s *Storage) GetOrders(ctx context.Context) ([]*models.OrderData, error) {
 		if !exists {
 			orderData, err = fillOrderData(row)
 			if err != nil {
-				return nil, fmt.Errorf("%s: can't fill order data: %v", fn, err)
+				return nil, fmt.Errorf("%s: can't fill order data: %w", fn, err)
 			}
 			ordersMap[row.OrderDB.OrderUID] = orderData
 		}
@@ -268,6 +684,205 @@ func (s *Storage) GetOrders(ctx context.Context) ([]*models.OrderData, error) {
 	return orders, nil
 }
 
+// GetOrdersSince возвращает заказы, созданные или измененные после `since`
+// (по `updated_at`). Используется для догрузки изменений в кэш вместо чтения
+// всей таблицы. Пустой результат ошибкой не считается.
+func (s *Storage) GetOrdersSince(ctx context.Context, since time.Time) (_ []*models.OrderData, err error) {
+	const fn = "storage.postgres.GetOrdersSince"
+	defer func(start time.Time) { observe("GetOrdersSince", start, err) }(time.Now())
+
+	query, args, err := s.selectOrders().
+		Where(squirrel.Gt{"o.updated_at": since}).
+		OrderBy("o.updated_at", "o.order_uid", "i.id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to build get orders since query: %w", fn, err)
+	}
+
+	var orders []*models.OrderData
+	err = s.read(ctx, fn, func(db *pgxpool.Pool) error {
+		orders = nil
+		return s.withoutStatementTimeout(ctx, db, func(tx pgx.Tx) error {
+			return streamRows(ctx, tx, query, args, func(order *models.OrderData) error {
+				orders = append(orders, order)
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return orders, nil
+}
+
+// StreamOrders читает все заказы из базы данных построчно и передает
+// их по одному в `handle`, не загружая всю таблицу в память.
+// Время чтения зависит от размера таблицы, поэтому statement_timeout
+// на этот запрос не действует.
+//
+// Строки JOIN-запроса упорядочены по `order_uid`, поэтому товары одного
+// заказа идут подряд: заказ передается в `handle`, как только начинается
+// следующий. Если `handle` возвращает ошибку, чтение прекращается.
+func (s *Storage) StreamOrders(ctx context.Context, handle func(*models.OrderData) error) error {
+	const fn = "storage.postgres.StreamOrders"
+
+	query, args, err := s.selectOrders().
+		OrderBy("o.order_uid", "i.id").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: failed to build stream orders query: %w", fn, err)
+	}
+
+	return s.withoutStatementTimeout(ctx, s.db, func(tx pgx.Tx) error {
+		return streamRows(ctx, tx, query, args, handle)
+	})
+}
+
+// StreamOrdersAfter работает как StreamOrders, но читает не больше `limit`
+// заказов с `order_uid` больше `afterUID` (пустая строка - с начала).
+// Позволяет читать таблицу по частям и продолжать чтение с места остановки.
+func (s *Storage) StreamOrdersAfter(ctx context.Context, afterUID string, limit int, handle func(*models.OrderData) error) error {
+	const fn = "storage.postgres.StreamOrdersAfter"
+
+	chunk := s.sq.Select("order_uid", "date_created").
+		From("orders").
+		Where(squirrel.Gt{"order_uid": afterUID}).
+		OrderBy("order_uid").
+		Limit(uint64(limit)).
+		PlaceholderFormat(squirrel.Question)
+
+	query, args, err := s.selectOrders().
+		Where(squirrel.Expr("(o.order_uid, o.date_created) IN (?)", chunk)).
+		OrderBy("o.order_uid", "i.id").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: failed to build stream orders query: %w", fn, err)
+	}
+
+	return s.withoutStatementTimeout(ctx, s.db, func(tx pgx.Tx) error {
+		return streamRows(ctx, tx, query, args, handle)
+	})
+}
+
+// StreamRecentOrders работает как StreamOrders, но читает только последние
+// заказы по `date_created`: не больше `limit` (0 - без ограничения) и не
+// старше `since` (нулевое время - без ограничения). Заказы передаются
+// в `handle` от новых к старым.
+func (s *Storage) StreamRecentOrders(ctx context.Context, limit int, since time.Time, handle func(*models.OrderData) error) error {
+	const fn = "storage.postgres.StreamRecentOrders"
+
+	recent := s.sq.Select("order_uid", "date_created").
+		From("orders").
+		OrderBy("date_created DESC").
+		PlaceholderFormat(squirrel.Question)
+	if limit > 0 {
+		recent = recent.Limit(uint64(limit))
+	}
+	if !since.IsZero() {
+		recent = recent.Where(squirrel.GtOrEq{"date_created": since})
+	}
+
+	query, args, err := s.selectOrders().
+		Where(squirrel.Expr("(o.order_uid, o.date_created) IN (?)", recent)).
+		OrderBy("o.date_created DESC", "o.order_uid", "i.id").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("%s: failed to build stream orders query: %w", fn, err)
+	}
+
+	return s.withoutStatementTimeout(ctx, s.db, func(tx pgx.Tx) error {
+		return streamRows(ctx, tx, query, args, handle)
+	})
+}
+
+// streamRows выполняет запрос StreamOrders и группирует строки в заказы.
+func streamRows(ctx context.Context, q querier, query string, args []any, handle func(*models.OrderData) error) error {
+	const fn = "storage.postgres.StreamOrders"
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: failed to execute stream orders query: %w", fn, err)
+	}
+	defer rows.Close()
+
+	var current *models.OrderData
+	for rows.Next() {
+		row, err := scanJoinedRow(rows)
+		if err != nil {
+			return fmt.Errorf("%s: can't scan row: %w", fn, err)
+		}
+
+		if current == nil || current.OrderUID != row.OrderDB.OrderUID {
+			if current != nil {
+				if err := handle(current); err != nil {
+					return err
+				}
+			}
+			if current, err = fillOrderData(row); err != nil {
+				return fmt.Errorf("%s: can't fill order data: %w", fn, err)
+			}
+		}
+		appendItems(row, current)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: can't read rows: %w", fn, err)
+	}
+
+	if current != nil {
+		return handle(current)
+	}
+	return nil
+}
+
+// selectOrders возвращает запрос заказов со всеми связанными таблицами:
+// по строке на каждый товар заказа. Таблица `orders` доступна под алиасом `o`.
+// Порядок колонок должен совпадать с scanJoinedRow.
+func (s *Storage) selectOrders() squirrel.SelectBuilder {
+	return s.sq.Select(
+		"o.order_uid", "o.track_number", "o.customer_id", "o.delivery_service",
+		"o.date_created", "o.status", "o.additional_data",
+		"d.name", "d.phone", "d.zip", "d.city", "d.address", "d.region", "d.email",
+		"p.transaction", "p.request_id", "p.currency", "p.provider", "p.amount", "p.payment_dt",
+		"p.bank", "p.delivery_cost", "p.goods_total", "p.custom_fee",
+		"i.id", "i.chrt_id", "i.track_number", "i.price", "i.rid", "i.name",
+		"i.sale", "i.size", "i.total_price", "i.nm_id", "i.brand", "i.status",
+	).
+		From("orders o").
+		Join("deliveries d ON d.order_uid = o.order_uid").
+		Join("payments p ON p.order_uid = o.order_uid").
+		Join("order_items i ON i.order_uid = o.order_uid AND i.date_created = o.date_created")
+}
+
+// querier - общий интерфейс пула и транзакции для выполнения запросов.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// selectJoined выполняет запрос, построенный selectOrders, и читает все строки.
+func selectJoined(ctx context.Context, q querier, query string, args ...any) ([]JoinedRow, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanJoinedRow)
+}
+
+// scanJoinedRow читает одну строку JOIN-запроса заказа и его товара.
+func scanJoinedRow(row pgx.CollectableRow) (JoinedRow, error) {
+	var r JoinedRow
+	err := row.Scan(
+		&r.OrderDB.OrderUID, &r.OrderDB.TrackNumber, &r.CustomerID, &r.DeliveryService,
+		&r.DateCreated, &r.OrderDB.Status, &r.AdditionalData,
+		&r.DeliveryDB.Name, &r.Phone, &r.Zip, &r.City, &r.Address, &r.Region, &r.Email,
+		&r.Transaction, &r.RequestID, &r.Currency, &r.Provider, &r.Amount, &r.PaymentDT,
+		&r.Bank, &r.DeliveryCost, &r.GoodsTotal, &r.CustomFee,
+		&r.ID, &r.ChrtID, &r.ItemDB.TrackNumber, &r.Price, &r.Rid, &r.ItemDB.Name,
+		&r.Sale, &r.Size, &r.TotalPrice, &r.NmID, &r.Brand, &r.ItemDB.Status,
+	)
+	return r, err
+}
+
 // convertOrder преобразует модель `models.OrderData` в `OrderDB` для сохранения в БД.
 func convertOrder(orderData *models.OrderData) (*OrderDB, error) {
 	order := &OrderDB{
@@ -276,17 +891,16 @@ func convertOrder(orderData *models.OrderData) (*OrderDB, error) {
 		CustomerID:      orderData.CustomerID,
 		DeliveryService: orderData.DeliveryService,
 		DateCreated:     orderData.DateCreated,
+		UpdatedAt:       orderData.UpdatedAt,
+	}
+	// Если время изменения не задано, считаем версию актуальной на момент записи.
+	if order.UpdatedAt.IsZero() {
+		order.UpdatedAt = time.Now()
 	}
 
 	var err error
-	if order.PaymentData, err = json.Marshal(orderData.Payment); err != nil {
-		return nil, fmt.Errorf("can't marshal payment: %v", err)
-	}
-	if order.DeliveryData, err = json.Marshal(orderData.Delivery); err != nil {
-		return nil, fmt.Errorf("can't marshal delivery: %v", err)
-	}
 	if order.AdditionalData, err = json.Marshal(orderData.AdditionalData); err != nil {
-		return nil, fmt.Errorf("can't marshal additional data: %v", err)
+		return nil, fmt.Errorf("can't marshal additional data: %w", err)
 	}
 
 	return order, nil
@@ -322,17 +936,14 @@ func fillOrderData(row JoinedRow) (*models.OrderData, error) {
 		CustomerID:      row.OrderDB.CustomerID,
 		DeliveryService: row.OrderDB.DeliveryService,
 		DateCreated:     row.OrderDB.DateCreated,
+		Status:          models.OrderStatus(row.OrderDB.Status),
 		Items:           make([]models.Item, 0),
+		Delivery:        row.DeliveryDB.model(),
+		Payment:         row.PaymentDB.model(),
 	}
 
-	if err := json.Unmarshal(row.PaymentData, &orderData.Payment); err != nil {
-		return nil, fmt.Errorf("can't unmarshal payment data: %v", err)
-	}
-	if err := json.Unmarshal(row.DeliveryData, &orderData.Delivery); err != nil {
-		return nil, fmt.Errorf("can't unmarshal delivery data: %v", err)
-	}
 	if err := json.Unmarshal(row.AdditionalData, &orderData.AdditionalData); err != nil {
-		return nil, fmt.Errorf("can't unmarshal additional data: %v", err)
+		return nil, fmt.Errorf("can't unmarshal additional data: %w", err)
 	}
 
 	return orderData, nil
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeTx - транзакция, в которой заказ уже сохранен с датой `saved`
+// (nil - заказа нет). Остальные методы pgx.Tx не реализованы.
+type fakeTx struct {
+	pgx.Tx
+	saved   *time.Time
+	queries []string
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	tx.queries = append(tx.queries, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (tx *fakeTx) QueryRow(_ context.Context, sql string, _ ...any) pgx.Row {
+	tx.queries = append(tx.queries, sql)
+	return fakeRow{tx.saved}
+}
+
+// fakeRow возвращает дату создания заказа или pgx.ErrNoRows.
+type fakeRow struct {
+	dateCreated *time.Time
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.dateCreated == nil {
+		return pgx.ErrNoRows
+	}
+	*dest[0].(*time.Time) = *r.dateCreated
+	return nil
+}
+
+// TestPinDateCreated проверяет, что версия заказа с другой датой создания
+// сохраняется с уже сохраненной датой, а не вставляется вторым заказом.
+func TestPinDateCreated(t *testing.T) {
+	incoming := time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC)
+	earlier := incoming.Add(-48 * time.Hour)
+	sameInOtherZone := incoming.In(time.FixedZone("MSK", 3*60*60))
+
+	tests := []struct {
+		name  string
+		saved *time.Time
+		want  time.Time
+	}{
+		{name: "new order", saved: nil, want: incoming},
+		{name: "different date", saved: &earlier, want: earlier},
+		{name: "same date", saved: &sameInOtherZone, want: incoming},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Storage{log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+			tx := &fakeTx{saved: tt.saved}
+			order := &models.OrderData{OrderUID: "b563feb7b2b84b6test", DateCreated: incoming}
+
+			if err := s.pinDateCreated(context.Background(), tx, order); err != nil {
+				t.Fatalf("pinDateCreated() error = %v", err)
+			}
+
+			if !order.DateCreated.Equal(tt.want) {
+				t.Errorf("DateCreated = %s, want %s", order.DateCreated, tt.want)
+			}
+			if tt.want.Equal(incoming) && order.DateCreated != incoming {
+				t.Errorf("DateCreated = %s, want it unchanged", order.DateCreated)
+			}
+			// Без блокировки до чтения параллельная транзакция могла бы
+			// вставить заказ между чтением и вставкой.
+			if len(tx.queries) != 2 || !strings.Contains(tx.queries[0], "pg_advisory_xact_lock") {
+				t.Errorf("queries = %q, want the order lock before the lookup", tx.queries)
+			}
+		})
+	}
+}
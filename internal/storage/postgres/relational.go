@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// DeliveryDB представляет структуру таблицы `deliveries` в базе данных.
+// На каждый заказ приходится ровно одна строка.
+type DeliveryDB struct {
+	Name    string `db:"name"`
+	Phone   string `db:"phone"`
+	Zip     string `db:"zip"`
+	City    string `db:"city"`
+	Address string `db:"address"`
+	Region  string `db:"region"`
+	Email   string `db:"email"`
+}
+
+// PaymentDB представляет структуру таблицы `payments` в базе данных.
+// На каждый заказ приходится ровно одна строка.
+type PaymentDB struct {
+	Transaction  string `db:"transaction"`
+	RequestID    string `db:"request_id"`
+	Currency     string `db:"currency"`
+	Provider     string `db:"provider"`
+	Amount       int    `db:"amount"`
+	PaymentDT    int64  `db:"payment_dt"`
+	Bank         string `db:"bank"`
+	DeliveryCost int    `db:"delivery_cost"`
+	GoodsTotal   int    `db:"goods_total"`
+	CustomFee    int    `db:"custom_fee"`
+}
+
+// model преобразует строку `deliveries` в модель приложения.
+func (d DeliveryDB) model() models.Delivery {
+	return models.Delivery{
+		Name:    d.Name,
+		Phone:   d.Phone,
+		Zip:     d.Zip,
+		City:    d.City,
+		Address: d.Address,
+		Region:  d.Region,
+		Email:   d.Email,
+	}
+}
+
+// model преобразует строку `payments` в модель приложения.
+func (p PaymentDB) model() models.Payment {
+	return models.Payment{
+		Transaction:  p.Transaction,
+		RequestID:    p.RequestID,
+		Currency:     p.Currency,
+		Provider:     p.Provider,
+		Amount:       p.Amount,
+		PaymentDT:    int(p.PaymentDT),
+		Bank:         p.Bank,
+		DeliveryCost: p.DeliveryCost,
+		GoodsTotal:   p.GoodsTotal,
+		CustomFee:    p.CustomFee,
+	}
+}
+
+// saveDelivery вставляет или обновляет данные о доставке заказа в рамках транзакции.
+func (s *Storage) saveDelivery(ctx context.Context, tx pgx.Tx, orderUID string, d models.Delivery) error {
+	query, args, err := s.sq.Insert("deliveries").
+		Columns("order_uid", "name", "phone", "zip", "city", "address", "region", "email").
+		Values(orderUID, d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email).
+		Suffix(`ON CONFLICT (order_uid) DO UPDATE SET
+			name = EXCLUDED.name,
+			phone = EXCLUDED.phone,
+			zip = EXCLUDED.zip,
+			city = EXCLUDED.city,
+			address = EXCLUDED.address,
+			region = EXCLUDED.region,
+			email = EXCLUDED.email`).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("can't build delivery query: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("can't save delivery: %w", err)
+	}
+
+	return nil
+}
+
+// savePayment вставляет или обновляет данные об оплате заказа в рамках транзакции.
+func (s *Storage) savePayment(ctx context.Context, tx pgx.Tx, orderUID string, p models.Payment) error {
+	query, args, err := s.sq.Insert("payments").
+		Columns(
+			"order_uid", "transaction", "request_id", "currency", "provider", "amount",
+			"payment_dt", "bank", "delivery_cost", "goods_total", "custom_fee",
+		).
+		Values(
+			orderUID, p.Transaction, p.RequestID, p.Currency, p.Provider, p.Amount,
+			p.PaymentDT, p.Bank, p.DeliveryCost, p.GoodsTotal, p.CustomFee,
+		).
+		Suffix(`ON CONFLICT (order_uid) DO UPDATE SET
+			transaction = EXCLUDED.transaction,
+			request_id = EXCLUDED.request_id,
+			currency = EXCLUDED.currency,
+			provider = EXCLUDED.provider,
+			amount = EXCLUDED.amount,
+			payment_dt = EXCLUDED.payment_dt,
+			bank = EXCLUDED.bank,
+			delivery_cost = EXCLUDED.delivery_cost,
+			goods_total = EXCLUDED.goods_total,
+			custom_fee = EXCLUDED.custom_fee`).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("can't build payment query: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("can't save payment: %w", err)
+	}
+
+	return nil
+}
+
+// writeJSONBDeliveryPayment продолжает заполнять колонки `orders.delivery_data`
+// и `orders.payment_data`, которые читает версия сервиса до миграции 4.
+func writeJSONBDeliveryPayment(ctx context.Context, tx pgx.Tx, orderData *models.OrderData) error {
+	delivery, err := json.Marshal(orderData.Delivery)
+	if err != nil {
+		return fmt.Errorf("can't marshal delivery: %w", err)
+	}
+	payment, err := json.Marshal(orderData.Payment)
+	if err != nil {
+		return fmt.Errorf("can't marshal payment: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		"UPDATE orders SET delivery_data = $1, payment_data = $2 WHERE order_uid = $3",
+		delivery, payment, orderData.OrderUID,
+	)
+	if err != nil {
+		return fmt.Errorf("can't write legacy delivery/payment: %w", err)
+	}
+
+	return nil
+}
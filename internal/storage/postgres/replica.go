@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// replicaDownInterval - время, на которое чтение переключается на основной
+// сервер после ошибки соединения с репликой.
+const replicaDownInterval = 30 * time.Second
+
+// replica - пул соединений с репликой для чтения и признак ее доступности.
+type replica struct {
+	db        *pgxpool.Pool
+	downUntil atomic.Int64 // Unix-время в наносекундах, до которого реплика считается недоступной.
+}
+
+// newReplica создает пул соединений с репликой, если она настроена.
+// Недоступная при старте реплика не считается ошибкой: чтение идет
+// с основного сервера, пока реплика не поднимется.
+func newReplica(ctx context.Context, cfg config.Postgres, log *slog.Logger) (*replica, error) {
+	if cfg.Replica.Host == "" {
+		return nil, nil
+	}
+
+	db, err := newPool(ctx, cfg, cfg.Replica.Host, cfg.Replica.Port)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &replica{db: db}
+	if err := db.Ping(ctx); err != nil {
+		log.Warn("read replica is unavailable, reading from primary",
+			slog.String("host", cfg.Replica.Host),
+			sl.Err(err),
+		)
+		r.markDown()
+	}
+
+	return r, nil
+}
+
+// up сообщает, можно ли сейчас читать с реплики.
+func (r *replica) up() bool {
+	return time.Now().UnixNano() >= r.downUntil.Load()
+}
+
+// markDown переключает чтение на основной сервер на replicaDownInterval.
+func (r *replica) markDown() {
+	r.downUntil.Store(time.Now().Add(replicaDownInterval).UnixNano())
+}
+
+// read выполняет запрос на чтение `do` на реплике, если она настроена и доступна,
+// иначе на основном сервере. Если реплика не отвечает, запрос повторяется
+// на основном сервере, а реплика на время исключается из чтения.
+func (s *Storage) read(ctx context.Context, fn string, do func(db *pgxpool.Pool) error) error {
+	if s.replica == nil || !s.replica.up() {
+		return do(s.db)
+	}
+
+	err := do(s.replica.db)
+	if err == nil || !isTransient(err) || ctx.Err() != nil {
+		return err
+	}
+
+	s.log.Warn("read replica failed, falling back to primary",
+		slog.String("fn", fn),
+		slog.Duration("for", replicaDownInterval),
+		sl.Err(err),
+	)
+	s.replica.markDown()
+
+	return do(s.db)
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Коды SQLSTATE, после которых операцию имеет смысл повторить.
+const (
+	sqlstateSerializationFailure = "40001" // serialization_failure
+	sqlstateDeadlockDetected     = "40P01" // deadlock_detected
+	sqlstateAdminShutdown        = "57P01" // admin_shutdown
+	sqlstateCannotConnectNow     = "57P03" // cannot_connect_now
+	sqlstateClassConnection      = "08"    // Класс connection_exception.
+)
+
+// retryPolicy описывает повторы операций при временных ошибках.
+type retryPolicy struct {
+	attempts       int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// newRetryPolicy создает политику повторов по конфигурации.
+func newRetryPolicy(cfg config.Retry) retryPolicy {
+	p := retryPolicy{
+		attempts:       cfg.Attempts,
+		initialBackoff: cfg.InitialBackoff,
+		maxBackoff:     cfg.MaxBackoff,
+	}
+	if p.attempts < 1 {
+		p.attempts = 1
+	}
+	return p
+}
+
+// backoff возвращает паузу перед попыткой с номером `attempt` (начиная с 1).
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	d := p.initialBackoff
+	for i := 1; i < attempt && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, p.maxBackoff)
+}
+
+// retry выполняет `op`, повторяя ее при временных ошибках. Каждая попытка
+// получает собственный query_timeout. Если все попытки завершились временной
+// ошибкой, возвращается ошибка, оборачивающая storage.ErrRetryExhausted.
+// Остальные ошибки возвращаются сразу, без повторов.
+func (s *Storage) retry(ctx context.Context, fn string, op func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; attempt <= s.retryPolicy.attempts; attempt++ {
+		if attempt > 1 {
+			wait := s.retryPolicy.backoff(attempt - 1)
+			s.log.Warn("retrying database operation",
+				slog.String("fn", fn),
+				slog.Int("attempt", attempt),
+				slog.Duration("backoff", wait),
+				sl.Err(err),
+			)
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+
+		err = func() error {
+			ctx, cancel := s.withQueryTimeout(ctx)
+			defer cancel()
+			return op(ctx)
+		}()
+		if err == nil || !isTransient(err) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("%w after %d attempts: %v", storage.ErrRetryExhausted, s.retryPolicy.attempts, err)
+}
+
+// isTransient сообщает, вызвана ли ошибка временной проблемой: конфликтом
+// транзакций, перезапуском сервера или обрывом соединения.
+func isTransient(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case sqlstateSerializationFailure, sqlstateDeadlockDetected,
+			sqlstateAdminShutdown, sqlstateCannotConnectNow:
+			return true
+		}
+		return len(pgErr.Code) == 5 && pgErr.Code[:2] == sqlstateClassConnection
+	}
+
+	// Таймауты не повторяем: медленный запрос, скорее всего, будет
+	// медленным и при следующей попытке.
+	if pgconn.Timeout(err) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && !netErr.Timeout()
+}
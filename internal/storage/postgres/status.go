@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/jackc/pgx/v5"
+)
+
+// UpdateStatus переводит заказ в статус `to` и возвращает предыдущий статус.
+//
+// Текущий статус читается с блокировкой строки (SELECT ... FOR UPDATE),
+// поэтому одновременные изменения статуса одного заказа не теряются.
+// Возвращает storage.ErrNoOrder, если заказа нет, и storage.ErrInvalidTransition,
+// если переход из текущего статуса в `to` не разрешен.
+func (s *Storage) UpdateStatus(ctx context.Context, orderUID string, to models.OrderStatus) (from models.OrderStatus, err error) {
+	const fn = "storage.postgres.UpdateStatus"
+	defer func(start time.Time) { observe("UpdateStatus", start, err) }(time.Now())
+
+	err = s.retry(ctx, fn, func(ctx context.Context) error {
+		from, err = s.updateStatusTx(ctx, orderUID, to)
+		return err
+	})
+	return from, err
+}
+
+// updateStatusTx (unexported) выполняет одну попытку смены статуса в транзакции.
+func (s *Storage) updateStatusTx(ctx context.Context, orderUID string, to models.OrderStatus) (from models.OrderStatus, err error) {
+	const fn = "storage.postgres.UpdateStatus"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%s: can't start transaction: %w", fn, err)
+	}
+	defer func() {
+		if err != nil {
+			if txErr := tx.Rollback(ctx); txErr != nil {
+				s.log.Error("can't rollback transaction", slog.String("fn", fn), sl.Err(txErr))
+			}
+		}
+	}()
+
+	if err = setActor(ctx, tx); err != nil {
+		return "", fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var current string
+	err = tx.QueryRow(ctx, "SELECT status FROM orders WHERE order_uid = $1 FOR UPDATE", orderUID).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", storage.ErrNoOrder
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: can't get order status: %w", fn, err)
+	}
+
+	from = models.OrderStatus(current)
+	if !from.CanTransitionTo(to) {
+		return from, fmt.Errorf("%w: %s -> %s", storage.ErrInvalidTransition, from, to)
+	}
+
+	if _, err = tx.Exec(ctx, "UPDATE orders SET status = $1 WHERE order_uid = $2", string(to), orderUID); err != nil {
+		return from, fmt.Errorf("%s: can't update order status: %w", fn, err)
+	}
+	if err = notifyChanged(ctx, tx, orderUID); err != nil {
+		return from, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return from, tx.Commit(ctx)
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/YusovID/order-service/internal/storage"
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/jackc/pgx/v5"
+)
+
+// UpdateOrder заменяет данные существующего заказа по тем же правилам, что
+// и SaveOrder, но не создает заказ: если его нет, возвращает storage.ErrNoOrder.
+//
+// Дата создания заказа неизменна и входит в ключ секционированной таблицы,
+// поэтому берется из сохраненного заказа. Для устаревших версий возвращается
+// ошибка, оборачивающая storage.ErrDuplicateOrder.
+func (s *Storage) UpdateOrder(ctx context.Context, orderData *models.OrderData) (err error) {
+	const fn = "storage.postgres.UpdateOrder"
+	defer func(start time.Time) { observe("UpdateOrder", start, err) }(time.Now())
+
+	return s.retry(ctx, fn, func(ctx context.Context) error {
+		return s.updateOrderTx(ctx, orderData)
+	})
+}
+
+// updateOrderTx (unexported) выполняет одну попытку изменения заказа в транзакции.
+func (s *Storage) updateOrderTx(ctx context.Context, orderData *models.OrderData) (err error) {
+	const fn = "storage.postgres.UpdateOrder"
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: can't start transaction: %w", fn, err)
+	}
+	defer func() {
+		if err != nil {
+			if txErr := tx.Rollback(ctx); txErr != nil {
+				s.log.Error("can't rollback transaction", slog.String("fn", fn), sl.Err(txErr))
+			}
+		}
+	}()
+
+	if err = setActor(ctx, tx); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var dateCreated time.Time
+	err = tx.QueryRow(ctx,
+		"SELECT date_created FROM orders WHERE order_uid = $1 FOR UPDATE", orderData.OrderUID,
+	).Scan(&dateCreated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ErrNoOrder
+	}
+	if err != nil {
+		return fmt.Errorf("%s: can't get order: %w", fn, err)
+	}
+
+	update := *orderData
+	update.DateCreated = dateCreated
+
+	applied, err := s.writeOrder(ctx, tx, &update)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if !applied {
+		return fmt.Errorf("%s: %w", fn, storage.ErrDuplicateOrder)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// CancelOrder отменяет заказ и возвращает его предыдущий статус. Возвращает
+// storage.ErrNoOrder, если заказа нет, и storage.ErrInvalidTransition, если
+// заказ уже нельзя отменить (например, он доставлен).
+func (s *Storage) CancelOrder(ctx context.Context, orderUID string) (models.OrderStatus, error) {
+	return s.UpdateStatus(ctx, orderUID, models.StatusCancelled)
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// attemptsTTL - срок хранения счетчика попыток обработки сообщения.
+// Счетчик удаляется после успешной обработки, поэтому срок нужен только
+// для сообщений, попыток обработки которых больше не будет.
+const attemptsTTL = 24 * time.Hour
+
+// attemptsKey (unexported) возвращает ключ счетчика попыток обработки
+// сообщения: `<namespace>:attempts:<topic>:<partition>:<offset>`.
+func (c *Client) attemptsKey(topic string, partition int32, offset int64) string {
+	return c.prefix + "attempts:" + topic + ":" + strconv.Itoa(int(partition)) + ":" + strconv.FormatInt(offset, 10)
+}
+
+// StartAttempt увеличивает счетчик попыток обработки сообщения и возвращает
+// номер текущей попытки. Счетчик не сбрасывается, если обработка прервалась
+// падением сервиса, поэтому по нему видно сообщения, которые его роняют.
+func (c *Client) StartAttempt(ctx context.Context, topic string, partition int32, offset int64) (int64, error) {
+	const fn = "storage.redis.StartAttempt"
+
+	key := c.attemptsKey(topic, partition, offset)
+
+	var incr *redis.IntCmd
+	err := c.call(ctx, func() error {
+		_, err := c.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			incr = pipe.Incr(ctx, key)
+			pipe.Expire(ctx, key, attemptsTTL)
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: can't count attempt: %w", fn, err)
+	}
+
+	return incr.Val(), nil
+}
+
+// ClearAttempts удаляет счетчик попыток обработки сообщения.
+func (c *Client) ClearAttempts(ctx context.Context, topic string, partition int32, offset int64) error {
+	const fn = "storage.redis.ClearAttempts"
+
+	err := c.call(ctx, func() error {
+		return c.Del(ctx, c.attemptsKey(topic, partition, offset)).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("%s: can't clear attempts: %w", fn, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Параметры автомата защиты (circuit breaker) от недоступности Redis.
+const (
+	// breakerThreshold - количество сбоев подряд, после которого Redis
+	// считается недоступным и запросы к нему не выполняются.
+	breakerThreshold = 5
+	// breakerCooldown - время, в течение которого запросы к недоступному Redis
+	// не выполняются. По его истечении один запрос пропускается как проба:
+	// если он успешен, автомат закрывается, иначе снова размыкается.
+	breakerCooldown = 10 * time.Second
+)
+
+// breaker отслеживает сбои соединения с Redis. Пока автомат разомкнут,
+// методы кэша сразу возвращают storage.ErrCacheUnavailable, и вызывающий
+// код идет в основное хранилище, не дожидаясь таймаута соединения.
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow сообщает, можно ли выполнить запрос к Redis.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerThreshold {
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+	// Пропускаем один пробный запрос.
+	b.probing = true
+	return true
+}
+
+// record учитывает результат запроса к Redis.
+func (b *breaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if ok {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= breakerThreshold {
+		b.openUntil = time.Now().Add(breakerCooldown)
+	}
+}
+
+// isOutage сообщает, что ошибка вызвана недоступностью Redis, а не ответом
+// сервера (например, отсутствием ключа) или отменой запроса вызывающим кодом.
+func isOutage(ctx context.Context, err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) || ctx.Err() != nil {
+		return false
+	}
+
+	var replyErr redis.Error
+	return !errors.As(err, &replyErr)
+}
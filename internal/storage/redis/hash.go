@@ -0,0 +1,86 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// Поля хеша заказа. Каждая часть заказа хранится в своем поле в JSON,
+// поэтому ее можно прочитать отдельно (HGET), не разбирая весь заказ.
+const (
+	fieldMeta     = "meta"     // Заказ без доставки, оплаты и товаров.
+	fieldDelivery = "delivery" // models.Delivery.
+	fieldPayment  = "payment"  // models.Payment.
+	fieldItems    = "items"    // []models.Item.
+
+	// fieldMissing - единственное поле хеша, если кэш помнит, что заказа
+	// нет в основном хранилище (см. SaveMissing).
+	fieldMissing = "missing"
+)
+
+// orderFields (unexported) разбивает заказ на поля хеша.
+func orderFields(order *models.OrderData) (map[string]any, error) {
+	meta := *order
+	meta.Items = nil
+	meta.Delivery = models.Delivery{}
+	meta.Payment = models.Payment{}
+
+	fields := make(map[string]any, 4)
+	for field, value := range map[string]any{
+		fieldMeta:     meta,
+		fieldDelivery: order.Delivery,
+		fieldPayment:  order.Payment,
+		fieldItems:    order.Items,
+	} {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("can't marshal %s: %v", field, err)
+		}
+		fields[field] = data
+	}
+
+	return fields, nil
+}
+
+// orderFromFields (unexported) собирает заказ из полей хеша.
+func orderFromFields(fields map[string]string) (*models.OrderData, error) {
+	order := &models.OrderData{}
+	if err := json.Unmarshal([]byte(fields[fieldMeta]), order); err != nil {
+		return nil, fmt.Errorf("can't unmarshal %s: %v", fieldMeta, err)
+	}
+
+	for field, dst := range map[string]any{
+		fieldDelivery: &order.Delivery,
+		fieldPayment:  &order.Payment,
+		fieldItems:    &order.Items,
+	} {
+		if err := json.Unmarshal([]byte(fields[field]), dst); err != nil {
+			return nil, fmt.Errorf("can't unmarshal %s: %v", field, err)
+		}
+	}
+
+	return order, nil
+}
+
+// queueSetOrder (unexported) добавляет в `pipe` команды записи заказа:
+// ключ перезаписывается целиком (в том числе отметка об отсутствии заказа
+// или значение в старом строковом формате) и получает срок жизни redis.ttl.
+func (c *Client) queueSetOrder(ctx context.Context, pipe redis.Pipeliner, order *models.OrderData) error {
+	fields, err := orderFields(order)
+	if err != nil {
+		return err
+	}
+
+	key := c.orderKey(order.OrderUID)
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, fields)
+	if ttl := c.ttl.Load(); ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+
+	return nil
+}
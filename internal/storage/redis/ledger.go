@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ledgerKey (unexported) возвращает ключ отметки об обработке сообщения:
+// `<namespace>:processed:<topic>:<partition>:<offset>`.
+func (c *Client) ledgerKey(topic string, partition int32, offset int64) string {
+	return c.prefix + "processed:" + topic + ":" + strconv.Itoa(int(partition)) + ":" + strconv.FormatInt(offset, 10)
+}
+
+// IsProcessed сообщает, отмечено ли сообщение как обработанное (см. MarkProcessed).
+// Если учет отключен (redis.ledger_ttl не больше 0), всегда возвращает false.
+func (c *Client) IsProcessed(ctx context.Context, topic string, partition int32, offset int64) (bool, error) {
+	const fn = "storage.redis.IsProcessed"
+
+	if c.ledgerTTL <= 0 {
+		return false, nil
+	}
+
+	var n int64
+	err := c.call(ctx, func() (err error) {
+		n, err = c.Exists(ctx, c.ledgerKey(topic, partition, offset)).Result()
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: can't check message: %w", fn, err)
+	}
+
+	return n > 0, nil
+}
+
+// MarkProcessed отмечает сообщение как обработанное на redis.ledger_ttl.
+// Отметка ставится после обработки, поэтому сообщение, обработка которого
+// прервалась, будет обработано повторно. Если учет отключен, ничего не делает.
+func (c *Client) MarkProcessed(ctx context.Context, topic string, partition int32, offset int64) error {
+	const fn = "storage.redis.MarkProcessed"
+
+	if c.ledgerTTL <= 0 {
+		return nil
+	}
+
+	err := c.call(ctx, func() error {
+		return c.SetNX(ctx, c.ledgerKey(topic, partition, offset), 1, c.ledgerTTL).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("%s: can't mark message: %w", fn, err)
+	}
+
+	return nil
+}
+
+// ClearProcessed удаляет отметки об обработке сообщений партиции `partition`
+// топика `topic` с офсетами не меньше `from` и возвращает число удаленных
+// отметок. Нужен при сбросе офсетов назад: иначе повторно прочитанные
+// сообщения будут пропущены как уже обработанные.
+func (c *Client) ClearProcessed(ctx context.Context, topic string, partition int32, from int64) (int64, error) {
+	const fn = "storage.redis.ClearProcessed"
+
+	prefix := c.ledgerKey(topic, partition, 0)
+	prefix = prefix[:len(prefix)-1] // Без офсета: `<namespace>:processed:<topic>:<partition>:`.
+
+	var deleted int64
+	iter := c.Scan(ctx, 0, prefix+"*", flushBatchSize).Iterator()
+	keys := make([]string, 0, flushBatchSize)
+	del := func() error {
+		n, err := c.Del(ctx, keys...).Result()
+		deleted += n
+		keys = keys[:0]
+		return err
+	}
+
+	for iter.Next(ctx) {
+		key := iter.Val()
+		// Шаблон может захватить ключи других топиков с ':' в имени.
+		offset, err := strconv.ParseInt(strings.TrimPrefix(key, prefix), 10, 64)
+		if err != nil || offset < from {
+			continue
+		}
+		keys = append(keys, key)
+		if len(keys) == flushBatchSize {
+			if err := del(); err != nil {
+				return deleted, fmt.Errorf("%s: can't delete keys: %w", fn, err)
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, fmt.Errorf("%s: can't scan keys: %w", fn, err)
+	}
+	if len(keys) > 0 {
+		if err := del(); err != nil {
+			return deleted, fmt.Errorf("%s: can't delete keys: %w", fn, err)
+		}
+	}
+
+	return deleted, nil
+}
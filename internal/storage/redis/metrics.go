@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/YusovID/order-service/internal/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// metricsHook записывает длительность команд Redis в метрики
+// (см. metrics.CacheCommandDuration).
+type metricsHook struct{}
+
+// DialHook реализует redis.Hook и не меняет установку соединения.
+func (metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook реализует redis.Hook для одиночных команд.
+func (metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		observeCommand(cmd.Name(), start, err)
+		return err
+	}
+}
+
+// ProcessPipelineHook реализует redis.Hook для pipeline и транзакций.
+func (metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		observeCommand("pipeline", start, err)
+		return err
+	}
+}
+
+// observeCommand записывает длительность команды `command` в метрики.
+func observeCommand(command string, start time.Time, err error) {
+	result := "ok"
+	if err != nil && !errors.Is(err, redis.Nil) {
+		result = "error"
+	}
+	metrics.CacheCommandDuration.WithLabelValues(command, result).Observe(time.Since(start).Seconds())
+}
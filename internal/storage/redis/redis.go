@@ -6,11 +6,13 @@ package redis
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
+	"time"
 
 	"github.com/YusovID/order-service/internal/config"
+	"github.com/YusovID/order-service/internal/metrics"
 	"github.com/YusovID/order-service/internal/models"
 	"github.com/YusovID/order-service/internal/storage"
 	"github.com/redis/go-redis/v9"
@@ -21,48 +23,228 @@ import (
 // публичный API пакета.
 type Client struct {
 	*redis.Client
+	prefix      string         // Префикс ключей сервиса: "<namespace>:".
+	ttl         atomicDuration // Срок жизни заказа в кэше, не больше 0 - бессрочно.
+	negativeTTL atomicDuration // Срок жизни отметки об отсутствии заказа, не больше 0 - не запоминать.
+	breaker     *breaker       // Защита от ожидания недоступного Redis.
+	warmLimit   int            // Сколько последних заказов прогревать, 0 - все.
+	warmDays    int            // За сколько последних дней прогревать заказы, 0 - за все время.
+	ledgerTTL   time.Duration  // Срок хранения отметок об обработанных сообщениях, не больше 0 - не вести учет.
 }
 
 // Storage определяет интерфейс для хранилища, из которого будут извлекаться
 // данные для наполнения кэша. Это сделано для того, чтобы `redis.Client`
 // не зависел напрямую от `postgres.Storage`, следуя принципу инверсии зависимостей.
 type Storage interface {
-	GetOrders(ctx context.Context) ([]*models.OrderData, error)
+	StreamOrdersAfter(ctx context.Context, afterUID string, limit int, fn func(*models.OrderData) error) error
+	StreamRecentOrders(ctx context.Context, limit int, since time.Time, fn func(*models.OrderData) error) error
+	GetOrdersSince(ctx context.Context, since time.Time) ([]*models.OrderData, error)
 }
 
+// flushBatchSize - сколько ключей Flush удаляет одной командой DEL.
+const flushBatchSize = 500
+
 // New создает и настраивает новый клиент для подключения к Redis.
 // Функция проверяет соединение с помощью команды PING и возвращает ошибку,
 // если Redis недоступен.
+//
+// Если задан redis.sentinel.master_name, клиент подключается к мастеру,
+// адрес которого сообщают sentinel, и сам переключается на новый мастер
+// при failover.
 func New(ctx context.Context, cfg config.Redis) (*Client, error) {
-	address := net.JoinHostPort(cfg.Host, cfg.Port)
+	tlsConfig, err := newTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("can't configure tls: %v", err)
+	}
 
-	client := redis.NewClient(&redis.Options{
-		Addr:     address,
-		Password: cfg.Password,
-		DB:       cfg.DB,
-	})
+	var client *redis.Client
+	if cfg.Sentinel.MasterName != "" {
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.Sentinel.MasterName,
+			SentinelAddrs:    cfg.Sentinel.Addrs,
+			SentinelPassword: cfg.Sentinel.Password,
+			Username:         cfg.Username,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+			TLSConfig:        tlsConfig,
+		})
+	} else {
+		client = redis.NewClient(&redis.Options{
+			Addr:      net.JoinHostPort(cfg.Host, cfg.Port),
+			Username:  cfg.Username,
+			Password:  cfg.Password,
+			DB:        cfg.DB,
+			TLSConfig: tlsConfig,
+		})
+	}
+
+	client.AddHook(metricsHook{})
 
 	// Проверяем, что соединение с Redis установлено и сервер отвечает.
 	if _, err := client.Ping(ctx).Result(); err != nil {
 		return nil, fmt.Errorf("can't ping redis: %v", err)
 	}
 
-	return &Client{client}, nil
+	c := &Client{
+		Client:    client,
+		prefix:    cfg.Namespace + ":",
+		breaker:   &breaker{},
+		warmLimit: cfg.WarmLimit,
+		warmDays:  cfg.WarmDays,
+		ledgerTTL: cfg.LedgerTTL,
+	}
+	c.SetTTL(cfg.TTL, cfg.NegativeTTL)
+
+	return c, nil
+}
+
+// SetTTL меняет срок жизни заказов в кэше и отметок об их отсутствии
+// (redis.ttl и redis.negative_ttl). Новые сроки применяются к следующим
+// записям; уже записанные ключи сохраняют прежний срок. Безопасен для
+// вызова во время работы, например, при перезагрузке конфигурации.
+func (c *Client) SetTTL(ttl, negativeTTL time.Duration) {
+	c.ttl.Store(ttl)
+	c.negativeTTL.Store(negativeTTL)
+}
+
+// atomicDuration - time.Duration, которую можно менять во время работы.
+type atomicDuration struct {
+	v atomic.Int64
+}
+
+// Load возвращает текущее значение.
+func (d *atomicDuration) Load() time.Duration {
+	return time.Duration(d.v.Load())
+}
+
+// Store задает новое значение.
+func (d *atomicDuration) Store(v time.Duration) {
+	d.v.Store(int64(v))
+}
+
+// Healthy проверяет доступность Redis командой PING.
+func (c *Client) Healthy(ctx context.Context) error {
+	if err := c.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("can't ping redis: %v", err)
+	}
+	return nil
+}
+
+// call (unexported) выполняет запрос к Redis через автомат защиты (см. breaker).
+// Если Redis считается недоступным, запрос не выполняется и возвращается
+// storage.ErrCacheUnavailable.
+func (c *Client) call(ctx context.Context, do func() error) error {
+	if !c.breaker.allow() {
+		return storage.ErrCacheUnavailable
+	}
+
+	err := do()
+	c.breaker.record(!isOutage(ctx, err))
+	return err
+}
+
+// orderKey (unexported) возвращает ключ заказа: `<namespace>:order:<order_uid>`.
+func (c *Client) orderKey(orderUID string) string {
+	return c.prefix + "order:" + orderUID
+}
+
+// Flush удаляет все ключи сервиса (с префиксом redis.namespace), не трогая
+// ключи других сервисов и окружений в том же Redis. Возвращает число
+// удаленных ключей.
+func (c *Client) Flush(ctx context.Context) (int64, error) {
+	const fn = "storage.redis.Flush"
+
+	var deleted int64
+	iter := c.Scan(ctx, 0, c.prefix+"*", flushBatchSize).Iterator()
+	keys := make([]string, 0, flushBatchSize)
+	del := func() error {
+		n, err := c.Del(ctx, keys...).Result()
+		deleted += n
+		keys = keys[:0]
+		return err
+	}
+
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == flushBatchSize {
+			if err := del(); err != nil {
+				return deleted, fmt.Errorf("%s: can't delete keys: %v", fn, err)
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, fmt.Errorf("%s: can't scan keys: %v", fn, err)
+	}
+	if len(keys) > 0 {
+		if err := del(); err != nil {
+			return deleted, fmt.Errorf("%s: can't delete keys: %v", fn, err)
+		}
+	}
+
+	return deleted, nil
 }
 
 // SaveOrder сохраняет данные одного заказа в Redis.
-// Данные заказа сериализуются в JSON и сохраняются как строковое значение.
-// Ключом является `OrderUID` заказа. Запись не имеет срока жизни (TTL=0).
+// Заказ хранится хешем: доставка, оплата, товары и остальные поля заказа
+// сериализуются в JSON в отдельные поля (см. orderFields), чтобы их можно
+// было читать по отдельности. Ключом является `OrderUID` заказа с префиксом
+// (см. orderKey). Запись живет redis.ttl.
 func (c *Client) SaveOrder(ctx context.Context, orderData *models.OrderData) error {
 	const fn = "storage.redis.SaveOrder"
 
-	orderBytes, err := json.Marshal(orderData)
+	err := c.call(ctx, func() error {
+		_, err := c.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return c.queueSetOrder(ctx, pipe, orderData)
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: can't set order: %w", fn, err)
+	}
+
+	return nil
+}
+
+// SaveMissing запоминает на redis.negative_ttl, что заказа нет в основном
+// хранилище: до истечения срока GetOrder возвращает storage.ErrNoOrderCached.
+// Отметка удаляется при сохранении или инвалидации заказа. Если negative_ttl
+// не больше 0, ничего не делает.
+func (c *Client) SaveMissing(ctx context.Context, orderUID string) error {
+	const fn = "storage.redis.SaveMissing"
+
+	negativeTTL := c.negativeTTL.Load()
+	if negativeTTL <= 0 {
+		return nil
+	}
+
+	key := c.orderKey(orderUID)
+	err := c.call(ctx, func() error {
+		_, err := c.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			pipe.HSet(ctx, key, fieldMissing, 1)
+			pipe.Expire(ctx, key, negativeTTL)
+			return nil
+		})
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("%s: can't marshal order data: %v", fn, err)
+		return fmt.Errorf("%s: can't set missing order: %w", fn, err)
 	}
 
-	if err := c.Set(ctx, orderData.OrderUID, orderBytes, 0).Err(); err != nil {
-		return fmt.Errorf("%s: can't set order: %v", fn, err)
+	return nil
+}
+
+// DeleteOrder удаляет заказ из кэша. Вызывается после изменения заказа
+// в основном хранилище, чтобы следующий запрос получил актуальные данные.
+// Отсутствие ключа ошибкой не считается.
+func (c *Client) DeleteOrder(ctx context.Context, orderUID string) error {
+	const fn = "storage.redis.DeleteOrder"
+
+	err := c.call(ctx, func() error {
+		return c.Del(ctx, c.orderKey(orderUID)).Err()
+	})
+	if err != nil {
+		return fmt.Errorf("%s: can't delete order: %w", fn, err)
 	}
 
 	return nil
@@ -70,54 +252,91 @@ func (c *Client) SaveOrder(ctx context.Context, orderData *models.OrderData) err
 
 // GetOrder извлекает данные заказа из Redis по его `orderUID`.
 // Если ключ не найден, функция возвращает ошибку `storage.ErrNoOrder`,
+// а если кэш помнит отсутствие заказа - `storage.ErrNoOrderCached`,
 // что позволяет вызывающему коду понять, что нужно обратиться к основной БД.
-// Если данные найдены, они десериализуются из JSON в структуру `models.OrderData`.
+// Если данные найдены, заказ собирается из полей хеша (см. orderFromFields).
 func (c *Client) GetOrder(ctx context.Context, orderUID string) (*models.OrderData, error) {
 	const fn = "storage.redis.GetOrder"
 
-	// Выполняем команду GET.
-	orderJSON, err := c.Get(ctx, orderUID).Result()
-	// `redis.Nil` - это специальная ошибка, означающая, что ключ не найден.
-	// Мы преобразуем ее в нашу доменную ошибку `storage.ErrNoOrder`.
-	if errors.Is(err, redis.Nil) {
+	// Выполняем команду HGETALL. Для отсутствующего ключа она возвращает
+	// пустой набор полей, а не `redis.Nil`.
+	var fields map[string]string
+	err := c.call(ctx, func() (err error) {
+		fields, err = c.HGetAll(ctx, c.orderKey(orderUID)).Result()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: can't get order: %w", fn, err)
+	}
+	if len(fields) == 0 {
+		metrics.CacheLookups.WithLabelValues("miss").Inc()
 		return nil, storage.ErrNoOrder
 	}
-	if err != nil {
-		return nil, fmt.Errorf("%s: can't get order: %v", fn, err)
+	if _, ok := fields[fieldMissing]; ok {
+		metrics.CacheLookups.WithLabelValues("negative").Inc()
+		return nil, storage.ErrNoOrderCached
 	}
+	metrics.CacheLookups.WithLabelValues("hit").Inc()
 
-	orderData := &models.OrderData{}
-	err = json.Unmarshal([]byte(orderJSON), orderData)
+	orderData, err := orderFromFields(fields)
 	if err != nil {
-		return nil, fmt.Errorf("%s: can't unmarshal order json: %v", fn, err)
+		return nil, fmt.Errorf("%s: %v", fn, err)
 	}
 
 	return orderData, nil
 }
 
-// Fill загружает все заказы из основного хранилища (например, PostgreSQL)
-// и сохраняет их в Redis. Этот метод вызывается при старте приложения
-// для "прогрева" кэша, чтобы обеспечить быстрый доступ к уже существующим данным.
-func (c *Client) Warm(ctx context.Context, storage Storage) error {
-	const fn = "storage.redis.Fill"
+// GetItems возвращает только товары заказа (поле `items` хеша), не читая
+// остальные части заказа. Ошибки те же, что у GetOrder.
+func (c *Client) GetItems(ctx context.Context, orderUID string) ([]models.Item, error) {
+	const fn = "storage.redis.GetItems"
 
-	// Получаем все заказы из основного хранилища.
-	orders, err := storage.GetOrders(ctx)
+	var values []any
+	err := c.call(ctx, func() (err error) {
+		values, err = c.HMGet(ctx, c.orderKey(orderUID), fieldItems, fieldMissing).Result()
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("can't get orders: %v", err)
+		return nil, fmt.Errorf("%s: can't get items: %w", fn, err)
+	}
+	if values[1] != nil {
+		return nil, storage.ErrNoOrderCached
+	}
+	itemsJSON, ok := values[0].(string)
+	if !ok {
+		return nil, storage.ErrNoOrder
 	}
 
-	// Итерируемся по всем заказам и сохраняем каждый в Redis.
-	for _, order := range orders {
-		orderJSON, err := json.Marshal(order)
-		if err != nil {
-			return fmt.Errorf("%s: can't marshal order: %v", fn, err)
-		}
+	var items []models.Item
+	if err := json.Unmarshal([]byte(itemsJSON), &items); err != nil {
+		return nil, fmt.Errorf("%s: can't unmarshal items: %v", fn, err)
+	}
 
-		if err := c.Set(ctx, order.OrderUID, orderJSON, 0).Err(); err != nil {
-			return fmt.Errorf("%s: can't set order: %v", fn, err)
-		}
+	return items, nil
+}
+
+// OrderExists проверяет, есть ли заказ в кэше (HEXISTS), не читая его.
+// false означает только, что заказа нет в кэше; если кэш помнит, что заказа
+// нет и в основном хранилище, возвращается storage.ErrNoOrderCached.
+func (c *Client) OrderExists(ctx context.Context, orderUID string) (bool, error) {
+	const fn = "storage.redis.OrderExists"
+
+	key := c.orderKey(orderUID)
+	var exists, missing *redis.BoolCmd
+	err := c.call(ctx, func() error {
+		_, err := c.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			exists = pipe.HExists(ctx, key, fieldMeta)
+			missing = pipe.HExists(ctx, key, fieldMissing)
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: can't check order: %w", fn, err)
+	}
+	if missing.Val() {
+		return false, storage.ErrNoOrderCached
 	}
 
-	return nil
+	return exists.Val(), nil
 }
@@ -0,0 +1,349 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/YusovID/order-service/internal/metrics"
+	"github.com/YusovID/order-service/internal/models"
+	"github.com/YusovID/order-service/lib/logger/sl"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/errgroup"
+)
+
+// Ключи состояния прогрева (без префикса).
+const (
+	// warmedAtKey - время начала последнего завершенного прогрева или обновления.
+	warmedAtKey = "cache:warmed_at"
+
+	// warmStateKey - хеш состояния незавершенного полного прогрева: время
+	// его начала и order_uid, до которого он дошел. Удаляется, когда полный
+	// прогрев завершен.
+	warmStateKey = "cache:warm_state"
+)
+
+// Поля хеша warmStateKey.
+const (
+	stateStartedAt = "started_at"
+	stateCursor    = "cursor"
+)
+
+// Параметры записи заказов при прогреве кэша.
+const (
+	warmBatchSize = 500   // Заказов в одном pipeline.
+	warmWorkers   = 4     // Pipeline, выполняемых параллельно.
+	warmChunkSize = 10000 // Заказов в одной части полного прогрева; после каждой части сохраняется курсор.
+)
+
+// warmOverlap - запас, с которым догружаются изменения: заказ мог быть
+// сохранен с `updated_at` чуть раньше начала предыдущего прогрева.
+const warmOverlap = time.Minute
+
+// Режимы прогрева (значения метки `mode` метрик прогрева).
+const (
+	modeFull        = "full"
+	modeRecent      = "recent"
+	modeIncremental = "incremental"
+)
+
+// Warm загружает заказы из основного хранилища (например, PostgreSQL)
+// и сохраняет их в Redis. Этот метод вызывается при старте приложения
+// для "прогрева" кэша, чтобы обеспечить быстрый доступ к уже существующим данным.
+//
+// Если кэш уже прогревался (например, при перезапуске сервиса с тем же
+// Redis), загружаются только заказы, измененные после начала прошлого
+// прогрева (GetOrdersSince). Иначе выполняется полный прогрев: таблица
+// читается частями по warmChunkSize заказов в порядке `order_uid`, и после
+// каждой части в Redis сохраняется курсор. Если прогрев прерван (например,
+// перезапуском сервиса), следующий вызов продолжит его с курсора.
+//
+// Заказы записываются в Redis пачками через pipeline несколькими горутинами
+// (см. writeOrders), поэтому память не растет с размером таблицы.
+// Полный прогрев можно ограничить последними заказами (redis.warm_limit,
+// redis.warm_days): старые заказы попадут в кэш при первом запросе. Такой
+// прогрев не делится на части и при прерывании начинается заново.
+//
+// Ход прогрева пишется в лог и в метрики order_service_cache_warm_*.
+func (c *Client) Warm(ctx context.Context, storage Storage, log *slog.Logger) error {
+	const fn = "storage.redis.Warm"
+	log = log.With(slog.String("fn", fn))
+
+	startedAt := time.Now()
+
+	state, err := c.HGetAll(ctx, c.prefix+warmStateKey).Result()
+	if err != nil {
+		return fmt.Errorf("%s: can't get warm state: %v", fn, err)
+	}
+
+	warmedAt, err := c.Get(ctx, c.prefix+warmedAtKey).Time()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("%s: can't get last warm time: %v", fn, err)
+	}
+	incremental := err == nil && len(state) == 0
+
+	mode := modeFull
+	switch {
+	case incremental:
+		mode = modeIncremental
+	case c.warmLimit > 0 || c.warmDays > 0:
+		mode = modeRecent
+	}
+
+	metrics.CacheWarmInProgress.Set(1)
+	defer metrics.CacheWarmInProgress.Set(0)
+
+	var warmed int
+	switch mode {
+	case modeIncremental:
+		warmed, err = c.writeChangedSince(ctx, storage, warmedAt)
+	case modeRecent:
+		warmed, err = c.warmRecent(ctx, storage, startedAt)
+	default:
+		warmed, startedAt, err = c.warmFull(ctx, storage, state, startedAt, log)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", fn, err)
+	}
+
+	_, err = c.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, c.prefix+warmedAtKey, startedAt, 0)
+		pipe.Del(ctx, c.prefix+warmStateKey)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: can't save warm time: %v", fn, err)
+	}
+
+	log.Info("cache warm finished",
+		slog.String("mode", mode),
+		slog.Int("orders", warmed),
+		slog.String("duration", time.Since(startedAt).String()),
+	)
+	return nil
+}
+
+// WarmFull сбрасывает время последнего прогрева и заново загружает в кэш
+// все заказы. Нужен, когда заказы попали в базу в обход сервиса со старым
+// `updated_at` (например, `orderctl seed`) и инкрементальный прогрев их не увидит.
+func (c *Client) WarmFull(ctx context.Context, storage Storage, log *slog.Logger) error {
+	const fn = "storage.redis.WarmFull"
+
+	if err := c.Del(ctx, c.prefix+warmedAtKey, c.prefix+warmStateKey).Err(); err != nil {
+		return fmt.Errorf("%s: can't reset warm time: %v", fn, err)
+	}
+
+	return c.Warm(ctx, storage, log)
+}
+
+// warmFull (unexported) выполняет полный прогрев частями, продолжая его
+// с курсора из `state`, если он есть. Возвращает число записанных заказов
+// и время начала прогрева (для продолженного прогрева - время начала
+// первой попытки, чтобы последующее обновление не пропустило изменения).
+func (c *Client) warmFull(
+	ctx context.Context,
+	storage Storage,
+	state map[string]string,
+	startedAt time.Time,
+	log *slog.Logger,
+) (int, time.Time, error) {
+	cursor := state[stateCursor]
+	if prev, err := time.Parse(time.RFC3339Nano, state[stateStartedAt]); err == nil {
+		startedAt = prev
+	}
+	if cursor != "" {
+		log.Info("resuming cache warm", slog.String("cursor", cursor))
+	}
+
+	total := 0
+	for {
+		var last string
+		warmed, err := c.writeOrders(ctx, modeFull, func(ctx context.Context, add func(*models.OrderData) error) error {
+			return storage.StreamOrdersAfter(ctx, cursor, warmChunkSize, func(order *models.OrderData) error {
+				last = order.OrderUID
+				return add(order)
+			})
+		})
+		total += warmed
+		if err != nil {
+			return total, startedAt, err
+		}
+		if warmed < warmChunkSize {
+			return total, startedAt, nil
+		}
+
+		cursor = last
+		err = c.HSet(ctx, c.prefix+warmStateKey,
+			stateStartedAt, startedAt.Format(time.RFC3339Nano),
+			stateCursor, cursor,
+		).Err()
+		if err != nil {
+			return total, startedAt, fmt.Errorf("can't save warm state: %v", err)
+		}
+
+		log.Info("cache warm progress", slog.Int("orders", total), slog.String("cursor", cursor))
+	}
+}
+
+// warmRecent (unexported) загружает в кэш последние заказы по redis.warm_limit
+// и redis.warm_days.
+func (c *Client) warmRecent(ctx context.Context, storage Storage, now time.Time) (int, error) {
+	var since time.Time
+	if c.warmDays > 0 {
+		since = now.AddDate(0, 0, -c.warmDays)
+	}
+
+	return c.writeOrders(ctx, modeRecent, func(ctx context.Context, add func(*models.OrderData) error) error {
+		return storage.StreamRecentOrders(ctx, c.warmLimit, since, add)
+	})
+}
+
+// Refresh загружает в кэш заказы, измененные после последнего прогрева
+// или обновления, чтобы кэш сходился с базой, даже если уведомления
+// об изменениях были пропущены. Если кэш еще не прогревался, ничего не делает.
+func (c *Client) Refresh(ctx context.Context, storage Storage) error {
+	const fn = "storage.redis.Refresh"
+
+	startedAt := time.Now()
+
+	warmedAt, err := c.Get(ctx, c.prefix+warmedAtKey).Time()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: can't get last warm time: %v", fn, err)
+	}
+
+	if _, err := c.writeChangedSince(ctx, storage, warmedAt); err != nil {
+		return fmt.Errorf("%s: %v", fn, err)
+	}
+
+	if err := c.Set(ctx, c.prefix+warmedAtKey, startedAt, 0).Err(); err != nil {
+		return fmt.Errorf("%s: can't save warm time: %v", fn, err)
+	}
+	return nil
+}
+
+// RunRefresh вызывает Refresh каждые `interval`, пока не отменен контекст.
+// Интервал не больше 0 отключает обновление.
+func (c *Client) RunRefresh(ctx context.Context, storage Storage, interval time.Duration, log *slog.Logger, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := c.Refresh(ctx, storage); err != nil {
+			log.Error("failed to refresh cache", sl.Err(err))
+		}
+	}
+}
+
+// writeChangedSince (unexported) записывает в кэш заказы, измененные
+// после `since` (с запасом warmOverlap).
+func (c *Client) writeChangedSince(ctx context.Context, storage Storage, since time.Time) (int, error) {
+	return c.writeOrders(ctx, modeIncremental, func(ctx context.Context, add func(*models.OrderData) error) error {
+		orders, err := storage.GetOrdersSince(ctx, since.Add(-warmOverlap))
+		if err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if err := add(order); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// orderSource (unexported) - источник заказов для прогрева: вызывает add
+// для каждого заказа.
+type orderSource func(ctx context.Context, add func(*models.OrderData) error) error
+
+// writeOrders (unexported) записывает в кэш заказы из `source` и возвращает
+// их количество.
+//
+// Заказы собираются в пачки по warmBatchSize, каждая пачка записывается
+// одним pipeline (один round-trip вместо отдельной записи на заказ).
+// Пачки записывают warmWorkers горутин параллельно с чтением из хранилища.
+func (c *Client) writeOrders(ctx context.Context, mode string, source orderSource) (int, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	batches := make(chan []*models.OrderData)
+	count := 0
+
+	for range warmWorkers {
+		g.Go(func() error {
+			for batch := range batches {
+				if err := c.setOrders(ctx, batch); err != nil {
+					return err
+				}
+				metrics.CacheWarmOrders.WithLabelValues(mode).Add(float64(len(batch)))
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		defer close(batches)
+
+		batch := make([]*models.OrderData, 0, warmBatchSize)
+		send := func() error {
+			select {
+			case batches <- batch:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			batch = make([]*models.OrderData, 0, warmBatchSize)
+			return nil
+		}
+
+		err := source(ctx, func(order *models.OrderData) error {
+			count++
+			batch = append(batch, order)
+			if len(batch) < warmBatchSize {
+				return nil
+			}
+			return send()
+		})
+		if err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			return send()
+		}
+		return nil
+	})
+
+	err := g.Wait()
+	return count, err
+}
+
+// setOrders (unexported) записывает заказы в кэш одним pipeline
+// со сроком жизни redis.ttl.
+func (c *Client) setOrders(ctx context.Context, orders []*models.OrderData) error {
+	pipe := c.TxPipeline()
+	for _, order := range orders {
+		if err := c.queueSetOrder(ctx, pipe, order); err != nil {
+			return err
+		}
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("can't set orders: %v", err)
+	}
+
+	return nil
+}
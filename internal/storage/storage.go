@@ -4,7 +4,11 @@
 // и избежать дублирования кода.
 package storage
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
 
 // Пакетные переменные, определяющие стандартные доменные ошибки.
 // Использование предопределенных ошибок позволяет вызывающему коду
@@ -18,4 +22,41 @@ var (
 	// ErrEmptyOrder может использоваться, если заказ найден, но
 	// он не содержит товаров, что может считаться невалидным состоянием.
 	ErrEmptyOrder = errors.New("no items in order")
+
+	// ErrInvalidTransition сигнализирует о том, что заказ нельзя
+	// перевести из текущего статуса в запрошенный.
+	ErrInvalidTransition = errors.New("invalid order status transition")
+
+	// ErrRetryExhausted сигнализирует о том, что операция завершалась
+	// временной ошибкой (deadlock, конфликт сериализации, обрыв соединения)
+	// при каждой попытке, и повторы исчерпаны.
+	ErrRetryExhausted = errors.New("retry attempts exhausted")
+
+	// ErrDuplicateOrder сигнализирует о том, что такая же или более новая
+	// версия заказа уже сохранена, и пришедшая версия пропущена
+	// (например, повторно доставленное сообщение).
+	ErrDuplicateOrder = errors.New("order already saved")
+
+	// ErrNoOrderCached сигнализирует о том, что кэш помнит отсутствие заказа
+	// и обращаться к основному хранилищу не нужно. Оборачивает ErrNoOrder.
+	ErrNoOrderCached = fmt.Errorf("%w (cached)", ErrNoOrder)
+
+	// ErrCacheUnavailable сигнализирует о том, что кэш недоступен и запрос
+	// к нему не выполнялся: данные нужно брать из основного хранилища.
+	ErrCacheUnavailable = errors.New("cache unavailable")
 )
+
+// actorKey - ключ контекста для автора изменения.
+type actorKey struct{}
+
+// WithActor возвращает контекст, в котором хранилище записывает `actor`
+// автором изменений заказа в журнал аудита (например, "kafka" или "api").
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext возвращает автора изменения из контекста.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok && actor != ""
+}
@@ -0,0 +1,84 @@
+package orderGen
+
+import (
+	"math/rand/v2"
+	"sync"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// Параметры пула покупателей по умолчанию.
+const (
+	DefaultCustomerPoolSize = 1000 // Количество покупателей.
+	DefaultCustomerSkew     = 1.1  // Параметр s распределения Ципфа (должен быть больше 1).
+)
+
+// CustomerPool - ограниченный набор покупателей, из которого выбирается
+// customer_id для новых заказов.
+//
+// Покупатели выбираются по закону Ципфа: небольшая часть покупателей делает
+// большую часть заказов, как в реальном магазине. Поэтому запросы и кэш по
+// покупателю ведут себя на сгенерированных данных реалистично.
+type CustomerPool struct {
+	mu   sync.Mutex
+	size int
+	skew float64
+	ids  []string // Создаются при первом обращении, чтобы учесть Seed.
+	zipf *rand.Zipf
+}
+
+// NewCustomerPool создает пул из `size` покупателей с параметром
+// распределения `skew`. Чем больше `skew`, тем сильнее заказы
+// концентрируются у самых активных покупателей.
+func NewCustomerPool(size int, skew float64) *CustomerPool {
+	return &CustomerPool{size: max(size, 1), skew: max(skew, DefaultCustomerSkew)}
+}
+
+// Next возвращает идентификатор покупателя для нового заказа.
+func (p *CustomerPool) Next() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ids == nil {
+		p.ids = make([]string, p.size)
+		for i := range p.ids {
+			p.ids[i] = gofakeit.UUID()
+		}
+		// Источник случайности - gofakeit, чтобы выбор зависел от Seed.
+		p.zipf = rand.NewZipf(rand.New(gofakeit.GlobalFaker), p.skew, 1, uint64(p.size-1))
+	}
+
+	return p.ids[p.zipf.Uint64()]
+}
+
+// customers - пул покупателей, из которого генерируются заказы.
+// nil означает уникального покупателя для каждого заказа.
+var customers = struct {
+	sync.Mutex
+	pool *CustomerPool
+}{pool: NewCustomerPool(DefaultCustomerPoolSize, DefaultCustomerSkew)}
+
+// SetCustomerPool задает пул покупателей для генерируемых заказов.
+// При `size` <= 0 каждый заказ получает нового покупателя.
+func SetCustomerPool(size int, skew float64) {
+	customers.Lock()
+	defer customers.Unlock()
+
+	if size <= 0 {
+		customers.pool = nil
+		return
+	}
+	customers.pool = NewCustomerPool(size, skew)
+}
+
+// customerID возвращает идентификатор покупателя для нового заказа.
+func customerID() string {
+	customers.Lock()
+	pool := customers.pool
+	customers.Unlock()
+
+	if pool == nil {
+		return gofakeit.UUID()
+	}
+	return pool.Next()
+}
@@ -0,0 +1,64 @@
+package orderGen
+
+import (
+	"encoding/json"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// Виды заведомо некорректных сообщений.
+const (
+	InvalidBrokenJSON = "broken_json"       // Обрезанный JSON, который нельзя разобрать.
+	InvalidMissingUID = "missing_order_uid" // Валидный JSON без order_uid.
+	InvalidWrongTypes = "wrong_types"       // Поля с неверными типами.
+)
+
+// invalidKinds - все виды некорректных сообщений.
+var invalidKinds = []string{InvalidBrokenJSON, InvalidMissingUID, InvalidWrongTypes}
+
+// Chance возвращает true с вероятностью `percent` процентов.
+// Использует генератор gofakeit, поэтому результат воспроизводим при заданном Seed.
+func Chance(percent float64) bool {
+	if percent <= 0 {
+		return false
+	}
+	return gofakeit.Float64Range(0, 100) < percent
+}
+
+// GenerateInvalidOrder создает тело заведомо некорректного сообщения о заказе
+// случайного вида (см. Invalid*). Используется для проверки того, что обработчик
+// пропускает такие сообщения и отправляет их в DLQ.
+//
+// Возвращает ключ сообщения (пустой для сообщения без order_uid),
+// тело в формате JSON и вид ошибки.
+func GenerateInvalidOrder() (key string, body []byte, kind string) {
+	order := GenerateOrderData()
+	kind = gofakeit.RandomString(invalidKinds)
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		// Заказ из генератора всегда сериализуется. Если нет - отдаем мусор.
+		return order.OrderUID, []byte("{"), InvalidBrokenJSON
+	}
+
+	var fields map[string]any
+	_ = json.Unmarshal(data, &fields) // Только что сериализованный объект всегда разбирается.
+
+	switch kind {
+	case InvalidBrokenJSON:
+		// Обрезаем тело посередине.
+		return order.OrderUID, data[:len(data)/2], kind
+
+	case InvalidMissingUID:
+		delete(fields, "order_uid")
+		key = ""
+
+	case InvalidWrongTypes:
+		fields["items"] = gofakeit.Word()
+		fields["date_created"] = gofakeit.Number(1, 1000000)
+		key = order.OrderUID
+	}
+
+	body, _ = json.Marshal(fields)
+	return key, body, kind
+}
@@ -7,6 +7,7 @@ package orderGen
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/YusovID/order-service/internal/models"
 	"github.com/brianvoe/gofakeit/v7"
@@ -20,7 +21,45 @@ var (
 	banks            = []string{"alpha", "sber", "vtb", "tinkoff"}
 )
 
-// GenerateOrder создает полную структуру заказа (`models.OrderData`) со случайными данными.
+// Seed задает начальное значение генератора случайных данных.
+// При одинаковом `seed` последовательные вызовы функций пакета возвращают
+// одни и те же заказы, что позволяет писать детерминированные
+// интеграционные тесты. Генерация должна идти из одной горутины,
+// иначе порядок вызовов (и результат) будет отличаться от запуска к запуску.
+func Seed(seed uint64) error {
+	if err := gofakeit.Seed(seed); err != nil {
+		return err
+	}
+
+	// Пересоздаем пул покупателей, чтобы их идентификаторы тоже зависели от seed.
+	customers.Lock()
+	defer customers.Unlock()
+	if customers.pool != nil {
+		customers.pool = NewCustomerPool(customers.pool.size, customers.pool.skew)
+	}
+
+	return nil
+}
+
+// GenerateOrder создает заказ со случайными данными и сериализует его в JSON.
+//
+// Возвращает:
+//   - `string`: сгенерированный `order_uid`, который используется как ключ сообщения в Kafka.
+//   - `[]byte`: JSON-представление сгенерированного заказа.
+func GenerateOrder() (string, []byte) {
+	order := GenerateOrderData()
+
+	jsonData, err := json.Marshal(order)
+	if err != nil {
+		// В данном контексте (генератор) просто выводим ошибку в консоль.
+		fmt.Println("Error marshaling to JSON:", err)
+		return "", nil
+	}
+
+	return order.OrderUID, jsonData
+}
+
+// GenerateOrderData создает полную структуру заказа (`models.OrderData`) со случайными данными.
 //
 // Функция последовательно генерирует все части заказа:
 //  1. Основные атрибуты: `order_uid`, `track_number`.
@@ -28,15 +67,22 @@ var (
 //  3. Данные о доставке (`delivery`) и оплате (`payment`).
 //  4. Дополнительные метаданные.
 //
-// Затем вся структура сериализуется в JSON.
-//
-// Возвращает:
-//   - `string`: сгенерированный `order_uid`, который используется как ключ сообщения в Kafka.
-//   - `[]byte`: JSON-представление сгенерированного заказа.
-func GenerateOrder() (string, []byte) {
+// Сериализация оставлена вызывающему коду, чтобы заказ можно было
+// закодировать в любом поддерживаемом формате.
+func GenerateOrderData() *models.OrderData {
+	return GenerateOrderDataAt(gofakeit.Date())
+}
+
+// GenerateOrderDataBetween создает заказ со случайной датой создания
+// в интервале [from, to]. Используется для генерации "исторических" данных.
+func GenerateOrderDataBetween(from, to time.Time) *models.OrderData {
+	return GenerateOrderDataAt(gofakeit.DateRange(from, to))
+}
+
+// GenerateOrderDataAt создает заказ со случайными данными и заданной датой создания.
+func GenerateOrderDataAt(dateCreated time.Time) *models.OrderData {
 	orderUID := gofakeit.UUID()
 	trackNumber := gofakeit.LetterN(4) + gofakeit.DigitN(8)
-	dateCreated := gofakeit.Date()
 
 	// Генерируем от 1 до 3 товаров в заказе.
 	itemsCount := gofakeit.Number(1, 3)
@@ -72,10 +118,10 @@ func GenerateOrder() (string, []byte) {
 		CustomFee:    0,
 	}
 
-	order := models.OrderData{
+	return &models.OrderData{
 		OrderUID:        orderUID,
 		TrackNumber:     trackNumber,
-		CustomerID:      gofakeit.UUID(),
+		CustomerID:      customerID(),
 		DeliveryService: gofakeit.RandomString(deliveryServices),
 		DateCreated:     dateCreated,
 		Items:           items,
@@ -90,15 +136,6 @@ func GenerateOrder() (string, []byte) {
 			OofShard:          "1",
 		},
 	}
-
-	jsonData, err := json.Marshal(order)
-	if err != nil {
-		// В данном контексте (генератор) просто выводим ошибку в консоль.
-		fmt.Println("Error marshaling to JSON:", err)
-		return "", nil
-	}
-
-	return orderUID, jsonData
 }
 
 // generateItem создает один случайный товар (`models.Item`).
@@ -23,6 +23,28 @@ const (
 	envProd  = "prod"  // Продакшен-среда (JSON, info уровень).
 )
 
+// Форматы вывода логов (см. Options.Format).
+const (
+	FormatJSON   = "json"   // Одна JSON-запись на строку, для Loki/ELK.
+	FormatPretty = "pretty" // Цветной читаемый вывод для локальной разработки.
+)
+
+// KeyTime - ключ времени записи в JSON-логах. Остальные ключи стандартные
+// для slog (level, msg); атрибуты заказа и запроса пишутся под ключами
+// order_uid и request_id.
+const KeyTime = "ts"
+
+// Options содержит параметры логгера.
+type Options struct {
+	// Format - формат вывода (FormatJSON или FormatPretty). Пустое значение -
+	// по окружению: pretty для local, json для dev и prod.
+	Format string
+	// Level - уровень логирования, который можно менять во время работы.
+	// В него записывается уровень по умолчанию для окружения. nil - свой
+	// уровень логгера.
+	Level *slog.LevelVar
+}
+
 // PrettyHandlerOptions содержит опции для настройки PrettyHandler.
 type PrettyHandlerOptions struct {
 	SlogOpts *slog.HandlerOptions // Стандартные опции slog, например, уровень логирования.
@@ -55,32 +77,70 @@ func (opts PrettyHandlerOptions) NewPrettyHandler(
 // `*slog.Logger` с подходящим обработчиком в зависимости от переданной
 // строки окружения (`env`).
 func SetupLogger(env string) *slog.Logger {
-	var log *slog.Logger
+	return New(env, Options{})
+}
+
+// New создает логгер для окружения `env` с параметрами `opts`: формат
+// вывода можно задать явно (например, JSON для local-окружения в контейнере),
+// а уровень логирования - менять во время работы, не пересоздавая логгер.
+//
+// Неизвестное окружение считается продакшеном.
+func New(env string, opts Options) *slog.Logger {
+	level := opts.Level
+	if level == nil {
+		level = new(slog.LevelVar)
+	}
 
+	format := FormatJSON
 	switch env {
 	case envLocal:
-		// Для локальной разработки используем наш красивый цветной логгер.
-		log = setupPrettySlog()
+		// Для локальной разработки - цветной логгер с уровнем Debug.
+		level.Set(slog.LevelDebug)
+		format = FormatPretty
 	case envDev:
-		// Для dev-окружения — стандартный JSON с уровнем Debug.
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		)
-	case envProd:
-		// Для продакшена — стандартный JSON с уровнем Info.
-		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
-		)
+		// Для dev-окружения - JSON с уровнем Debug.
+		level.Set(slog.LevelDebug)
+	default:
+		// Для продакшена - JSON с уровнем Info.
+		level.Set(slog.LevelInfo)
 	}
-	return log
+	if opts.Format != "" {
+		format = opts.Format
+	}
+
+	if format == FormatPretty {
+		return setupPrettySlog(level)
+	}
+	return setupJSONSlog(level)
+}
+
+// setupJSONSlog создает логгер с JSON-выводом, в котором время записи
+// лежит под ключом KeyTime.
+func setupJSONSlog(level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				a.Key = KeyTime
+			}
+			return a
+		},
+	}))
+}
+
+// ParseLevel разбирает уровень логирования (debug, info, warn, error).
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(s))
+	return level, err
 }
 
 // setupPrettySlog — вспомогательная функция для инкапсуляции
 // создания и настройки PrettyHandler.
-func setupPrettySlog() *slog.Logger {
+func setupPrettySlog(level slog.Leveler) *slog.Logger {
 	opts := PrettyHandlerOptions{
 		SlogOpts: &slog.HandlerOptions{
-			Level: slog.LevelDebug,
+			Level: level,
 		},
 	}
 	handler := opts.NewPrettyHandler(os.Stdout)
@@ -0,0 +1,67 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Group - набор задач, выполняемых воркерами пула, с общим результатом
+// (по аналогии с errgroup.Group). Избавляет от ручного подсчета задач
+// через sync.WaitGroup и сбора их ошибок.
+//
+// Ошибка одной задачи не отменяет остальные: Wait возвращает ошибки
+// всех задач, объединенные через errors.Join.
+type Group[Data any] struct {
+	pool *Pool[Data]
+	ctx  context.Context
+	wg   sync.WaitGroup
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// Group создает набор задач, выполняемых воркерами пула в контексте `ctx`.
+func (p *Pool[Data]) Group(ctx context.Context) *Group[Data] {
+	return &Group[Data]{pool: p, ctx: ctx}
+}
+
+// Go выполняет задачу `task` на одном из воркеров пула.
+// Блокируется, пока все воркеры заняты. Если пул не запущен,
+// задача не выполняется, а Wait вернет ErrPoolStopped.
+func (g *Group[Data]) Go(task func(ctx context.Context) error) {
+	if err := g.pool.acquire(); err != nil {
+		g.mu.Lock()
+		g.errs = append(g.errs, err)
+		g.mu.Unlock()
+		return
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer g.pool.release()
+
+		var err error
+		if panicErr := g.pool.execute(g.ctx, func(ctx context.Context) {
+			err = task(ctx)
+		}); panicErr != nil {
+			err = panicErr
+		}
+		if err != nil {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+		}
+	}()
+}
+
+// Wait дожидается завершения всех задач набора и возвращает их ошибки
+// или nil, если все задачи выполнены успешно.
+func (g *Group[Data]) Wait() error {
+	g.wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return errors.Join(g.errs...)
+}
@@ -0,0 +1,98 @@
+package workerpool
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// Stats - состояние пула воркеров в момент вызова Stats.
+type Stats struct {
+	Size   int // Текущий размер пула.
+	Busy   int // Воркеры, выполняющие задачи.
+	Queued int // Задачи в очереди (см. WithQueue).
+}
+
+// Stats возвращает текущее состояние пула. Подходит для экспорта метрик
+// при каждом их сборе: например, по доле занятых воркеров можно
+// настроить оповещение о длительной перегрузке пула.
+func (p *Pool[Data]) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return Stats{Size: p.size, Busy: p.active, Queued: len(p.queue)}
+}
+
+// Hooks - функции, которые пул вызывает для наблюдения за задачами.
+// Любая из них может быть nil. Хуки вызываются в горутине задачи,
+// поэтому не должны блокироваться.
+type Hooks struct {
+	// TaskDone вызывается после завершения каждой задачи с ее длительностью.
+	TaskDone func(duration time.Duration)
+	// Panic вызывается при панике в задаче со значением паники и стеком
+	// горутины. После вызова паника продолжается, если не задан WithRecover.
+	Panic func(value any, stack []byte)
+}
+
+// WithHooks задает хуки наблюдения за задачами пула.
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = hooks
+	}
+}
+
+// WithRecover перехватывает паники в задачах пула: вместо падения процесса
+// паника превращается в PanicError. Задачи Submit и Group возвращают ее как
+// результат, для задач Handle и TrySubmit о ней сообщает хук Hooks.Panic.
+func WithRecover() Option {
+	return func(o *options) {
+		o.recover = true
+	}
+}
+
+// PanicError - ошибка задачи, завершившейся паникой (см. WithRecover).
+type PanicError struct {
+	Value any    // Значение паники.
+	Stack []byte // Стек горутины в момент паники.
+}
+
+// Error возвращает значение паники вместе со стеком.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("worker pool task panicked: %v\n%s", e.Value, e.Stack)
+}
+
+// execute выполняет задачу `fn` в контексте задачи (см. taskContext)
+// и сообщает о ней хукам. Возвращает PanicError, если задача
+// завершилась паникой, а пул создан с WithRecover.
+func (p *Pool[Data]) execute(ctx context.Context, fn func(ctx context.Context)) (err error) {
+	taskCtx, cancel := p.taskContext(ctx)
+	defer cancel()
+
+	hooks := p.opts.hooks
+	start := time.Now()
+	defer func() {
+		if hooks.TaskDone != nil {
+			hooks.TaskDone(time.Since(start))
+		}
+		if hooks.Panic == nil && !p.opts.recover {
+			return
+		}
+
+		r := recover()
+		if r == nil {
+			return
+		}
+		stack := debug.Stack()
+		if hooks.Panic != nil {
+			hooks.Panic(r, stack)
+		}
+		if !p.opts.recover {
+			panic(r)
+		}
+		err = &PanicError{Value: r, Stack: stack}
+	}()
+
+	fn(taskCtx)
+	return nil
+}
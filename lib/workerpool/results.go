@@ -0,0 +1,90 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+)
+
+// Result - результат обработки одного входного значения в ResultPool.
+type Result[In, Out any] struct {
+	Index int   // Позиция входного значения в переданном срезе.
+	In    In    // Входное значение.
+	Out   Out   // Результат обработчика. Не определен, если Err != nil.
+	Err   error // Ошибка обработчика, PanicError (см. WithRecover) или ErrPoolStopped.
+}
+
+// ResultPool - пул воркеров, обработчик которого возвращает значение.
+// Подходит для параллельной выборки (fan-out) с ограничением числа
+// одновременных запросов, например, для загрузки нескольких заказов.
+//
+// Размером, жизненным циклом и метриками ResultPool управляют так же,
+// как Pool: Start, Stop, Grow, Shrink, Stats.
+type ResultPool[In, Out any] struct {
+	*Pool[In]
+	fn func(ctx context.Context, in In) (Out, error)
+}
+
+// NewResult создает пул воркеров с обработчиком `fn`, возвращающим значение.
+// Пул начинает принимать задачи после Start.
+func NewResult[In, Out any](fn func(ctx context.Context, in In) (Out, error), opts ...Option) *ResultPool[In, Out] {
+	return &ResultPool[In, Out]{
+		// Handle пула выполняет обработчик, отбрасывая результат.
+		Pool: New(func(ctx context.Context, in In) { _, _ = fn(ctx, in) }, opts...),
+		fn:   fn,
+	}
+}
+
+// Map обрабатывает значения `inputs` на воркерах пула и возвращает канал
+// с результатами. При `ordered` результаты приходят в порядке `inputs`,
+// иначе - по мере готовности. Канал закрывается после последнего результата.
+// Канал буферизирован на все результаты, поэтому их можно не дочитывать.
+func (r *ResultPool[In, Out]) Map(ctx context.Context, inputs []In, ordered bool) <-chan Result[In, Out] {
+	results := make(chan Result[In, Out], len(inputs))
+
+	// slot - задача, переданная в пул, и ее будущий результат.
+	type slot struct {
+		result Result[In, Out]
+		done   <-chan error
+	}
+	slots := make(chan *slot, len(inputs))
+
+	// Передаем задачи в пул по мере освобождения воркеров.
+	go func() {
+		defer close(slots)
+
+		for i, in := range inputs {
+			s := &slot{result: Result[In, Out]{Index: i, In: in}}
+			s.done = r.Submit(ctx, func(ctx context.Context) error {
+				var err error
+				s.result.Out, err = r.fn(ctx, in)
+				return err
+			})
+			slots <- s
+		}
+	}()
+
+	go func() {
+		defer close(results)
+
+		if ordered {
+			for s := range slots {
+				s.result.Err = <-s.done
+				results <- s.result
+			}
+			return
+		}
+
+		var wg sync.WaitGroup
+		for s := range slots {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.result.Err = <-s.done
+				results <- s.result
+			}()
+		}
+		wg.Wait()
+	}()
+
+	return results
+}
@@ -6,71 +6,397 @@ package workerpool
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
 )
 
-// MaxWorkersCount определяет максимальное количество воркеров в пуле,
-// то есть максимальное число задач, которые могут выполняться параллельно.
+// MaxWorkersCount определяет количество воркеров в новом пуле,
+// то есть число задач, которые могут выполняться параллельно.
+// Размер пула можно менять во время работы (см. Grow и Shrink).
 const MaxWorkersCount = 10
 
-// Worker представляет собой "пропуск" на выполнение задачи.
-// Наличие Worker в канале `pool` означает, что есть свободный слот
-// для выполнения работы. Сама структура пуста, она используется как семафор.
-type Worker struct{}
+var (
+	// ErrPoolSaturated возвращается TrySubmit, если все воркеры заняты,
+	// а очередь задач заполнена.
+	ErrPoolSaturated = errors.New("worker pool is saturated")
+	// ErrPoolStopped возвращается при передаче задачи в пул, который
+	// еще не запущен (см. Start) или уже остановлен (см. Stop).
+	ErrPoolStopped = errors.New("worker pool is not running")
+)
+
+// Option настраивает пул воркеров при создании (см. New).
+type Option func(*options)
+
+// options - параметры пула воркеров.
+type options struct {
+	queueSize   int
+	taskTimeout time.Duration
+	hooks       Hooks
+	recover     bool
+}
 
-var workers = [MaxWorkersCount]*Worker{{}, {}, {}, {}, {}, {}, {}, {}, {}, {}}
+// WithQueue задает размер очереди задач, которые TrySubmit принимает,
+// когда все воркеры заняты. Задачи из очереди выполняются по порядку
+// по мере освобождения воркеров. По умолчанию очереди нет.
+func WithQueue(size int) Option {
+	return func(o *options) {
+		o.queueSize = max(size, 0)
+	}
+}
+
+// WithTaskTimeout ограничивает время выполнения каждой задачи: обработчик
+// получает контекст, который отменяется через `timeout`. Обработчик должен
+// сам следить за контекстом - пул не прерывает задачу принудительно.
+func WithTaskTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.taskTimeout = max(timeout, 0)
+	}
+}
 
 // Pool - это generic-структура для пула воркеров.
 // Она может работать с любым типом данных `Data`, который будет передаваться в обработчик.
+//
+// Пул ограничивает число одновременно выполняемых задач своим размером.
+// Размер можно менять во время работы, не пересоздавая пул: например,
+// уменьшать, когда хранилище не справляется с нагрузкой.
+//
+// Жизненный цикл пула: New, затем Start, передача задач (Handle, TrySubmit,
+// Submit) и Stop, который дожидается выполняемых задач.
 type Pool[Data any] struct {
-	pool    chan *Worker                        // Канал, который работает как семафор, ограничивая количество воркеров.
-	handler func(ctx context.Context, msg Data) // Функция, которая будет выполнять основную работу.
+	mu       sync.Mutex
+	cond     *sync.Cond                          // Сигнализирует об освобождении воркера или изменении состояния.
+	size     int                                 // Максимум одновременно выполняемых задач.
+	active   int                                 // Число выполняемых сейчас задач.
+	running  bool                                // Пул принимает задачи (между Start и Stop).
+	queue    []*task[Data]                       // Задачи, ожидающие свободного воркера (см. TrySubmit).
+	inflight map[*task[Data]]struct{}            // Выполняемые задачи с данными, для отчета Stop.
+	handler  func(ctx context.Context, msg Data) // Функция, которая будет выполнять основную работу.
+	opts     options
+
+	stopped context.Context    // Отменяется Cancel; от него зависят контексты всех задач.
+	cancel  context.CancelFunc // Отменяет stopped.
+}
+
+// task - задача с данными для обработчика пула.
+type task[Data any] struct {
+	ctx  context.Context
+	data Data
 }
 
-// New создает и возвращает новый экземпляр пула воркеров.
+// New создает и возвращает новый экземпляр пула воркеров
+// размером MaxWorkersCount. Пул начинает принимать задачи после Start.
 //
 // Параметры:
 //   - handler: функция, которая будет вызываться для обработки каждой единицы данных.
+//   - opts: дополнительные параметры пула (например, WithQueue).
 //
 // Возвращает:
 //   - *Pool[Data]: указатель на созданный пул.
-func New[Data any](handler func(ctx context.Context, msg Data)) *Pool[Data] {
-	return &Pool[Data]{
-		pool:    make(chan *Worker, len(workers)),
-		handler: handler,
+func New[Data any](handler func(ctx context.Context, msg Data), opts ...Option) *Pool[Data] {
+	p := &Pool[Data]{
+		size:     MaxWorkersCount,
+		inflight: make(map[*task[Data]]struct{}),
+		handler:  handler,
+	}
+	for _, opt := range opts {
+		opt(&p.opts)
+	}
+	p.cond = sync.NewCond(&p.mu)
+	p.stopped, p.cancel = context.WithCancel(context.Background())
+	return p
+}
+
+// Start запускает пул: после него пул принимает задачи. Остановленный
+// пул можно запустить повторно.
+func (p *Pool[Data]) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return
+	}
+	if p.stopped.Err() != nil {
+		p.stopped, p.cancel = context.WithCancel(context.Background())
+	}
+	p.running = true
+}
+
+// Stop останавливает пул: новые задачи больше не принимаются, а уже
+// принятые (выполняемые и из очереди) выполняются до отмены `ctx`.
+// После отмены `ctx` очередь отбрасывается, контексты выполняемых задач
+// отменяются, и Stop дожидается их завершения.
+//
+// Возвращает данные задач, которые не успели выполниться: задачи из
+// очереди и задачи, прерванные отменой. Задачи Submit и Group в отчет
+// не попадают - их результат приходит вызывающему коду.
+func (p *Pool[Data]) Stop(ctx context.Context) []Data {
+	p.mu.Lock()
+	p.running = false
+	p.mu.Unlock()
+	// Будим задачи, ожидающие воркера: они получат ErrPoolStopped.
+	p.cond.Broadcast()
+
+	idle := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
 	}
+
+	p.mu.Lock()
+	undone := make([]Data, 0, len(p.queue)+len(p.inflight))
+	for _, t := range p.queue {
+		undone = append(undone, t.data)
+	}
+	p.queue = nil
+	for t := range p.inflight {
+		undone = append(undone, t.data)
+	}
+	p.mu.Unlock()
+
+	p.Cancel()
+	<-idle
+
+	return undone
 }
 
-// Create "заполняет" пул воркерами. Этот метод нужно вызвать перед
-// началом обработки пачки задач, чтобы в канале появились "пропуски".
-func (p *Pool[Data]) Create() {
-	for _, w := range workers {
-		p.pool <- w
+// Cancel отменяет контексты выполняемых задач и задач в очереди.
+// Пул после этого продолжает принимать задачи, но их контексты
+// сразу отменены.
+func (p *Pool[Data]) Cancel() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cancel()
+}
+
+// taskContext возвращает контекст для выполнения одной задачи: он
+// отменяется вместе с `ctx`, при вызове Cancel или по таймауту задачи.
+func (p *Pool[Data]) taskContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	p.mu.Lock()
+	stopped := p.stopped
+	p.mu.Unlock()
+
+	var cancel context.CancelFunc
+	if p.opts.taskTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, p.opts.taskTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	stop := context.AfterFunc(stopped, cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
 	}
 }
 
 // Handle передает данные на обработку одному из воркеров.
 //
 // Этот метод блокируется, если все воркеры в пуле заняты.
-// Как только воркер освобождается (из канала `pool` удается прочитать значение),
-// он "захватывается", выполняется функция `handler`, и после завершения
-// воркер возвращается обратно в пул.
-func (p *Pool[Data]) Handle(ctx context.Context, data Data) {
-	// Ожидаем, пока в канале `pool` появится свободный воркер.
-	w := <-p.pool
-
-	// Запускаем новую горутину, в которой происходит основная работа
+// Как только воркер освобождается, он "захватывается", выполняется
+// функция `handler`, и после завершения воркер возвращается обратно в пул.
+// Возвращает ErrPoolStopped, если пул не запущен или остановлен, пока
+// задача ждала воркера.
+func (p *Pool[Data]) Handle(ctx context.Context, data Data) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.acquireLocked(); err != nil {
+		return err
+	}
+	p.start(&task[Data]{ctx: ctx, data: data})
+	return nil
+}
+
+// TrySubmit передает данные на обработку, не блокируясь. Если все воркеры
+// заняты, данные ставятся в очередь (см. WithQueue); если заполнена и она,
+// возвращается ErrPoolSaturated. Так вызывающий код может притормозить
+// источник задач вместо того, чтобы ждать в Handle.
+func (p *Pool[Data]) TrySubmit(ctx context.Context, data Data) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := &task[Data]{ctx: ctx, data: data}
+	switch {
+	case !p.running:
+		return ErrPoolStopped
+	case p.active < p.size && len(p.queue) == 0:
+		p.active++
+		p.start(t)
+	case len(p.queue) < p.opts.queueSize:
+		p.queue = append(p.queue, t)
+	default:
+		return ErrPoolSaturated
+	}
+	return nil
+}
+
+// start запускает задачу на уже захваченном воркере.
+// Вызывается под p.mu.
+func (p *Pool[Data]) start(t *task[Data]) {
+	p.inflight[t] = struct{}{}
+	go p.run(t)
+}
+
+// run выполняет обработчик на захваченном воркере, а затем задачи из
+// очереди, пока они есть и размер пула позволяет оставить воркер.
+func (p *Pool[Data]) run(t *task[Data]) {
+	for {
+		p.handle(t)
+
+		next, ok := p.next(t)
+		if !ok {
+			return
+		}
+		t = next
+	}
+}
+
+// handle выполняет обработчик с данными задачи (см. execute). О панике
+// обработчика, перехваченной WithRecover, сообщает только хук Hooks.Panic.
+func (p *Pool[Data]) handle(t *task[Data]) {
+	_ = p.execute(t.ctx, func(ctx context.Context) {
+		p.handler(ctx, t.data)
+	})
+}
+
+// next снимает с учета выполненную задачу `done` и забирает следующую задачу
+// из очереди для освободившегося воркера. Если задач нет или пул уменьшился,
+// воркер возвращается в пул.
+func (p *Pool[Data]) next(done *task[Data]) (*task[Data], bool) {
+	p.mu.Lock()
+	if done != nil {
+		delete(p.inflight, done)
+	}
+	if len(p.queue) > 0 && p.active <= p.size {
+		next := p.queue[0]
+		p.queue = p.queue[1:]
+		p.inflight[next] = struct{}{}
+		p.mu.Unlock()
+		return next, true
+	}
+	p.active--
+	p.mu.Unlock()
+	p.cond.Broadcast()
+
+	return nil, false
+}
+
+// Submit выполняет задачу `task` на одном из воркеров пула и возвращает
+// канал, в который будет записан ее результат. Канал буферизирован, поэтому
+// результат можно не читать. Как и Handle, блокируется, пока все воркеры
+// заняты, а Wait дожидается завершения задачи. Если пул не запущен,
+// в канал сразу записывается ErrPoolStopped.
+func (p *Pool[Data]) Submit(ctx context.Context, task func(ctx context.Context) error) <-chan error {
+	result := make(chan error, 1)
+
+	if err := p.acquire(); err != nil {
+		result <- err
+		close(result)
+		return result
+	}
 	go func() {
-		p.handler(ctx, data)
-		p.pool <- w
+		defer p.release()
+
+		var err error
+		if panicErr := p.execute(ctx, func(ctx context.Context) {
+			err = task(ctx)
+		}); panicErr != nil {
+			err = panicErr
+		}
+		result <- err
+		close(result)
 	}()
+
+	return result
+}
+
+// acquire ожидает, пока в пуле появится свободный воркер, и захватывает его.
+func (p *Pool[Data]) acquire() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.acquireLocked()
+}
+
+// acquireLocked - acquire под уже захваченным p.mu. Задачи из очереди
+// TrySubmit получают воркеров в первую очередь.
+func (p *Pool[Data]) acquireLocked() error {
+	for p.running && (p.active >= p.size || len(p.queue) > 0) {
+		p.cond.Wait()
+	}
+	if !p.running {
+		return ErrPoolStopped
+	}
+	p.active++
+	return nil
+}
+
+// release возвращает воркер в пул или передает его задаче из очереди.
+func (p *Pool[Data]) release() {
+	if next, ok := p.next(nil); ok {
+		go p.run(next)
+	}
 }
 
 // Wait ожидает, пока все воркеры завершат свою работу и вернутся в пул.
-// Этот метод следует вызывать после того, как все задачи были отправлены
-// в `Handle`, чтобы дождаться их полного выполнения.
+// В отличие от Stop, пул продолжает принимать задачи. Метод следует
+// вызывать после того, как все задачи пачки были переданы в пул,
+// чтобы дождаться их полного выполнения.
 func (p *Pool[Data]) Wait() {
-	for range len(workers) {
-		// Блокируемся, читая из канала, пока он не наполнится всеми воркерами.
-		<-p.pool
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.active > 0 {
+		p.cond.Wait()
+	}
+}
+
+// Size возвращает текущий размер пула.
+func (p *Pool[Data]) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.size
+}
+
+// Grow увеличивает размер пула на `n` воркеров. Ожидающие в Handle
+// задачи сразу получают новых воркеров.
+func (p *Pool[Data]) Grow(n int) {
+	if n <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	p.size += n
+	// Новые воркеры сначала забирают задачи из очереди.
+	for p.active < p.size && len(p.queue) > 0 {
+		next := p.queue[0]
+		p.queue = p.queue[1:]
+		p.active++
+		p.start(next)
 	}
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+// Shrink уменьшает размер пула на `n` воркеров, но не меньше чем до одного.
+// Уже выполняемые задачи не прерываются: лишние воркеры просто не
+// возвращаются в пул после завершения своих задач.
+func (p *Pool[Data]) Shrink(n int) {
+	if n <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.size = max(p.size-n, 1)
 }
@@ -0,0 +1,3 @@
+-- Откат миграции 10_outbox.up.sql: удаляет таблицу `outbox`.
+
+DROP TABLE IF EXISTS outbox;
@@ -0,0 +1,14 @@
+-- Эта миграция добавляет таблицу `outbox` (transactional outbox).
+--
+-- SaveOrder записывает событие в эту таблицу в той же транзакции, что и заказ,
+-- поэтому событие не теряется, даже если Kafka недоступна в момент сохранения.
+-- Отдельная горутина сервиса (relay) читает события по порядку, публикует их
+-- в топик событий и удаляет опубликованные строки.
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id         BIGSERIAL PRIMARY KEY,                            -- Порядок публикации.
+    event_type TEXT NOT NULL,                                    -- Тип события, например order.saved.
+    event_key  TEXT NOT NULL,                                    -- Ключ сообщения Kafka (order_uid).
+    payload    JSONB NOT NULL,                                   -- Тело события.
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()   -- Время записи события.
+);
@@ -0,0 +1,3 @@
+-- Откат миграции 11_orders_updated_at_idx.up.sql.
+
+DROP INDEX IF EXISTS orders_updated_at_idx;
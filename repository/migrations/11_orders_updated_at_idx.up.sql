@@ -0,0 +1,4 @@
+-- Эта миграция добавляет индекс по `updated_at` для догрузки измененных
+-- заказов в кэш (GetOrdersSince).
+
+CREATE INDEX IF NOT EXISTS orders_updated_at_idx ON orders (updated_at);
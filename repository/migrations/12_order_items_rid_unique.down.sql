@@ -0,0 +1,3 @@
+-- Откат миграции 12_order_items_rid_unique.up.sql. Удаленные дубли не восстанавливаются.
+
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_order_uid_rid_key;
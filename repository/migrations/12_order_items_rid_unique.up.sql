@@ -0,0 +1,18 @@
+-- Эта миграция запрещает дубли товаров внутри заказа: `rid` уникален
+-- в пределах заказа. Раньше повторно доставленное сообщение могло вставить
+-- товары заказа второй раз.
+--
+-- У секционированной таблицы уникальное ограничение должно включать ключ
+-- секционирования, поэтому в него входит `date_created` (та же, что у заказа).
+-- Перед созданием ограничения удаляются уже существующие дубли: остается
+-- товар с наименьшим `id`.
+
+DELETE FROM order_items a
+USING order_items b
+WHERE a.order_uid = b.order_uid
+  AND a.date_created = b.date_created
+  AND a.rid = b.rid
+  AND a.id > b.id;
+
+ALTER TABLE order_items
+    ADD CONSTRAINT order_items_order_uid_rid_key UNIQUE (order_uid, date_created, rid);
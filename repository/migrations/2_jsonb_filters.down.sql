@@ -0,0 +1,4 @@
+-- Откат миграции 2_jsonb_filters.up.sql: удаляет GIN-индексы по JSONB-колонкам.
+
+DROP INDEX IF EXISTS payment_data_gin_idx;
+DROP INDEX IF EXISTS additional_data_gin_idx;
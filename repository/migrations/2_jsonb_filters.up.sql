@@ -0,0 +1,8 @@
+-- Эта миграция добавляет GIN-индексы для фильтрации заказов по полям JSONB-колонок
+-- (провайдер и банк платежа, язык и источник заказа).
+-- Класс операторов `jsonb_path_ops` поддерживает только оператор `@>`,
+-- зато индекс получается компактнее и быстрее, чем с `jsonb_ops`.
+-- Именно `@>` используют хелперы из пакета storage/postgres (JSONBContains).
+
+CREATE INDEX IF NOT EXISTS payment_data_gin_idx ON orders USING GIN (payment_data jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS additional_data_gin_idx ON orders USING GIN (additional_data jsonb_path_ops);
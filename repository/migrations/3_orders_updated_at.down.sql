@@ -0,0 +1,3 @@
+-- Откат миграции 3_orders_updated_at.up.sql: удаляет колонку `updated_at`.
+
+ALTER TABLE orders DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,8 @@
+-- Эта миграция добавляет колонку `updated_at` - время последнего изменения заказа.
+-- По ней SaveOrder решает, новее ли пришедшее сообщение, чем сохраненная версия
+-- заказа (upsert с `ON CONFLICT DO UPDATE ... WHERE`).
+-- Для существующих заказов временем изменения считается дата создания.
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE;
+UPDATE orders SET updated_at = date_created WHERE updated_at IS NULL;
+ALTER TABLE orders ALTER COLUMN updated_at SET NOT NULL;
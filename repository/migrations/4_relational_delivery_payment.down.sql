@@ -0,0 +1,25 @@
+-- Откат миграции 4_relational_delivery_payment.up.sql: возвращает данные
+-- о доставке и оплате в JSONB-колонки и удаляет таблицы `deliveries` и `payments`.
+
+UPDATE orders o
+SET delivery_data = jsonb_build_object(
+    'name', d.name, 'phone', d.phone, 'zip', d.zip, 'city', d.city,
+    'address', d.address, 'region', d.region, 'email', d.email
+)
+FROM deliveries d
+WHERE d.order_uid = o.order_uid;
+
+UPDATE orders o
+SET payment_data = jsonb_build_object(
+    'transaction', p.transaction, 'request_id', p.request_id, 'currency', p.currency,
+    'provider', p.provider, 'amount', p.amount, 'payment_dt', p.payment_dt, 'bank', p.bank,
+    'delivery_cost', p.delivery_cost, 'goods_total', p.goods_total, 'custom_fee', p.custom_fee
+)
+FROM payments p
+WHERE p.order_uid = o.order_uid;
+
+ALTER TABLE orders ALTER COLUMN delivery_data SET NOT NULL;
+ALTER TABLE orders ALTER COLUMN payment_data SET NOT NULL;
+
+DROP TABLE IF EXISTS payments;
+DROP TABLE IF EXISTS deliveries;
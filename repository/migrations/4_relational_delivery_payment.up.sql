@@ -0,0 +1,74 @@
+-- Эта миграция переносит данные о доставке и оплате из JSONB-колонок
+-- `orders.delivery_data` и `orders.payment_data` в отдельные таблицы
+-- `deliveries` и `payments` (по одной строке на заказ), чтобы по ним
+-- было удобно фильтровать и строить отчеты.
+--
+-- JSONB-колонки не удаляются: предыдущая версия сервиса продолжает их читать.
+-- Пока включен режим совместимости (postgres.compat), новая версия продолжает
+-- заполнять их вместе с новыми таблицами. Поэтому колонки становятся nullable,
+-- а удалить их можно отдельной миграцией после закрытия окна совместимости.
+
+CREATE TABLE IF NOT EXISTS deliveries (
+    order_uid TEXT PRIMARY KEY REFERENCES orders(order_uid) ON DELETE CASCADE, -- Заказ, к которому относится доставка.
+    name      TEXT NOT NULL,                                                   -- Имя и фамилия получателя.
+    phone     TEXT NOT NULL,                                                   -- Контактный телефон.
+    zip       TEXT NOT NULL,                                                   -- Почтовый индекс.
+    city      TEXT NOT NULL,                                                   -- Город доставки.
+    address   TEXT NOT NULL,                                                   -- Адрес доставки.
+    region    TEXT NOT NULL,                                                   -- Регион/область.
+    email     TEXT NOT NULL                                                    -- Электронная почта получателя.
+);
+
+CREATE TABLE IF NOT EXISTS payments (
+    order_uid     TEXT PRIMARY KEY REFERENCES orders(order_uid) ON DELETE CASCADE, -- Заказ, к которому относится оплата.
+    transaction   TEXT NOT NULL,                                                   -- ID транзакции.
+    request_id    TEXT NOT NULL,                                                   -- Внутренний ID запроса на оплату.
+    currency      TEXT NOT NULL,                                                   -- Валюта платежа.
+    provider      TEXT NOT NULL,                                                   -- Платежный провайдер.
+    amount        INTEGER NOT NULL,                                                -- Общая сумма к оплате.
+    payment_dt    BIGINT NOT NULL,                                                 -- Unix-время транзакции.
+    bank          TEXT NOT NULL,                                                   -- Банк.
+    delivery_cost INTEGER NOT NULL,                                                -- Стоимость доставки.
+    goods_total   INTEGER NOT NULL,                                                -- Стоимость товаров.
+    custom_fee    INTEGER NOT NULL                                                 -- Таможенный сбор.
+);
+
+-- Индексы для частых фильтров по оплате.
+CREATE INDEX IF NOT EXISTS payments_provider_idx ON payments (provider);
+CREATE INDEX IF NOT EXISTS payments_bank_idx ON payments (bank);
+
+-- Backfill: переносим существующие данные из JSONB.
+INSERT INTO deliveries (order_uid, name, phone, zip, city, address, region, email)
+SELECT order_uid,
+       COALESCE(delivery_data->>'name', ''),
+       COALESCE(delivery_data->>'phone', ''),
+       COALESCE(delivery_data->>'zip', ''),
+       COALESCE(delivery_data->>'city', ''),
+       COALESCE(delivery_data->>'address', ''),
+       COALESCE(delivery_data->>'region', ''),
+       COALESCE(delivery_data->>'email', '')
+FROM orders
+WHERE delivery_data IS NOT NULL
+ON CONFLICT (order_uid) DO NOTHING;
+
+INSERT INTO payments (
+    order_uid, transaction, request_id, currency, provider, amount,
+    payment_dt, bank, delivery_cost, goods_total, custom_fee
+)
+SELECT order_uid,
+       COALESCE(payment_data->>'transaction', ''),
+       COALESCE(payment_data->>'request_id', ''),
+       COALESCE(payment_data->>'currency', ''),
+       COALESCE(payment_data->>'provider', ''),
+       COALESCE((payment_data->>'amount')::INTEGER, 0),
+       COALESCE((payment_data->>'payment_dt')::BIGINT, 0),
+       COALESCE(payment_data->>'bank', ''),
+       COALESCE((payment_data->>'delivery_cost')::INTEGER, 0),
+       COALESCE((payment_data->>'goods_total')::INTEGER, 0),
+       COALESCE((payment_data->>'custom_fee')::INTEGER, 0)
+FROM orders
+WHERE payment_data IS NOT NULL
+ON CONFLICT (order_uid) DO NOTHING;
+
+ALTER TABLE orders ALTER COLUMN delivery_data DROP NOT NULL;
+ALTER TABLE orders ALTER COLUMN payment_data DROP NOT NULL;
@@ -0,0 +1,9 @@
+-- Откат миграции 5_filter_indexes.up.sql: удаляет индексы под фильтры
+-- и возвращает индекс по customer_id из первой миграции.
+
+CREATE INDEX IF NOT EXISTS customer_id_idx ON orders (customer_id);
+
+DROP INDEX IF EXISTS orders_customer_id_date_created_idx;
+DROP INDEX IF EXISTS orders_date_created_idx;
+DROP INDEX IF EXISTS order_items_track_number_idx;
+DROP INDEX IF EXISTS order_items_order_uid_idx;
@@ -0,0 +1,20 @@
+-- Эта миграция добавляет индексы под фильтры ListOrders, чтобы выборки
+-- по покупателю, периоду и трек-номеру не приводили к последовательному
+-- сканированию таблиц.
+--
+-- ListOrders сортирует заказы по `date_created DESC`, поэтому составной индекс
+-- (customer_id, date_created DESC) отдает страницу заказов покупателя без
+-- отдельной сортировки. Он же покрывает поиск только по customer_id, поэтому
+-- индекс `customer_id_idx` из первой миграции больше не нужен.
+
+CREATE INDEX IF NOT EXISTS orders_customer_id_date_created_idx ON orders (customer_id, date_created DESC);
+DROP INDEX IF EXISTS customer_id_idx;
+
+-- Фильтр по периоду создания и постраничный вывод всех заказов.
+CREATE INDEX IF NOT EXISTS orders_date_created_idx ON orders (date_created DESC);
+
+-- Фильтр по трек-номеру товара (OrderFilter.TrackNumber).
+CREATE INDEX IF NOT EXISTS order_items_track_number_idx ON order_items (track_number);
+
+-- JOIN заказов с товарами и удаление товаров при обновлении заказа.
+CREATE INDEX IF NOT EXISTS order_items_order_uid_idx ON order_items (order_uid);
@@ -0,0 +1,3 @@
+-- Откат миграции 6_order_status.up.sql: удаляет колонку `status`.
+
+ALTER TABLE orders DROP COLUMN IF EXISTS status;
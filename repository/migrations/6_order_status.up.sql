@@ -0,0 +1,6 @@
+-- Эта миграция добавляет статус заказа. Существующие заказы получают
+-- начальный статус `created`. Допустимые переходы между статусами проверяет
+-- сервис (models.OrderStatus.CanTransitionTo), база проверяет только значение.
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'created'
+    CONSTRAINT orders_status_check CHECK (status IN ('created', 'paid', 'shipped', 'delivered', 'cancelled'));
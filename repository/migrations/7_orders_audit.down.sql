@@ -0,0 +1,5 @@
+-- Откат миграции 7_orders_audit.up.sql: удаляет триггер и журнал изменений.
+
+DROP TRIGGER IF EXISTS orders_audit ON orders;
+DROP FUNCTION IF EXISTS orders_audit_trigger();
+DROP TABLE IF EXISTS orders_audit;
@@ -0,0 +1,44 @@
+-- Эта миграция добавляет журнал изменений заказов `orders_audit`.
+--
+-- Записи добавляет триггер на таблице `orders` в той же транзакции, что и
+-- само изменение, поэтому журнал не расходится с данными и покрывает в том
+-- числе изменения, сделанные в обход сервиса. Автора изменения сервис передает
+-- через параметр сессии `app.actor` (set_config(..., true) действует до конца
+-- транзакции); если он не задан, записывается текущий пользователь базы.
+
+CREATE TABLE IF NOT EXISTS orders_audit (
+    id         BIGSERIAL PRIMARY KEY,                              -- Порядковый номер записи.
+    order_uid  TEXT NOT NULL,                                      -- Заказ. Без внешнего ключа: записи переживают удаление заказа.
+    operation  TEXT NOT NULL,                                      -- INSERT, UPDATE или DELETE.
+    old_data   JSONB,                                              -- Строка заказа до изменения (NULL для INSERT).
+    new_data   JSONB,                                              -- Строка заказа после изменения (NULL для DELETE).
+    actor      TEXT NOT NULL,                                      -- Кто изменил заказ.
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()     -- Время изменения.
+);
+
+CREATE INDEX IF NOT EXISTS orders_audit_order_uid_idx ON orders_audit (order_uid, id);
+
+CREATE OR REPLACE FUNCTION orders_audit_trigger() RETURNS TRIGGER AS $$
+DECLARE
+    actor TEXT := COALESCE(NULLIF(current_setting('app.actor', true), ''), current_user);
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO orders_audit (order_uid, operation, new_data, actor)
+        VALUES (NEW.order_uid, TG_OP, to_jsonb(NEW), actor);
+        RETURN NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO orders_audit (order_uid, operation, old_data, new_data, actor)
+        VALUES (NEW.order_uid, TG_OP, to_jsonb(OLD), to_jsonb(NEW), actor);
+        RETURN NEW;
+    END IF;
+
+    INSERT INTO orders_audit (order_uid, operation, old_data, actor)
+    VALUES (OLD.order_uid, TG_OP, to_jsonb(OLD), actor);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS orders_audit ON orders;
+CREATE TRIGGER orders_audit
+    AFTER INSERT OR UPDATE OR DELETE ON orders
+    FOR EACH ROW EXECUTE FUNCTION orders_audit_trigger();
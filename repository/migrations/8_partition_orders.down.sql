@@ -0,0 +1,69 @@
+-- Откат миграции 8_partition_orders.up.sql: возвращает обычные
+-- (несекционированные) таблицы `orders` и `order_items` и внешние ключи
+-- `deliveries` и `payments`.
+
+ALTER TABLE orders RENAME TO orders_partitioned;
+ALTER TABLE order_items RENAME TO order_items_partitioned;
+ALTER SEQUENCE order_items_id_seq OWNED BY NONE;
+ALTER TABLE orders_partitioned RENAME CONSTRAINT orders_pkey TO orders_partitioned_pkey;
+ALTER TABLE order_items_partitioned RENAME CONSTRAINT order_items_pkey TO order_items_partitioned_pkey;
+
+CREATE TABLE orders (
+    order_uid        TEXT PRIMARY KEY,
+    track_number     TEXT NOT NULL,
+    customer_id      TEXT NOT NULL,
+    delivery_service TEXT NOT NULL,
+    date_created     TIMESTAMP WITH TIME ZONE NOT NULL,
+    payment_data     JSONB,
+    delivery_data    JSONB,
+    additional_data  JSONB NOT NULL,
+    updated_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+    status           TEXT NOT NULL DEFAULT 'created'
+        CONSTRAINT orders_status_check CHECK (status IN ('created', 'paid', 'shipped', 'delivered', 'cancelled'))
+);
+
+CREATE TABLE order_items (
+    id           INTEGER PRIMARY KEY DEFAULT nextval('order_items_id_seq'),
+    order_uid    TEXT REFERENCES orders(order_uid) NOT NULL,
+    chrt_id      INTEGER NOT NULL,
+    track_number TEXT NOT NULL,
+    price        NUMERIC NOT NULL,
+    rid          TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    sale         NUMERIC NOT NULL,
+    size         TEXT NOT NULL,
+    total_price  NUMERIC NOT NULL,
+    nm_id        INTEGER NOT NULL,
+    brand        TEXT NOT NULL,
+    status       INTEGER NOT NULL
+);
+
+INSERT INTO orders SELECT
+    order_uid, track_number, customer_id, delivery_service, date_created,
+    payment_data, delivery_data, additional_data, updated_at, status
+FROM orders_partitioned;
+
+INSERT INTO order_items SELECT
+    id, order_uid, chrt_id, track_number, price, rid, name,
+    sale, size, total_price, nm_id, brand, status
+FROM order_items_partitioned;
+
+DROP TABLE order_items_partitioned;
+DROP TABLE orders_partitioned;
+ALTER SEQUENCE order_items_id_seq OWNED BY order_items.id;
+
+ALTER TABLE deliveries ADD CONSTRAINT deliveries_order_uid_fkey
+    FOREIGN KEY (order_uid) REFERENCES orders(order_uid) ON DELETE CASCADE;
+ALTER TABLE payments ADD CONSTRAINT payments_order_uid_fkey
+    FOREIGN KEY (order_uid) REFERENCES orders(order_uid) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS payment_data_gin_idx ON orders USING GIN (payment_data jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS additional_data_gin_idx ON orders USING GIN (additional_data jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS orders_customer_id_date_created_idx ON orders (customer_id, date_created DESC);
+CREATE INDEX IF NOT EXISTS orders_date_created_idx ON orders (date_created DESC);
+CREATE INDEX IF NOT EXISTS order_items_track_number_idx ON order_items (track_number);
+CREATE INDEX IF NOT EXISTS order_items_order_uid_idx ON order_items (order_uid);
+
+CREATE TRIGGER orders_audit
+    AFTER INSERT OR UPDATE OR DELETE ON orders
+    FOR EACH ROW EXECUTE FUNCTION orders_audit_trigger();
@@ -0,0 +1,117 @@
+-- Эта миграция переводит таблицы `orders` и `order_items` на декларативное
+-- секционирование по диапазону `date_created` (одна секция на месяц), чтобы
+-- запросы за период читали только нужные секции, а старые данные можно было
+-- отсоединять и удалять целыми секциями.
+--
+-- Ограничения секционированных таблиц:
+--   - первичный и уникальные ключи должны включать ключ секционирования,
+--     поэтому ключ заказа - (order_uid, date_created), а `date_created`
+--     заказа после создания не меняется;
+--   - в `order_items` добавлена колонка `date_created` (копия из заказа),
+--     а внешний ключ ссылается на (order_uid, date_created);
+--   - `deliveries` и `payments` больше не ссылаются на `orders` внешним
+--     ключом: уникального индекса по одному order_uid у секционированной
+--     таблицы нет. Их строки удаляет сервис вместе с заказом.
+--
+-- Секции на ближайшие месяцы создает сервис (postgres.Storage.EnsurePartitions).
+-- Строки вне созданных секций попадают в секцию по умолчанию.
+
+ALTER TABLE orders RENAME TO orders_legacy;
+ALTER TABLE order_items RENAME TO order_items_legacy;
+ALTER SEQUENCE order_items_id_seq OWNED BY NONE;
+-- Имена индексов уникальны в схеме, поэтому освобождаем имена первичных ключей.
+ALTER TABLE orders_legacy RENAME CONSTRAINT orders_pkey TO orders_legacy_pkey;
+ALTER TABLE order_items_legacy RENAME CONSTRAINT order_items_pkey TO order_items_legacy_pkey;
+
+CREATE TABLE orders (
+    order_uid        TEXT NOT NULL,
+    track_number     TEXT NOT NULL,
+    customer_id      TEXT NOT NULL,
+    delivery_service TEXT NOT NULL,
+    date_created     TIMESTAMP WITH TIME ZONE NOT NULL,
+    payment_data     JSONB,
+    delivery_data    JSONB,
+    additional_data  JSONB NOT NULL,
+    updated_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+    status           TEXT NOT NULL DEFAULT 'created'
+        CONSTRAINT orders_status_check CHECK (status IN ('created', 'paid', 'shipped', 'delivered', 'cancelled')),
+    PRIMARY KEY (order_uid, date_created)
+) PARTITION BY RANGE (date_created);
+
+CREATE TABLE order_items (
+    id           BIGINT NOT NULL DEFAULT nextval('order_items_id_seq'),
+    order_uid    TEXT NOT NULL,
+    date_created TIMESTAMP WITH TIME ZONE NOT NULL,
+    chrt_id      INTEGER NOT NULL,
+    track_number TEXT NOT NULL,
+    price        NUMERIC NOT NULL,
+    rid          TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    sale         NUMERIC NOT NULL,
+    size         TEXT NOT NULL,
+    total_price  NUMERIC NOT NULL,
+    nm_id        INTEGER NOT NULL,
+    brand        TEXT NOT NULL,
+    status       INTEGER NOT NULL,
+    PRIMARY KEY (id, date_created),
+    FOREIGN KEY (order_uid, date_created) REFERENCES orders (order_uid, date_created) ON DELETE CASCADE
+) PARTITION BY RANGE (date_created);
+
+CREATE TABLE orders_default PARTITION OF orders DEFAULT;
+CREATE TABLE order_items_default PARTITION OF order_items DEFAULT;
+
+-- Месячные секции для существующих данных и на два месяца вперед.
+DO $$
+DECLARE
+    month_start TIMESTAMP WITH TIME ZONE;
+    last_month  TIMESTAMP WITH TIME ZONE := date_trunc('month', now() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' + INTERVAL '2 months';
+    suffix      TEXT;
+BEGIN
+    SELECT date_trunc('month', min(date_created) AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' INTO month_start FROM orders_legacy;
+    month_start := LEAST(COALESCE(month_start, last_month), date_trunc('month', now() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC');
+
+    WHILE month_start <= last_month LOOP
+        suffix := to_char(month_start AT TIME ZONE 'UTC', '"y"YYYY"m"MM');
+        EXECUTE format('CREATE TABLE IF NOT EXISTS %I PARTITION OF orders FOR VALUES FROM (%L) TO (%L)',
+            'orders_' || suffix, month_start, month_start + INTERVAL '1 month');
+        EXECUTE format('CREATE TABLE IF NOT EXISTS %I PARTITION OF order_items FOR VALUES FROM (%L) TO (%L)',
+            'order_items_' || suffix, month_start, month_start + INTERVAL '1 month');
+        month_start := month_start + INTERVAL '1 month';
+    END LOOP;
+END $$;
+
+INSERT INTO orders (
+    order_uid, track_number, customer_id, delivery_service, date_created,
+    payment_data, delivery_data, additional_data, updated_at, status
+)
+SELECT order_uid, track_number, customer_id, delivery_service, date_created,
+       payment_data, delivery_data, additional_data, updated_at, status
+FROM orders_legacy;
+
+INSERT INTO order_items (
+    id, order_uid, date_created, chrt_id, track_number, price, rid, name,
+    sale, size, total_price, nm_id, brand, status
+)
+SELECT i.id, i.order_uid, o.date_created, i.chrt_id, i.track_number, i.price, i.rid, i.name,
+       i.sale, i.size, i.total_price, i.nm_id, i.brand, i.status
+FROM order_items_legacy i
+JOIN orders_legacy o ON o.order_uid = i.order_uid;
+
+-- CASCADE удаляет внешние ключи deliveries и payments на старую таблицу.
+DROP TABLE order_items_legacy;
+DROP TABLE orders_legacy CASCADE;
+ALTER SEQUENCE order_items_id_seq OWNED BY order_items.id;
+
+-- Индексы из миграций 2 и 5 создаются заново на секционированных таблицах.
+CREATE INDEX IF NOT EXISTS payment_data_gin_idx ON orders USING GIN (payment_data jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS additional_data_gin_idx ON orders USING GIN (additional_data jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS orders_customer_id_date_created_idx ON orders (customer_id, date_created DESC);
+CREATE INDEX IF NOT EXISTS orders_date_created_idx ON orders (date_created DESC);
+CREATE INDEX IF NOT EXISTS orders_order_uid_idx ON orders (order_uid);
+CREATE INDEX IF NOT EXISTS order_items_track_number_idx ON order_items (track_number);
+CREATE INDEX IF NOT EXISTS order_items_order_uid_idx ON order_items (order_uid);
+
+-- Триггер журнала изменений из миграции 7.
+CREATE TRIGGER orders_audit
+    AFTER INSERT OR UPDATE OR DELETE ON orders
+    FOR EACH ROW EXECUTE FUNCTION orders_audit_trigger();
@@ -0,0 +1,3 @@
+-- Откат миграции 9_orders_archive.up.sql: удаляет архив заказов.
+
+DROP TABLE IF EXISTS orders_archive;
@@ -0,0 +1,13 @@
+-- Эта миграция добавляет таблицу `orders_archive` для заказов старше срока
+-- хранения. Архивный заказ хранится целиком (с доставкой, оплатой и товарами)
+-- одним JSONB-документом в формате API, а из рабочих таблиц удаляется.
+
+CREATE TABLE IF NOT EXISTS orders_archive (
+    order_uid    TEXT NOT NULL,                                    -- Идентификатор заказа.
+    date_created TIMESTAMP WITH TIME ZONE NOT NULL,                -- Дата создания заказа.
+    data         JSONB NOT NULL,                                   -- Заказ целиком, как его возвращает API.
+    archived_at  TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),  -- Время переноса в архив.
+    PRIMARY KEY (order_uid, date_created)
+);
+
+CREATE INDEX IF NOT EXISTS orders_archive_date_created_idx ON orders_archive (date_created);
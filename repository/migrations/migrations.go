@@ -0,0 +1,10 @@
+// Package migrations встраивает SQL-файлы миграций в бинарный файл,
+// чтобы сервис мог применить их при старте без доступа к исходникам.
+package migrations
+
+import "embed"
+
+// FS содержит все файлы миграций (`*.up.sql` и `*.down.sql`).
+//
+//go:embed *.sql
+var FS embed.FS